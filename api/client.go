@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -15,43 +16,95 @@ import (
 
 // Client represents the API client
 type Client struct {
-	updateURL  string
-	queryURL   string
-	httpClient *http.Client
+	updateURL   string
+	queryURL    string
+	httpClient  *http.Client
+	retryPolicy RetryPolicy
 }
 
-// NewClient creates a new API client
-func NewClient(updateURL, queryURL string) *Client {
-	return &Client{
+// NewClient creates a new API client. By default requests time out after 30 seconds and are
+// retried following DefaultRetryPolicy.
+func NewClient(updateURL, queryURL string, opts ...ClientOption) *Client {
+	c := &Client{
 		updateURL: updateURL,
 		queryURL:  queryURL,
 		httpClient: &http.Client{
 			Timeout: time.Second * 30,
 		},
+		retryPolicy: DefaultRetryPolicy(),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// do sends a request and returns the response if it has the expected status code; otherwise
+// the returned error is one of the types in errors.go. Requests that are safe to repeat (see
+// isIdempotent; readOnly marks the POST endpoints of the Query API) are retried following the
+// retry policy of the client. The caller must close the body of the returned response.
+func (c *Client) do(ctx context.Context, method string, url string, body []byte, expectedStatus int, readOnly bool) (*http.Response, error) {
+	attempts := 1
+	if isIdempotent(method, readOnly) && c.retryPolicy.MaxAttempts > 1 {
+		attempts = c.retryPolicy.MaxAttempts
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			if err := sleep(ctx, c.retryPolicy.backoff(attempt-1)); err != nil {
+				return nil, err
+			}
+		}
+
+		var bodyReader io.Reader
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		if method == http.MethodPost || method == http.MethodPut {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && resp.StatusCode == expectedStatus {
+			return resp, nil
+		}
+		if err == nil {
+			err = newRequestError(req, resp)
+			resp.Body.Close()
+		}
+
+		lastErr = err
+		if !shouldRetry(ctx, err) {
+			break
+		}
+	}
+
+	return nil, lastErr
 }
 
 // CreateEntity creates a new entity
 func (c *Client) CreateEntity(entity *models.Entity) (*models.Entity, error) {
+	return c.CreateEntityContext(context.Background(), entity)
+}
+
+// CreateEntityContext is like CreateEntity but sends the request with the given context
+func (c *Client) CreateEntityContext(ctx context.Context, entity *models.Entity) (*models.Entity, error) {
 	jsonData, err := json.Marshal(entity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal entity: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(
-		c.updateURL,
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	resp, err := c.do(ctx, http.MethodPost, c.updateURL, jsonData, http.StatusCreated, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create entity: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
 	var createdEntity models.Entity
 	if err := json.NewDecoder(resp.Body).Decode(&createdEntity); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -62,6 +115,11 @@ func (c *Client) CreateEntity(entity *models.Entity) (*models.Entity, error) {
 
 // UpdateEntity updates an existing entity
 func (c *Client) UpdateEntity(id string, entity *models.Entity) (*models.Entity, error) {
+	return c.UpdateEntityContext(context.Background(), id, entity)
+}
+
+// UpdateEntityContext is like UpdateEntity but sends the request with the given context
+func (c *Client) UpdateEntityContext(ctx context.Context, id string, entity *models.Entity) (*models.Entity, error) {
 	jsonData, err := json.Marshal(entity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal entity: %w", err)
@@ -70,26 +128,12 @@ func (c *Client) UpdateEntity(id string, entity *models.Entity) (*models.Entity,
 	// URL encode the entity ID to handle special characters like slashes
 	encodedID := url.QueryEscape(id)
 
-	req, err := http.NewRequest(
-		http.MethodPut,
-		fmt.Sprintf("%s/%s", c.updateURL, encodedID),
-		bytes.NewBuffer(jsonData),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(ctx, http.MethodPut, fmt.Sprintf("%s/%s", c.updateURL, encodedID), jsonData, http.StatusOK, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update entity: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
 	var updatedEntity models.Entity
 	if err := json.NewDecoder(resp.Body).Decode(&updatedEntity); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -100,45 +144,39 @@ func (c *Client) UpdateEntity(id string, entity *models.Entity) (*models.Entity,
 
 // DeleteEntity deletes an entity
 func (c *Client) DeleteEntity(id string) error {
-	req, err := http.NewRequest(
-		http.MethodDelete,
-		fmt.Sprintf("%s/%s", c.updateURL, id),
-		nil,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
+	return c.DeleteEntityContext(context.Background(), id)
+}
+
+// DeleteEntityContext is like DeleteEntity but sends the request with the given context
+func (c *Client) DeleteEntityContext(ctx context.Context, id string) error {
+	// URL encode the entity ID to handle special characters like slashes
+	encodedID := url.QueryEscape(id)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("%s/%s", c.updateURL, encodedID), nil, http.StatusNoContent, false)
 	if err != nil {
 		return fmt.Errorf("failed to delete entity: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
+	resp.Body.Close()
 
 	return nil
 }
 
 // GetRootEntities gets root entity IDs of a given kind
 func (c *Client) GetRootEntities(kind string) ([]string, error) {
+	return c.GetRootEntitiesContext(context.Background(), kind)
+}
+
+// GetRootEntitiesContext is like GetRootEntities but sends the request with the given context
+func (c *Client) GetRootEntitiesContext(ctx context.Context, kind string) ([]string, error) {
 	params := url.Values{}
 	params.Add("kind", kind)
 
-	resp, err := c.httpClient.Get(
-		fmt.Sprintf("%s/root?%s", c.queryURL, params.Encode()),
-	)
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s/root?%s", c.queryURL, params.Encode()), nil, http.StatusOK, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get root entities: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
 	var response models.RootEntitiesResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -149,25 +187,22 @@ func (c *Client) GetRootEntities(kind string) ([]string, error) {
 
 // SearchEntities searches for entities based on criteria
 func (c *Client) SearchEntities(criteria *models.SearchCriteria) ([]models.SearchResult, error) {
+	return c.SearchEntitiesContext(context.Background(), criteria)
+}
+
+// SearchEntitiesContext is like SearchEntities but sends the request with the given context
+func (c *Client) SearchEntitiesContext(ctx context.Context, criteria *models.SearchCriteria) ([]models.SearchResult, error) {
 	jsonData, err := json.Marshal(criteria)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal search criteria: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(
-		fmt.Sprintf("%s/search", c.queryURL),
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	resp, err := c.do(ctx, http.MethodPost, fmt.Sprintf("%s/search", c.queryURL), jsonData, http.StatusOK, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search entities: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
 	// Read the raw response body
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -203,18 +238,20 @@ func (c *Client) SearchEntities(criteria *models.SearchCriteria) ([]models.Searc
 
 // GetEntityMetadata gets metadata of an entity
 func (c *Client) GetEntityMetadata(entityID string) (map[string]interface{}, error) {
-	resp, err := c.httpClient.Get(
-		fmt.Sprintf("%s/%s/metadata", c.queryURL, entityID),
-	)
+	return c.GetEntityMetadataContext(context.Background(), entityID)
+}
+
+// GetEntityMetadataContext is like GetEntityMetadata but sends the request with the given context
+func (c *Client) GetEntityMetadataContext(ctx context.Context, entityID string) (map[string]interface{}, error) {
+	// URL encode the entity ID to handle special characters like slashes
+	encodedID := url.QueryEscape(entityID)
+
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s/%s/metadata", c.queryURL, encodedID), nil, http.StatusOK, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get entity metadata: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
 	var metadata map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -225,7 +262,15 @@ func (c *Client) GetEntityMetadata(entityID string) (map[string]interface{}, err
 
 // GetEntityAttribute retrieves a specific attribute of an entity
 func (c *Client) GetEntityAttribute(entityID, attributeName string, startTime, endTime string) (interface{}, error) {
-	url := fmt.Sprintf("%s/%s/attributes/%s", c.queryURL, entityID, attributeName)
+	return c.GetEntityAttributeContext(context.Background(), entityID, attributeName, startTime, endTime)
+}
+
+// GetEntityAttributeContext is like GetEntityAttribute but sends the request with the given context
+func (c *Client) GetEntityAttributeContext(ctx context.Context, entityID, attributeName string, startTime, endTime string) (interface{}, error) {
+	// URL encode the entity ID to handle special characters like slashes
+	encodedID := url.QueryEscape(entityID)
+
+	url := fmt.Sprintf("%s/%s/attributes/%s", c.queryURL, encodedID, attributeName)
 	if startTime != "" {
 		url += fmt.Sprintf("?startTime=%s", startTime)
 		if endTime != "" {
@@ -233,7 +278,7 @@ func (c *Client) GetEntityAttribute(entityID, attributeName string, startTime, e
 		}
 	}
 
-	resp, err := c.httpClient.Get(url)
+	resp, err := c.do(ctx, http.MethodGet, url, nil, http.StatusOK, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get entity attribute: %w", err)
 	}
@@ -249,6 +294,11 @@ func (c *Client) GetEntityAttribute(entityID, attributeName string, startTime, e
 
 // GetRelatedEntities gets related entity IDs based on query parameters
 func (c *Client) GetRelatedEntities(entityID string, query *models.Relationship) ([]models.Relationship, error) {
+	return c.GetRelatedEntitiesContext(context.Background(), entityID, query)
+}
+
+// GetRelatedEntitiesContext is like GetRelatedEntities but sends the request with the given context
+func (c *Client) GetRelatedEntitiesContext(ctx context.Context, entityID string, query *models.Relationship) ([]models.Relationship, error) {
 	jsonData, err := json.Marshal(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal query: %w", err)
@@ -257,21 +307,12 @@ func (c *Client) GetRelatedEntities(entityID string, query *models.Relationship)
 	// URL encode the entity ID to handle special characters like slashes
 	encodedID := url.QueryEscape(entityID)
 
-	resp, err := c.httpClient.Post(
-		fmt.Sprintf("%s/%s/relations", c.queryURL, encodedID),
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	resp, err := c.do(ctx, http.MethodPost, fmt.Sprintf("%s/%s/relations", c.queryURL, encodedID), jsonData, http.StatusOK, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get related entities: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
-	}
-
 	var relations []models.Relationship
 	if err := json.NewDecoder(resp.Body).Decode(&relations); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -282,23 +323,20 @@ func (c *Client) GetRelatedEntities(entityID string, query *models.Relationship)
 
 // GetAllRelatedEntities gets all related entity IDs without filters
 func (c *Client) GetAllRelatedEntities(entityID string) ([]models.Relationship, error) {
+	return c.GetAllRelatedEntitiesContext(context.Background(), entityID)
+}
+
+// GetAllRelatedEntitiesContext is like GetAllRelatedEntities but sends the request with the given context
+func (c *Client) GetAllRelatedEntitiesContext(ctx context.Context, entityID string) ([]models.Relationship, error) {
 	// URL encode the entity ID to handle special characters like slashes
 	encodedID := url.QueryEscape(entityID)
 
-	resp, err := c.httpClient.Post(
-		fmt.Sprintf("%s/%s/allrelations", c.queryURL, encodedID),
-		"application/json",
-		nil,
-	)
+	resp, err := c.do(ctx, http.MethodPost, fmt.Sprintf("%s/%s/allrelations", c.queryURL, encodedID), nil, http.StatusOK, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all related entities: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-
 	var relations []models.Relationship
 	if err := json.NewDecoder(resp.Body).Decode(&relations); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables that override the values of a config file
+const (
+	EnvUpdateEndpoint = "ORGCHART_UPDATE_ENDPOINT"
+	EnvQueryEndpoint  = "ORGCHART_QUERY_ENDPOINT"
+	EnvTimeout        = "ORGCHART_TIMEOUT"
+	EnvRetries        = "ORGCHART_RETRIES"
+)
+
+// Config holds the settings shared by the commands, read from a YAML file such as:
+//
+//	update_endpoint: http://nexoan:8080/entities
+//	query_endpoint: http://nexoan:8081/v1/entities
+//	timeout: 1m
+//	retry:
+//	  max_attempts: 5
+//	  initial_backoff: 1s
+//	roots:
+//	  - id: gov_01
+//	    name: Government of Sri Lanka
+//	presidencies:
+//	  rw:
+//	    orgchart: data/orgchart/rw
+//	    people: data/people/rw
+//	kinds:
+//	  ministry: minister
+//
+// Settings missing from the file keep their defaults (see DefaultConfig).
+type Config struct {
+	UpdateEndpoint string `yaml:"update_endpoint"`
+	QueryEndpoint  string `yaml:"query_endpoint"`
+	// Timeout is the time limit of each request to Nexoan
+	Timeout time.Duration `yaml:"timeout"`
+	Retry   RetryPolicy   `yaml:"retry"`
+	// Roots are the root nodes created by -init; empty means the -root_* flags are used
+	Roots []RootNode `yaml:"roots"`
+	// Presidencies maps the name of each presidency to its data directories
+	Presidencies map[string]Presidency `yaml:"presidencies"`
+	// Kinds maps parent_type and child_type values of the data to minor kinds; see ProcessOptions
+	Kinds map[string]string `yaml:"kinds"`
+}
+
+// Presidency holds the data directories of a presidency. Relative paths in a config file are
+// relative to the directory of the file.
+type Presidency struct {
+	Orgchart string `yaml:"orgchart"`
+	People   string `yaml:"people"`
+}
+
+// DefaultConfig returns the settings used when there is no config file
+func DefaultConfig() *Config {
+	return &Config{
+		UpdateEndpoint: "http://localhost:8080/entities",
+		QueryEndpoint:  "http://localhost:8081/v1/entities",
+		Timeout:        30 * time.Second,
+		Retry:          DefaultRetryPolicy(),
+	}
+}
+
+// LoadConfig reads a config file over the defaults. An empty path returns the defaults.
+func LoadConfig(path string) (*Config, error) {
+	config := DefaultConfig()
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode config file %s: %w", path, err)
+	}
+
+	if err := completeRootNodes(config.Roots, path); err != nil {
+		return nil, err
+	}
+	for name, presidency := range config.Presidencies {
+		presidency.Orgchart = configPath(path, presidency.Orgchart)
+		presidency.People = configPath(path, presidency.People)
+		config.Presidencies[name] = presidency
+	}
+
+	return config, nil
+}
+
+// configPath resolves a path given in the config file at configFile
+func configPath(configFile string, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(configFile), path)
+}
+
+// ApplyEnv overrides the settings with the ORGCHART_* environment variables that are set.
+// lookup is usually os.LookupEnv.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	if value, ok := lookup(EnvUpdateEndpoint); ok {
+		c.UpdateEndpoint = value
+	}
+	if value, ok := lookup(EnvQueryEndpoint); ok {
+		c.QueryEndpoint = value
+	}
+	if value, ok := lookup(EnvTimeout); ok {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", EnvTimeout, err)
+		}
+		c.Timeout = timeout
+	}
+	if value, ok := lookup(EnvRetries); ok {
+		attempts, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", EnvRetries, err)
+		}
+		c.Retry.MaxAttempts = attempts
+	}
+	return nil
+}
+
+// ClientOptions returns the options that configure a Client with the timeout and retry policy
+func (c *Config) ClientOptions() []ClientOption {
+	return []ClientOption{
+		WithHTTPClient(&http.Client{Timeout: c.Timeout}),
+		WithRetryPolicy(c.Retry),
+	}
+}
+
+// DataDir returns the data directory of a presidency for the given process type
+func (c *Config) DataDir(presidency string, processType string) (string, error) {
+	dirs, exists := c.Presidencies[presidency]
+	if !exists {
+		names := make([]string, 0, len(c.Presidencies))
+		for name := range c.Presidencies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("unknown presidency %q (configured: %s)", presidency, strings.Join(names, ", "))
+	}
+
+	dir := dirs.Orgchart
+	if processType == "person" {
+		dir = dirs.People
+	}
+	if dir == "" {
+		return "", fmt.Errorf("presidency %q has no %s data directory", presidency, processType)
+	}
+	return dir, nil
+}
+
+// stateDirUnsafe matches the characters of an endpoint that are not used in its state directory
+var stateDirUnsafe = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// StateFile returns the path of a file holding state kept between runs against the Nexoan of the
+// config, such as the entity counters and the journal. The state describes one database, so it
+// lives in a directory named after the update endpoint, under the user's config directory, e.g.
+// ~/.config/orgchart/http_localhost_8080_entities. The directory is created if needed.
+func (c *Config) StateFile(name string) (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find state directory: %w", err)
+	}
+	endpoint := strings.Trim(stateDirUnsafe.ReplaceAllString(c.UpdateEndpoint, "_"), "_")
+	dir := filepath.Join(configDir, "orgchart", endpoint)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create state directory %s: %w", dir, err)
+	}
+	return filepath.Join(dir, name), nil
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+
+	"orgchart_nexoan/models"
+)
+
+// contextBinder is implemented by the stores whose requests can be bound to a context
+type contextBinder interface {
+	// WithContext returns a view of the store that sends every request with ctx
+	WithContext(ctx context.Context) Store
+}
+
+// WithContext returns a Store that sends every request of the client with ctx, so cancelling
+// ctx or reaching its deadline interrupts requests in flight
+func (c *Client) WithContext(ctx context.Context) Store {
+	return &boundClient{client: c, ctx: ctx}
+}
+
+// boundClient is a Client whose requests are sent with a fixed context
+type boundClient struct {
+	client *Client
+	ctx    context.Context
+}
+
+func (b *boundClient) CreateEntity(entity *models.Entity) (*models.Entity, error) {
+	return b.client.CreateEntityContext(b.ctx, entity)
+}
+
+func (b *boundClient) UpdateEntity(id string, entity *models.Entity) (*models.Entity, error) {
+	return b.client.UpdateEntityContext(b.ctx, id, entity)
+}
+
+func (b *boundClient) DeleteEntity(id string) error {
+	return b.client.DeleteEntityContext(b.ctx, id)
+}
+
+func (b *boundClient) SearchEntities(criteria *models.SearchCriteria) ([]models.SearchResult, error) {
+	return b.client.SearchEntitiesContext(b.ctx, criteria)
+}
+
+func (b *boundClient) GetRelatedEntities(entityID string, query *models.Relationship) ([]models.Relationship, error) {
+	return b.client.GetRelatedEntitiesContext(b.ctx, entityID, query)
+}
+
+func (b *boundClient) GetAllRelatedEntities(entityID string) ([]models.Relationship, error) {
+	return b.client.GetAllRelatedEntitiesContext(b.ctx, entityID)
+}
+
+func (b *boundClient) GetRootEntities(kind string) ([]string, error) {
+	return b.client.GetRootEntitiesContext(b.ctx, kind)
+}
+
+func (b *boundClient) GetEntityMetadata(entityID string) (map[string]interface{}, error) {
+	return b.client.GetEntityMetadataContext(b.ctx, entityID)
+}
+
+// bindContext binds the store the processor sends its requests to, or in dry-run mode the store
+// the plan reads from, to ctx until the returned function is called. Stores that cannot be bound
+// are left as they are. The store is swapped in place, so this is not safe while another
+// goroutine uses the processor.
+func (p *Processor) bindContext(ctx context.Context) func() {
+	if p.plan != nil {
+		return p.plan.bindContext(ctx)
+	}
+	binder, ok := p.store.(contextBinder)
+	if !ok {
+		return func() {}
+	}
+	store := p.store
+	p.store = binder.WithContext(ctx)
+	return func() { p.store = store }
+}
+
+// bindContext binds the store the plan reads from to ctx until the returned function is called
+func (p *Plan) bindContext(ctx context.Context) func() {
+	binder, ok := p.store.(contextBinder)
+	if !ok {
+		return func() {}
+	}
+	store := p.store
+	p.store = binder.WithContext(ctx)
+	return func() { p.store = store }
+}
@@ -0,0 +1,371 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"orgchart_nexoan/models"
+)
+
+// DiffEntity identifies an entity in a Diff
+type DiffEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// DiffRelationship is a department or person related to a minister
+type DiffRelationship struct {
+	Entity   DiffEntity `json:"entity"`
+	Minister DiffEntity `json:"minister"`
+}
+
+// DepartmentMove is a department that belongs to another minister on the later date
+type DepartmentMove struct {
+	Department DiffEntity `json:"department"`
+	From       DiffEntity `json:"from"`
+	To         DiffEntity `json:"to"`
+}
+
+// MinisterRename is a minister that was renamed between the two dates (RENAMED_TO)
+type MinisterRename struct {
+	From DiffEntity `json:"from"`
+	To   DiffEntity `json:"to"`
+	Date string     `json:"date"`
+}
+
+// MinisterMerge is a group of ministers that were merged into one between the two dates
+// (MERGED_INTO)
+type MinisterMerge struct {
+	From []DiffEntity `json:"from"`
+	Into DiffEntity   `json:"into"`
+	Date string       `json:"date"`
+}
+
+// Diff lists what changed in the org chart between two dates. Ministers that were renamed or
+// merged are listed under Renames and Merges rather than as added and terminated, and the
+// departments they handed over are not listed as moved.
+type Diff struct {
+	From                  string             `json:"from"`
+	To                    string             `json:"to"`
+	MinistersAdded        []DiffEntity       `json:"ministers_added"`
+	MinistersTerminated   []DiffEntity       `json:"ministers_terminated"`
+	Renames               []MinisterRename   `json:"renames"`
+	Merges                []MinisterMerge    `json:"merges"`
+	DepartmentsAdded      []DiffRelationship `json:"departments_added"`
+	DepartmentsTerminated []DiffRelationship `json:"departments_terminated"`
+	DepartmentsMoved      []DepartmentMove   `json:"departments_moved"`
+	PeopleAppointed       []DiffRelationship `json:"people_appointed"`
+	PeopleRemoved         []DiffRelationship `json:"people_removed"`
+}
+
+// Empty reports whether nothing changed between the two dates
+func (d *Diff) Empty() bool {
+	return len(d.MinistersAdded) == 0 && len(d.MinistersTerminated) == 0 &&
+		len(d.Renames) == 0 && len(d.Merges) == 0 &&
+		len(d.DepartmentsAdded) == 0 && len(d.DepartmentsTerminated) == 0 && len(d.DepartmentsMoved) == 0 &&
+		len(d.PeopleAppointed) == 0 && len(d.PeopleRemoved) == 0
+}
+
+// chartState is the flattened content of a snapshot: the ministers of the government and the
+// ministers each department and person is related to
+type chartState struct {
+	ministers   map[string]DiffEntity
+	departments map[string]map[string]DiffEntity
+	people      map[string]map[string]DiffEntity
+	names       map[string]string
+}
+
+// newChartState flattens a snapshot
+func newChartState(snapshot *Snapshot) *chartState {
+	state := &chartState{
+		ministers:   map[string]DiffEntity{},
+		departments: map[string]map[string]DiffEntity{},
+		people:      map[string]map[string]DiffEntity{},
+		names:       map[string]string{},
+	}
+	for _, minister := range snapshot.Root.Children {
+		if !slices.Contains(snapshotRelationships["government"], minister.Relationship) {
+			continue
+		}
+		ministerEntity := DiffEntity{ID: minister.ID, Name: minister.Name}
+		state.ministers[minister.ID] = ministerEntity
+		state.names[minister.ID] = minister.Name
+		for _, child := range minister.Children {
+			state.names[child.ID] = child.Name
+			var related map[string]map[string]DiffEntity
+			switch child.Relationship {
+			case "AS_DEPARTMENT":
+				related = state.departments
+			case "AS_APPOINTED":
+				related = state.people
+			default:
+				continue
+			}
+			if related[child.ID] == nil {
+				related[child.ID] = map[string]DiffEntity{}
+			}
+			related[child.ID][minister.ID] = ministerEntity
+		}
+	}
+	return state
+}
+
+// entity returns the ID and name of an entity of the state
+func (s *chartState) entity(id string) DiffEntity {
+	return DiffEntity{ID: id, Name: s.names[id]}
+}
+
+// BuildDiff compares the org chart on two dates (YYYY-MM-DD). An empty rootID selects the
+// government root of the store.
+func BuildDiff(store Store, rootID string, fromDate string, toDate string) (*Diff, error) {
+	from, err := time.Parse("2006-01-02", strings.TrimSpace(fromDate))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse from date: %w", err)
+	}
+	to, err := time.Parse("2006-01-02", strings.TrimSpace(toDate))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse to date: %w", err)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from date %s must be before to date %s", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	}
+
+	if rootID == "" {
+		rootID, err = findGovernmentRoot(store)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	before, err := BuildSnapshot(store, rootID, from.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build snapshot on %s: %w", from.Format("2006-01-02"), err)
+	}
+	after, err := BuildSnapshot(store, rootID, to.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build snapshot on %s: %w", to.Format("2006-01-02"), err)
+	}
+	oldState := newChartState(before)
+	newState := newChartState(after)
+
+	diff := &Diff{From: before.Date, To: after.Date}
+
+	// Ministers that left the government are renamed, merged or terminated. successors maps
+	// them to the minister that took over their departments.
+	successors := map[string]string{}
+	mergeIndex := map[string]int{}
+	replaced := map[string]bool{}
+	for _, id := range sortedKeys(oldState.ministers) {
+		if _, ok := newState.ministers[id]; ok {
+			continue
+		}
+		rel, err := successorRelationship(store, id, from.Format(time.RFC3339), to.Format(time.RFC3339))
+		if err != nil {
+			return nil, err
+		}
+		if rel == nil {
+			diff.MinistersTerminated = append(diff.MinistersTerminated, oldState.ministers[id])
+			continue
+		}
+
+		successor := newState.entity(rel.RelatedEntityID)
+		if successor.Name == "" {
+			if node, err := snapshotNode(store, rel.RelatedEntityID); err == nil {
+				successor.Name = node.Name
+			}
+		}
+		successors[id] = rel.RelatedEntityID
+		replaced[rel.RelatedEntityID] = true
+		date := strings.TrimSuffix(rel.StartTime, "T00:00:00Z")
+
+		if rel.Name == "RENAMED_TO" {
+			diff.Renames = append(diff.Renames, MinisterRename{From: oldState.ministers[id], To: successor, Date: date})
+			continue
+		}
+		index, ok := mergeIndex[rel.RelatedEntityID]
+		if !ok {
+			index = len(diff.Merges)
+			mergeIndex[rel.RelatedEntityID] = index
+			diff.Merges = append(diff.Merges, MinisterMerge{Into: successor, Date: date})
+		}
+		diff.Merges[index].From = append(diff.Merges[index].From, oldState.ministers[id])
+	}
+
+	for _, id := range sortedKeys(newState.ministers) {
+		if _, ok := oldState.ministers[id]; !ok && !replaced[id] {
+			diff.MinistersAdded = append(diff.MinistersAdded, newState.ministers[id])
+		}
+	}
+
+	// Departments
+	for _, id := range sortedKeys(oldState.departments) {
+		oldMinisters := oldState.departments[id]
+		newMinisters, ok := newState.departments[id]
+		if !ok {
+			for _, ministerID := range sortedKeys(oldMinisters) {
+				diff.DepartmentsTerminated = append(diff.DepartmentsTerminated, DiffRelationship{Entity: oldState.entity(id), Minister: oldMinisters[ministerID]})
+			}
+			continue
+		}
+		if sharesKey(oldMinisters, newMinisters) {
+			continue
+		}
+
+		oldMinisterID := sortedKeys(oldMinisters)[0]
+		newMinisterID := sortedKeys(newMinisters)[0]
+		if _, ok := newMinisters[successors[oldMinisterID]]; ok {
+			// Handed over by a rename or merge
+			continue
+		}
+		diff.DepartmentsMoved = append(diff.DepartmentsMoved, DepartmentMove{
+			Department: newState.entity(id),
+			From:       oldMinisters[oldMinisterID],
+			To:         newMinisters[newMinisterID],
+		})
+	}
+	for _, id := range sortedKeys(newState.departments) {
+		if _, ok := oldState.departments[id]; ok {
+			continue
+		}
+		for _, ministerID := range sortedKeys(newState.departments[id]) {
+			diff.DepartmentsAdded = append(diff.DepartmentsAdded, DiffRelationship{Entity: newState.entity(id), Minister: newState.departments[id][ministerID]})
+		}
+	}
+
+	// People are compared appointment by appointment, since a person can hold several portfolios
+	for _, id := range sortedKeys(oldState.people) {
+		for _, ministerID := range sortedKeys(oldState.people[id]) {
+			if _, ok := newState.people[id][ministerID]; !ok {
+				diff.PeopleRemoved = append(diff.PeopleRemoved, DiffRelationship{Entity: oldState.entity(id), Minister: oldState.people[id][ministerID]})
+			}
+		}
+	}
+	for _, id := range sortedKeys(newState.people) {
+		for _, ministerID := range sortedKeys(newState.people[id]) {
+			if _, ok := oldState.people[id][ministerID]; !ok {
+				diff.PeopleAppointed = append(diff.PeopleAppointed, DiffRelationship{Entity: newState.entity(id), Minister: newState.people[id][ministerID]})
+			}
+		}
+	}
+
+	return diff, nil
+}
+
+// successorRelationship returns the RENAMED_TO or MERGED_INTO relationship of a minister that
+// started after fromISO and no later than toISO, or nil if there is none. A successor that was
+// itself renamed or merged by toISO is followed, so the relationship returned leads to the minister
+// active at toISO: it starts on the date of the last step and is a MERGED_INTO if any step was.
+func successorRelationship(store Store, ministerID string, fromISO string, toISO string) (*models.Relationship, error) {
+	var successor *models.Relationship
+	visited := map[string]bool{ministerID: true}
+	for {
+		rel, err := nextSuccessor(store, ministerID, fromISO, toISO)
+		if err != nil || rel == nil || visited[rel.RelatedEntityID] {
+			return successor, err
+		}
+		if successor != nil && successor.Name == "MERGED_INTO" {
+			rel.Name = "MERGED_INTO"
+		}
+		successor = rel
+		visited[rel.RelatedEntityID] = true
+		ministerID, fromISO = rel.RelatedEntityID, rel.StartTime
+	}
+}
+
+// nextSuccessor returns the first RENAMED_TO or MERGED_INTO relationship of a minister that
+// started after fromISO and no later than toISO, or nil if there is none
+func nextSuccessor(store Store, ministerID string, fromISO string, toISO string) (*models.Relationship, error) {
+	relations, err := store.GetAllRelatedEntities(ministerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relationships of %s: %w", ministerID, err)
+	}
+	for _, rel := range relations {
+		if rel.Name != "RENAMED_TO" && rel.Name != "MERGED_INTO" {
+			continue
+		}
+		if rel.StartTime > fromISO && rel.StartTime <= toISO {
+			rel := rel
+			return &rel, nil
+		}
+	}
+	return nil, nil
+}
+
+// sortedKeys returns the keys of a map in order, so that the diff is stable
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sharesKey reports whether two maps have a key in common
+func sharesKey[V any](a map[string]V, b map[string]V) bool {
+	for key := range a {
+		if _, ok := b[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes the diff as a report with one section per kind of change
+func (d *Diff) Print(w io.Writer) {
+	fmt.Fprintf(w, "Changes between %s and %s\n", d.From, d.To)
+	if d.Empty() {
+		fmt.Fprintln(w, "No changes")
+		return
+	}
+
+	section := func(title string, count int, line func(i int) string) {
+		if count == 0 {
+			return
+		}
+		fmt.Fprintf(w, "\n%s:\n", title)
+		for i := 0; i < count; i++ {
+			fmt.Fprintf(w, "  %s\n", line(i))
+		}
+	}
+
+	section("Ministers added", len(d.MinistersAdded), func(i int) string {
+		return "+ " + d.MinistersAdded[i].String()
+	})
+	section("Ministers terminated", len(d.MinistersTerminated), func(i int) string {
+		return "- " + d.MinistersTerminated[i].String()
+	})
+	section("Ministers renamed", len(d.Renames), func(i int) string {
+		return fmt.Sprintf("%s -> %s on %s", d.Renames[i].From, d.Renames[i].To, d.Renames[i].Date)
+	})
+	section("Ministers merged", len(d.Merges), func(i int) string {
+		names := make([]string, len(d.Merges[i].From))
+		for j, from := range d.Merges[i].From {
+			names[j] = from.String()
+		}
+		return fmt.Sprintf("%s -> %s on %s", strings.Join(names, ", "), d.Merges[i].Into, d.Merges[i].Date)
+	})
+	section("Departments added", len(d.DepartmentsAdded), func(i int) string {
+		return fmt.Sprintf("+ %s under %s", d.DepartmentsAdded[i].Entity, d.DepartmentsAdded[i].Minister)
+	})
+	section("Departments terminated", len(d.DepartmentsTerminated), func(i int) string {
+		return fmt.Sprintf("- %s under %s", d.DepartmentsTerminated[i].Entity, d.DepartmentsTerminated[i].Minister)
+	})
+	section("Departments moved", len(d.DepartmentsMoved), func(i int) string {
+		return fmt.Sprintf("%s: %s -> %s", d.DepartmentsMoved[i].Department, d.DepartmentsMoved[i].From, d.DepartmentsMoved[i].To)
+	})
+	section("People appointed", len(d.PeopleAppointed), func(i int) string {
+		return fmt.Sprintf("+ %s to %s", d.PeopleAppointed[i].Entity, d.PeopleAppointed[i].Minister)
+	})
+	section("People removed", len(d.PeopleRemoved), func(i int) string {
+		return fmt.Sprintf("- %s from %s", d.PeopleRemoved[i].Entity, d.PeopleRemoved[i].Minister)
+	})
+}
+
+func (e DiffEntity) String() string {
+	return fmt.Sprintf("%s (%s)", e.Name, e.ID)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LoadEntityCounters reads the entity-ID counters saved by a previous run from the given file.
+// A missing file is not an error; it yields an empty set of counters so the first run starts at zero.
+func LoadEntityCounters(path string) (map[string]int, error) {
+	entityCounters := map[string]int{}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return entityCounters, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entity counters from %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, &entityCounters); err != nil {
+		return nil, fmt.Errorf("failed to decode entity counters from %s: %w", path, err)
+	}
+
+	return entityCounters, nil
+}
+
+// SaveEntityCounters writes the entity-ID counters to the given file so that the next run
+// continues numbering where this one stopped. The file is replaced atomically.
+func SaveEntityCounters(path string, entityCounters map[string]int) error {
+	data, err := json.MarshalIndent(entityCounters, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode entity counters: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", path, err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(append(data, '\n')); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write entity counters to %s: %w", tmpFile.Name(), err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write entity counters to %s: %w", tmpFile.Name(), err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to save entity counters to %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -8,42 +9,38 @@ import (
 	"orgchart_nexoan/models"
 )
 
-// CreateGovernmentNode creates the initial government node
-func (c *Client) CreateGovernmentNode() (*models.Entity, error) {
-	// Create the government entity
-	governmentEntity := &models.Entity{
-		ID:      "gov_01",
-		Created: "2024-01-01T00:00:00Z",
-		Kind: models.Kind{
-			Major: "Organisation",
-			Minor: "government",
-		},
-		Name: models.TimeBasedValue{
-			StartTime: "2024-01-01T00:00:00Z",
-			Value:     "Government of Sri Lanka",
-		},
-	}
+// EntityExistsError is returned by AddOrgEntity when an entity of the same kind and name is
+// already active under the parent, so adding it again would create a duplicate.
+type EntityExistsError struct {
+	EntityID string
+	Kind     string
+	Name     string
+	ParentID string
+}
 
-	// Create the entity
-	createdEntity, err := c.CreateEntity(governmentEntity)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create government entity: %w", err)
-	}
+func (e *EntityExistsError) Error() string {
+	return fmt.Sprintf("%s %q already exists as %s under %s", e.Kind, e.Name, e.EntityID, e.ParentID)
+}
 
-	return createdEntity, nil
+// entityID returns the ID of the entity of the given kind created by a transaction with the given
+// counter value, e.g. 2153-12_min_3. The transaction ID must have at least 7 characters.
+func entityID(transactionID string, kind string, counter int) string {
+	return fmt.Sprintf("%s_%s_%d", transactionID[:7], strings.ToLower(kind[:3]), counter)
 }
 
 // AddOrgEntity creates a new entity and establishes its relationship with a parent entity.
-// Assumes the parent entity already exists.
-func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+// Assumes the parent entity already exists. It returns the counter of the child type, which is
+// the one used for the new entity's ID once the entity creation was requested, even on error:
+// a request that fails, e.g. on a timeout, may still have created the entity.
+func (p *Processor) AddOrgEntity(transaction AddTransaction, entityCounters map[string]int) (int, error) {
 	// Extract details from the transaction
-	parent := transaction["parent"].(string)
-	child := transaction["child"].(string)
-	dateStr := transaction["date"].(string)
-	parentType := transaction["parent_type"].(string)
-	childType := transaction["child_type"].(string)
-	relType := transaction["rel_type"].(string)
-	transactionID := transaction["transaction_id"].(string)
+	parent := transaction.Parent
+	child := transaction.Child
+	dateStr := transaction.Date
+	parentType := transaction.ParentType
+	childType := transaction.ChildType
+	relType := transaction.RelType
+	transactionID := transaction.TransactionID
 
 	// Parse the date
 	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
@@ -56,10 +53,12 @@ func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters
 	if _, exists := entityCounters[childType]; !exists {
 		return 0, fmt.Errorf("unknown child type: %s", childType)
 	}
+	if len(transactionID) < 7 {
+		return 0, fmt.Errorf("transaction id too short to derive an entity id: %s", transactionID)
+	}
 
-	prefix := fmt.Sprintf("%s_%s", transactionID[:7], strings.ToLower(childType[:3]))
 	entityCounter := entityCounters[childType] + 1
-	newEntityID := fmt.Sprintf("%s_%d", prefix, entityCounter)
+	newEntityID := entityID(transactionID, childType, entityCounter)
 
 	// Get the parent entity ID
 	searchCriteria := &models.SearchCriteria{
@@ -70,7 +69,7 @@ func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters
 		Name: parent,
 	}
 
-	searchResults, err := c.SearchEntities(searchCriteria)
+	searchResults, err := p.store.SearchEntities(searchCriteria)
 	if err != nil {
 		return 0, fmt.Errorf("failed to search for parent entity: %w", err)
 	}
@@ -81,6 +80,33 @@ func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters
 
 	parentID := searchResults[0].ID
 
+	// Reuse an entity of the same kind and name that is already active under the parent
+	existingResults, err := p.store.SearchEntities(&models.SearchCriteria{
+		Kind: &models.Kind{
+			Major: "Organisation",
+			Minor: childType,
+		},
+		Name: child,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to search for existing child entity: %w", err)
+	}
+	for _, existing := range existingResults {
+		activeRel, err := p.activeRelationship(parentID, existing.ID, relType, dateISO)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check existing child entity: %w", err)
+		}
+		if activeRel != nil {
+			p.recordReuse(existing.ID)
+			return entityCounters[childType], &EntityExistsError{
+				EntityID: existing.ID,
+				Kind:     childType,
+				Name:     child,
+				ParentID: parentID,
+			}
+		}
+	}
+
 	// Create the new child entity
 	childEntity := &models.Entity{
 		ID: newEntityID,
@@ -99,10 +125,10 @@ func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters
 		Relationships: []models.RelationshipEntry{},
 	}
 
-	// Create the child entity
-	createdChild, err := c.CreateEntity(childEntity)
+	// Create the child entity. Its ID is used up once the request is sent.
+	createdChild, err := p.store.CreateEntity(childEntity)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create child entity: %w", err)
+		return entityCounter, fmt.Errorf("failed to create child entity: %w", err)
 	}
 
 	// Update the parent entity to add the relationship to the child
@@ -128,23 +154,23 @@ func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters
 		},
 	}
 
-	_, err = c.UpdateEntity(parentID, parentEntity)
+	_, err = p.store.UpdateEntity(parentID, parentEntity)
 	if err != nil {
-		return 0, fmt.Errorf("failed to update parent entity: %w", err)
+		return entityCounter, fmt.Errorf("failed to update parent entity: %w", err)
 	}
 
 	return entityCounter, nil
 }
 
 // TerminateOrgEntity terminates a specific relationship between parent and child at a given date
-func (c *Client) TerminateOrgEntity(transaction map[string]interface{}) error {
+func (p *Processor) TerminateOrgEntity(transaction TerminateTransaction) error {
 	// Extract details from the transaction
-	parent := transaction["parent"].(string)
-	child := transaction["child"].(string)
-	dateStr := transaction["date"].(string)
-	parentType := transaction["parent_type"].(string)
-	childType := transaction["child_type"].(string)
-	relType := transaction["rel_type"].(string)
+	parent := transaction.Parent
+	child := transaction.Child
+	dateStr := transaction.Date
+	parentType := transaction.ParentType
+	childType := transaction.ChildType
+	relType := transaction.RelType
 
 	// Parse the date
 	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
@@ -161,7 +187,7 @@ func (c *Client) TerminateOrgEntity(transaction map[string]interface{}) error {
 		},
 		Name: parent,
 	}
-	parentResults, err := c.SearchEntities(searchCriteria)
+	parentResults, err := p.store.SearchEntities(searchCriteria)
 	if err != nil {
 		return fmt.Errorf("failed to search for parent entity: %w", err)
 	}
@@ -173,7 +199,7 @@ func (c *Client) TerminateOrgEntity(transaction map[string]interface{}) error {
 	// Get the child entity ID
 	searchCriteria.Kind.Minor = childType
 	searchCriteria.Name = child
-	childResults, err := c.SearchEntities(searchCriteria)
+	childResults, err := p.store.SearchEntities(searchCriteria)
 	if err != nil {
 		return fmt.Errorf("failed to search for child entity: %w", err)
 	}
@@ -185,7 +211,7 @@ func (c *Client) TerminateOrgEntity(transaction map[string]interface{}) error {
 	// If we're terminating a minister, check for active departments
 	if childType == "minister" {
 		// Get all relationships for the minister
-		relations, err := c.GetAllRelatedEntities(childID)
+		relations, err := p.store.GetAllRelatedEntities(childID)
 		if err != nil {
 			return fmt.Errorf("failed to get minister's relationships: %w", err)
 		}
@@ -198,32 +224,18 @@ func (c *Client) TerminateOrgEntity(transaction map[string]interface{}) error {
 		}
 	}
 
-	// Get the specific relationship that is still active (no end date) -> this should give us the relationship(s) active for dateISO
-	relations, err := c.GetRelatedEntities(parentID, &models.Relationship{
-		RelatedEntityID: childID,
-		Name:            relType,
-		StartTime:       dateISO,
-	})
+	// Get the specific relationship that is still active (no end date) at dateISO
+	activeRel, err := p.activeRelationship(parentID, childID, relType, dateISO)
 	if err != nil {
 		return fmt.Errorf("failed to get relationship: %w", err)
 	}
 
-	// FIXME: Is it possible to have more than one active relationship? For orgchart case only it won't happen
-	// Find the active relationship (no end time)
-	var activeRel *models.Relationship
-	for _, rel := range relations {
-		if rel.RelatedEntityID == childID && rel.EndTime == "" {
-			activeRel = &rel
-			break
-		}
-	}
-
 	if activeRel == nil {
 		return fmt.Errorf("no active relationship found between %s and %s with type %s", parentID, childID, relType)
 	}
 
 	// Update the relationship to set the end date
-	_, err = c.UpdateEntity(parentID, &models.Entity{
+	_, err = p.store.UpdateEntity(parentID, &models.Entity{
 		ID: parentID,
 		Relationships: []models.RelationshipEntry{
 			{
@@ -242,13 +254,61 @@ func (c *Client) TerminateOrgEntity(transaction map[string]interface{}) error {
 	return nil
 }
 
+// activeRelationship returns the relationship of type relType from parentID to childID that is
+// active at dateISO and has no end time, or nil if there is none
+func (p *Processor) activeRelationship(parentID string, childID string, relType string, dateISO string) (*models.Relationship, error) {
+	relations, err := p.store.GetRelatedEntities(parentID, &models.Relationship{
+		RelatedEntityID: childID,
+		Name:            relType,
+		StartTime:       dateISO,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// FIXME: Is it possible to have more than one active relationship? For orgchart case only it won't happen
+	for _, rel := range relations {
+		if rel.RelatedEntityID == childID && rel.EndTime == "" {
+			return &rel, nil
+		}
+	}
+	return nil, nil
+}
+
+// ministerParent returns the root entity a minister is related to at dateISO, such as the
+// government or a provincial council, and the relationship from it to the minister
+func (p *Processor) ministerParent(ministerID string, dateISO string) (models.SearchResult, models.Relationship, error) {
+	roots, err := graphRoots(p.store)
+	if err != nil {
+		return models.SearchResult{}, models.Relationship{}, err
+	}
+	relations, err := incomingRelationships(p.store, ministerID, roots, models.Relationship{StartTime: dateISO})
+	if err != nil {
+		return models.SearchResult{}, models.Relationship{}, fmt.Errorf("failed to get relationships pointing to minister %s: %w", ministerID, err)
+	}
+
+	for _, rel := range relations {
+		if rel.EndTime != "" {
+			continue
+		}
+		results, err := p.store.SearchEntities(&models.SearchCriteria{ID: rel.RelatedEntityID})
+		if err != nil {
+			return models.SearchResult{}, models.Relationship{}, fmt.Errorf("failed to search for parent entity: %w", err)
+		}
+		if len(results) > 0 {
+			return results[0], rel, nil
+		}
+	}
+	return models.SearchResult{}, models.Relationship{}, fmt.Errorf("no active parent found for minister %s", ministerID)
+}
+
 // MoveDepartment moves a department from one minister to another
-func (c *Client) MoveDepartment(transaction map[string]interface{}) error {
+func (p *Processor) MoveDepartment(transaction MoveTransaction) error {
 	// Extract details from the transaction
-	newParent := transaction["new_parent"].(string)
-	oldParent := transaction["old_parent"].(string)
-	child := transaction["child"].(string)
-	dateStr := transaction["date"].(string)
+	newParent := transaction.NewParent
+	oldParent := transaction.OldParent
+	child := transaction.Child
+	dateStr := transaction.Date
 
 	// Parse the date
 	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
@@ -258,7 +318,7 @@ func (c *Client) MoveDepartment(transaction map[string]interface{}) error {
 	dateISO := date.Format(time.RFC3339)
 
 	// Get the new minister (parent) entity ID
-	newParentResults, err := c.SearchEntities(&models.SearchCriteria{
+	newParentResults, err := p.store.SearchEntities(&models.SearchCriteria{
 		Kind: &models.Kind{
 			Major: "Organisation",
 			Minor: "minister",
@@ -274,7 +334,7 @@ func (c *Client) MoveDepartment(transaction map[string]interface{}) error {
 	newParentID := newParentResults[0].ID
 
 	// Get the department (child) entity ID
-	childResults, err := c.SearchEntities(&models.SearchCriteria{
+	childResults, err := p.store.SearchEntities(&models.SearchCriteria{
 		Kind: &models.Kind{
 			Major: "Organisation",
 			Minor: "department",
@@ -306,22 +366,23 @@ func (c *Client) MoveDepartment(transaction map[string]interface{}) error {
 		},
 	}
 
-	_, err = c.UpdateEntity(newParentID, newRelationship)
+	_, err = p.store.UpdateEntity(newParentID, newRelationship)
 	if err != nil {
 		return fmt.Errorf("failed to create new relationship: %w", err)
 	}
 
 	// Terminate the old relationship
-	terminateTransaction := map[string]interface{}{
-		"parent":      oldParent,
-		"child":       child,
-		"date":        dateStr,
-		"parent_type": "minister",
-		"child_type":  "department",
-		"rel_type":    "AS_DEPARTMENT",
+	terminateTransaction := TerminateTransaction{
+		TransactionID: transaction.TransactionID,
+		Parent:        oldParent,
+		Child:         child,
+		Date:          dateStr,
+		ParentType:    "minister",
+		ChildType:     "department",
+		RelType:       "AS_DEPARTMENT",
 	}
 
-	err = c.TerminateOrgEntity(terminateTransaction)
+	err = p.TerminateOrgEntity(terminateTransaction)
 	if err != nil {
 		return fmt.Errorf("failed to terminate old relationship: %w", err)
 	}
@@ -329,14 +390,32 @@ func (c *Client) MoveDepartment(transaction map[string]interface{}) error {
 	return nil
 }
 
-// RenameMinister renames a minister and transfers all its departments to the new minister
-func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+// replacementRelationship returns the name of the relationship from the parent of the old
+// minister of a RENAME or MERGE to the new one. The type column holds either that name, such as
+// AS_MINISTER, or the kind of the replaced entities, as in data/orgchart, in which case the new
+// minister is related by the same relationship as the old one. So is it when the type is empty.
+// Only ministers can be replaced.
+func replacementRelationship(transactionType string, parentRel models.Relationship) (string, error) {
+	transactionType = strings.TrimSpace(transactionType)
+	if strings.HasPrefix(transactionType, "AS_") {
+		return transactionType, nil
+	}
+	if transactionType != "" && transactionType != "minister" {
+		return "", fmt.Errorf("unsupported type %q: only ministers can be renamed or merged", transactionType)
+	}
+	return parentRel.Name, nil
+}
+
+// RenameMinister renames a minister and transfers all its departments to the new minister. The
+// new minister is placed under the parent of the old one, such as the government or a provincial
+// council, by the relationship named by the type column of the transaction (see
+// replacementRelationship).
+func (p *Processor) RenameMinister(transaction RenameTransaction, entityCounters map[string]int) (int, error) {
 	// Extract details from the transaction
-	oldName := transaction["old"].(string)
-	newName := transaction["new"].(string)
-	dateStr := transaction["date"].(string)
-	relType := transaction["type"].(string)
-	transactionID := transaction["transaction_id"]
+	oldName := transaction.Old
+	newName := transaction.New
+	dateStr := transaction.Date
+	transactionID := transaction.TransactionID
 
 	// Parse the date
 	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
@@ -346,7 +425,7 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 	dateISO := date.Format(time.RFC3339)
 
 	// Get the old minister's ID
-	oldMinisterResults, err := c.SearchEntities(&models.SearchCriteria{
+	oldMinisterResults, err := p.store.SearchEntities(&models.SearchCriteria{
 		Kind: &models.Kind{
 			Major: "Organisation",
 			Minor: "minister",
@@ -361,58 +440,58 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 	}
 	oldMinisterID := oldMinisterResults[0].ID
 
-	// Create new minister
-	addEntityTransaction := map[string]interface{}{
-		"parent":         "Government of Sri Lanka",
-		"child":          newName,
-		"date":           dateStr,
-		"parent_type":    "government",
-		"child_type":     "minister",
-		"rel_type":       relType,
-		"transaction_id": transactionID,
-	}
-
-	// Create the new minister
-	newMinisterCounter, err := c.AddOrgEntity(addEntityTransaction, entityCounters)
+	// The new minister takes the place of the old one under the same parent
+	parent, parentRel, err := p.ministerParent(oldMinisterID, dateISO)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create new minister: %w", err)
+		return 0, err
 	}
-
-	// Get the new minister's ID
-	newMinisterResults, err := c.SearchEntities(&models.SearchCriteria{
-		Kind: &models.Kind{
-			Major: "Organisation",
-			Minor: "minister",
-		},
-		Name: newName,
-	})
+	relType, err := replacementRelationship(transaction.Type, parentRel)
 	if err != nil {
-		return 0, fmt.Errorf("failed to search for new minister: %w", err)
+		return 0, err
 	}
-	if len(newMinisterResults) == 0 {
-		return 0, fmt.Errorf("new minister not found: %s", newName)
+
+	// Create new minister
+	addEntityTransaction := AddTransaction{
+		TransactionID: transactionID,
+		Parent:        parent.Name,
+		Child:         newName,
+		Date:          dateStr,
+		ParentType:    parent.Kind.Minor,
+		ChildType:     "minister",
+		RelType:       relType,
+	}
+
+	// Create the new minister, reusing an existing minister with the new name
+	newMinisterCounter, err := p.AddOrgEntity(addEntityTransaction, entityCounters)
+	var newMinisterID string
+	var existsErr *EntityExistsError
+	if errors.As(err, &existsErr) {
+		newMinisterID = existsErr.EntityID
+	} else if err != nil {
+		return newMinisterCounter, fmt.Errorf("failed to create new minister: %w", err)
+	} else {
+		newMinisterID = entityID(transactionID, "minister", newMinisterCounter)
 	}
-	newMinisterID := newMinisterResults[0].ID
 
 	// Get all active departments of the old minister
-	oldRelations, err := c.GetAllRelatedEntities(oldMinisterID)
+	oldRelations, err := p.store.GetAllRelatedEntities(oldMinisterID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get old minister's relationships: %w", err)
+		return newMinisterCounter, fmt.Errorf("failed to get old minister's relationships: %w", err)
 	}
 
 	// Transfer each active department to the new minister
 	for _, rel := range oldRelations {
 		if rel.Name == "AS_DEPARTMENT" && rel.EndTime == "" {
 			// Get the department name using its ID
-			departmentResults, err := c.SearchEntities(&models.SearchCriteria{
+			departmentResults, err := p.store.SearchEntities(&models.SearchCriteria{
 				ID: rel.RelatedEntityID,
 			})
 			if err != nil {
-				return 0, fmt.Errorf("failed to search for department: %w", err)
+				return newMinisterCounter, fmt.Errorf("failed to search for department: %w", err)
 			}
 
 			if len(departmentResults) == 0 {
-				return 0, fmt.Errorf("failed to find department with ID: %s", rel.RelatedEntityID)
+				return newMinisterCounter, fmt.Errorf("failed to find department with ID: %s", rel.RelatedEntityID)
 			}
 
 			// Create new relationship between new minister and department
@@ -432,41 +511,43 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 				},
 			}
 
-			_, err = c.UpdateEntity(newMinisterID, newRelationship)
+			_, err = p.store.UpdateEntity(newMinisterID, newRelationship)
 			if err != nil {
-				return 0, fmt.Errorf("failed to create new department relationship: %w", err)
+				return newMinisterCounter, fmt.Errorf("failed to create new department relationship: %w", err)
 			}
 
 			// Terminate the old relationship
-			terminateTransaction := map[string]interface{}{
-				"parent":      oldName,
-				"child":       departmentResults[0].Name,
-				"date":        dateStr,
-				"parent_type": "minister",
-				"child_type":  "department",
-				"rel_type":    "AS_DEPARTMENT",
+			terminateTransaction := TerminateTransaction{
+				TransactionID: transactionID,
+				Parent:        oldName,
+				Child:         departmentResults[0].Name,
+				Date:          dateStr,
+				ParentType:    "minister",
+				ChildType:     "department",
+				RelType:       "AS_DEPARTMENT",
 			}
 
-			err = c.TerminateOrgEntity(terminateTransaction)
+			err = p.TerminateOrgEntity(terminateTransaction)
 			if err != nil {
-				return 0, fmt.Errorf("failed to terminate old department relationship: %w", err)
+				return newMinisterCounter, fmt.Errorf("failed to terminate old department relationship: %w", err)
 			}
 		}
 	}
 
-	// Terminate the old minister's relationship with government
-	terminateGovTransaction := map[string]interface{}{
-		"parent":      "Government of Sri Lanka",
-		"child":       oldName,
-		"date":        dateStr,
-		"parent_type": "government",
-		"child_type":  "minister",
-		"rel_type":    relType,
+	// Terminate the old minister's relationship with its parent
+	terminateParentTransaction := TerminateTransaction{
+		TransactionID: transactionID,
+		Parent:        parent.Name,
+		Child:         oldName,
+		Date:          dateStr,
+		ParentType:    parent.Kind.Minor,
+		ChildType:     "minister",
+		RelType:       parentRel.Name,
 	}
 
-	err = c.TerminateOrgEntity(terminateGovTransaction)
+	err = p.TerminateOrgEntity(terminateParentTransaction)
 	if err != nil {
-		return 0, fmt.Errorf("failed to terminate old minister's government relationship: %w", err)
+		return newMinisterCounter, fmt.Errorf("failed to terminate old minister's parent relationship: %w", err)
 	}
 
 	// Create RENAMED_TO relationship
@@ -486,21 +567,23 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 		},
 	}
 
-	_, err = c.UpdateEntity(oldMinisterID, renameRelationship)
+	_, err = p.store.UpdateEntity(oldMinisterID, renameRelationship)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create RENAMED_TO relationship: %w", err)
+		return newMinisterCounter, fmt.Errorf("failed to create RENAMED_TO relationship: %w", err)
 	}
 
 	return newMinisterCounter, nil
 }
 
-// MergeMinisters merges multiple ministers into a new minister
-func (c *Client) MergeMinisters(transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+// MergeMinisters merges multiple ministers into a new minister, which is placed under the parent
+// of the first of them by the relationship named by the type column of the transaction (see
+// replacementRelationship)
+func (p *Processor) MergeMinisters(transaction MergeTransaction, entityCounters map[string]int) (int, error) {
 	// Extract details from the transaction
-	oldMinistersStr := transaction["old"].(string)
-	newMinister := transaction["new"].(string)
-	dateStr := transaction["date"].(string)
-	transactionID := transaction["transaction_id"].(string)
+	oldMinisters := transaction.OldNames()
+	newMinister := transaction.New
+	dateStr := transaction.Date
+	transactionID := transaction.TransactionID
 
 	// Parse the date
 	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
@@ -509,48 +592,60 @@ func (c *Client) MergeMinisters(transaction map[string]interface{}, entityCounte
 	}
 	dateISO := date.Format(time.RFC3339)
 
-	// Parse old ministers list
-	oldMinisters := strings.Split(strings.Trim(oldMinistersStr, "[]"), ",")
-	for i := range oldMinisters {
-		oldMinisters[i] = strings.TrimSpace(oldMinisters[i])
+	if len(oldMinisters) == 0 {
+		return 0, fmt.Errorf("no ministers to merge into %s", newMinister)
 	}
 
-	// 1. Create new minister using AddEntity
-	addEntityTransaction := map[string]interface{}{
-		"parent":         "Government of Sri Lanka",
-		"child":          newMinister,
-		"date":           dateStr,
-		"parent_type":    "government",
-		"child_type":     "minister",
-		"rel_type":       "AS_MINISTER",
-		"transaction_id": transactionID,
-	}
-
-	newMinisterCounter, err := c.AddOrgEntity(addEntityTransaction, entityCounters)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create new minister: %w", err)
-	}
-
-	// Get the new minister's ID
-	newMinisterResults, err := c.SearchEntities(&models.SearchCriteria{
+	// The new minister is placed under the parent of the first old minister
+	firstResults, err := p.store.SearchEntities(&models.SearchCriteria{
 		Kind: &models.Kind{
 			Major: "Organisation",
 			Minor: "minister",
 		},
-		Name: newMinister,
+		Name: oldMinisters[0],
 	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to search for new minister: %w", err)
+		return 0, fmt.Errorf("failed to search for old minister: %w", err)
+	}
+	if len(firstResults) == 0 {
+		return 0, fmt.Errorf("old minister not found: %s", oldMinisters[0])
 	}
-	if len(newMinisterResults) == 0 {
-		return 0, fmt.Errorf("new minister not found: %s", newMinister)
+	parent, parentRel, err := p.ministerParent(firstResults[0].ID, dateISO)
+	if err != nil {
+		return 0, err
+	}
+	relType, err := replacementRelationship(transaction.Type, parentRel)
+	if err != nil {
+		return 0, err
+	}
+
+	// 1. Create new minister using AddEntity
+	addEntityTransaction := AddTransaction{
+		TransactionID: transactionID,
+		Parent:        parent.Name,
+		Child:         newMinister,
+		Date:          dateStr,
+		ParentType:    parent.Kind.Minor,
+		ChildType:     "minister",
+		RelType:       relType,
+	}
+
+	// An existing minister with the new name is reused
+	newMinisterCounter, err := p.AddOrgEntity(addEntityTransaction, entityCounters)
+	var newMinisterID string
+	var existsErr *EntityExistsError
+	if errors.As(err, &existsErr) {
+		newMinisterID = existsErr.EntityID
+	} else if err != nil {
+		return newMinisterCounter, fmt.Errorf("failed to create new minister: %w", err)
+	} else {
+		newMinisterID = entityID(transactionID, "minister", newMinisterCounter)
 	}
-	newMinisterID := newMinisterResults[0].ID
 
 	// For each old minister
 	for _, oldMinister := range oldMinisters {
 		// Get the old minister's ID
-		oldMinisterResults, err := c.SearchEntities(&models.SearchCriteria{
+		oldMinisterResults, err := p.store.SearchEntities(&models.SearchCriteria{
 			Kind: &models.Kind{
 				Major: "Organisation",
 				Minor: "minister",
@@ -558,61 +653,67 @@ func (c *Client) MergeMinisters(transaction map[string]interface{}, entityCounte
 			Name: oldMinister,
 		})
 		if err != nil {
-			return 0, fmt.Errorf("failed to search for old minister: %w", err)
+			return newMinisterCounter, fmt.Errorf("failed to search for old minister: %w", err)
 		}
 		if len(oldMinisterResults) == 0 {
-			return 0, fmt.Errorf("old minister not found: %s", oldMinister)
+			return newMinisterCounter, fmt.Errorf("old minister not found: %s", oldMinister)
 		}
 		oldMinisterID := oldMinisterResults[0].ID
 
 		// 2. Move old minister's departments to new minister
-		oldRelations, err := c.GetAllRelatedEntities(oldMinisterID)
+		oldRelations, err := p.store.GetAllRelatedEntities(oldMinisterID)
 		if err != nil {
-			return 0, fmt.Errorf("failed to get old minister's relationships: %w", err)
+			return newMinisterCounter, fmt.Errorf("failed to get old minister's relationships: %w", err)
 		}
 
 		for _, rel := range oldRelations {
 			if rel.Name == "AS_DEPARTMENT" && rel.EndTime == "" {
 				// Get the department name using its ID
-				departmentResults, err := c.SearchEntities(&models.SearchCriteria{
+				departmentResults, err := p.store.SearchEntities(&models.SearchCriteria{
 					ID: rel.RelatedEntityID,
 				})
 				if err != nil {
-					return 0, fmt.Errorf("failed to search for department: %w", err)
+					return newMinisterCounter, fmt.Errorf("failed to search for department: %w", err)
 				}
 				if len(departmentResults) == 0 {
-					return 0, fmt.Errorf("failed to find department with ID: %s", rel.RelatedEntityID)
+					return newMinisterCounter, fmt.Errorf("failed to find department with ID: %s", rel.RelatedEntityID)
 				}
 
 				// Move department to new minister
-				moveTransaction := map[string]interface{}{
-					"old_parent": oldMinister,
-					"new_parent": newMinister,
-					"child":      departmentResults[0].Name,
-					"type":       "AS_DEPARTMENT",
-					"date":       dateStr,
+				moveTransaction := MoveTransaction{
+					TransactionID: transactionID,
+					OldParent:     oldMinister,
+					NewParent:     newMinister,
+					Child:         departmentResults[0].Name,
+					Type:          "AS_DEPARTMENT",
+					Date:          dateStr,
 				}
 
-				err = c.MoveDepartment(moveTransaction)
+				err = p.MoveDepartment(moveTransaction)
 				if err != nil {
-					return 0, fmt.Errorf("failed to move department: %w", err)
+					return newMinisterCounter, fmt.Errorf("failed to move department: %w", err)
 				}
 			}
 		}
 
-		// 3. Terminate gov -> old minister relationship
-		terminateGovTransaction := map[string]interface{}{
-			"parent":      "Government of Sri Lanka",
-			"child":       oldMinister,
-			"date":        dateStr,
-			"parent_type": "government",
-			"child_type":  "minister",
-			"rel_type":    "AS_MINISTER",
+		// 3. Terminate parent -> old minister relationship
+		oldParent, oldParentRel, err := p.ministerParent(oldMinisterID, dateISO)
+		if err != nil {
+			return newMinisterCounter, err
+		}
+		terminateParentTransaction := TerminateTransaction{
+			TransactionID: transactionID,
+			Parent:        oldParent.Name,
+			Child:         oldMinister,
+			Date:          dateStr,
+			ParentType:    oldParent.Kind.Minor,
+			ChildType:     "minister",
+			RelType:       oldParentRel.Name,
 		}
 
-		err = c.TerminateOrgEntity(terminateGovTransaction)
+		err = p.TerminateOrgEntity(terminateParentTransaction)
 		if err != nil {
-			return 0, fmt.Errorf("failed to terminate old minister's government relationship: %w", err)
+			return newMinisterCounter, fmt.Errorf("failed to terminate old minister's parent relationship: %w", err)
 		}
 
 		// 4. Create old minister -> new minister MERGED_INTO relationship
@@ -632,9 +733,9 @@ func (c *Client) MergeMinisters(transaction map[string]interface{}, entityCounte
 			},
 		}
 
-		_, err = c.UpdateEntity(oldMinisterID, mergedIntoRelationship)
+		_, err = p.store.UpdateEntity(oldMinisterID, mergedIntoRelationship)
 		if err != nil {
-			return 0, fmt.Errorf("failed to create MERGED_INTO relationship: %w", err)
+			return newMinisterCounter, fmt.Errorf("failed to create MERGED_INTO relationship: %w", err)
 		}
 	}
 
@@ -642,16 +743,17 @@ func (c *Client) MergeMinisters(transaction map[string]interface{}, entityCounte
 }
 
 // AddPersonEntity creates a new person entity and establishes its relationship with a parent entity.
-// Assumes the parent entity already exists.
-func (c *Client) AddPersonEntity(transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+// Assumes the parent entity already exists. Like AddOrgEntity it returns the counter used for the
+// new entity's ID even on error.
+func (p *Processor) AddPersonEntity(transaction AddTransaction, entityCounters map[string]int) (int, error) {
 	// Extract details from the transaction
-	parent := transaction["parent"].(string)
-	child := transaction["child"].(string)
-	dateStr := transaction["date"].(string)
-	parentType := transaction["parent_type"].(string)
-	childType := transaction["child_type"].(string)
-	relType := transaction["rel_type"].(string)
-	transactionID := transaction["transaction_id"].(string)
+	parent := transaction.Parent
+	child := transaction.Child
+	dateStr := transaction.Date
+	parentType := transaction.ParentType
+	childType := transaction.ChildType
+	relType := transaction.RelType
+	transactionID := transaction.TransactionID
 
 	// Parse the date
 	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
@@ -669,7 +771,7 @@ func (c *Client) AddPersonEntity(transaction map[string]interface{}, entityCount
 		Name: parent,
 	}
 
-	searchResults, err := c.SearchEntities(searchCriteria)
+	searchResults, err := p.store.SearchEntities(searchCriteria)
 	if err != nil {
 		return 0, fmt.Errorf("failed to search for parent entity: %w", err)
 	}
@@ -688,7 +790,7 @@ func (c *Client) AddPersonEntity(transaction map[string]interface{}, entityCount
 		Name: child,
 	}
 
-	personResults, err := c.SearchEntities(personSearchCriteria)
+	personResults, err := p.store.SearchEntities(personSearchCriteria)
 	if err != nil {
 		return 0, fmt.Errorf("failed to search for person entity: %w", err)
 	}
@@ -698,7 +800,7 @@ func (c *Client) AddPersonEntity(transaction map[string]interface{}, entityCount
 	}
 
 	var childID string
-	entityCounter := 0
+	entityCounter := entityCounters[childType]
 	if len(personResults) == 1 {
 		// Person exists, use existing ID
 		childID = personResults[0].ID
@@ -707,9 +809,12 @@ func (c *Client) AddPersonEntity(transaction map[string]interface{}, entityCount
 		if _, exists := entityCounters[childType]; !exists {
 			return 0, fmt.Errorf("unknown child type: %s", childType)
 		}
+		if len(transactionID) < 7 {
+			return 0, fmt.Errorf("transaction id too short to derive an entity id: %s", transactionID)
+		}
 
 		prefix := fmt.Sprintf("%s_%s", transactionID[:7], strings.ToLower(childType[:3]))
-		entityCounter := entityCounters[childType] + 1
+		entityCounter = entityCounters[childType] + 1
 		newEntityID := fmt.Sprintf("%s_%d", prefix, entityCounter)
 
 		// Create the new child entity
@@ -730,10 +835,10 @@ func (c *Client) AddPersonEntity(transaction map[string]interface{}, entityCount
 			Relationships: []models.RelationshipEntry{},
 		}
 
-		// Create the child entity
-		createdChild, err := c.CreateEntity(childEntity)
+		// Create the child entity. Its ID is used up once the request is sent.
+		createdChild, err := p.store.CreateEntity(childEntity)
 		if err != nil {
-			return 0, fmt.Errorf("failed to create child entity: %w", err)
+			return entityCounter, fmt.Errorf("failed to create child entity: %w", err)
 		}
 		childID = createdChild.ID
 	}
@@ -761,23 +866,23 @@ func (c *Client) AddPersonEntity(transaction map[string]interface{}, entityCount
 		},
 	}
 
-	_, err = c.UpdateEntity(parentID, parentEntity)
+	_, err = p.store.UpdateEntity(parentID, parentEntity)
 	if err != nil {
-		return 0, fmt.Errorf("failed to update parent entity: %w", err)
+		return entityCounter, fmt.Errorf("failed to update parent entity: %w", err)
 	}
 
 	return entityCounter, nil
 }
 
 // TerminatePersonEntity terminates a specific relationship between Person type entity and another entity at a given date
-func (c *Client) TerminatePersonEntity(transaction map[string]interface{}) error {
+func (p *Processor) TerminatePersonEntity(transaction TerminateTransaction) error {
 	// Extract details from the transaction
-	parent := transaction["parent"].(string)
-	child := transaction["child"].(string)
-	dateStr := transaction["date"].(string)
-	parentType := transaction["parent_type"].(string)
-	childType := transaction["child_type"].(string)
-	relType := transaction["rel_type"].(string)
+	parent := transaction.Parent
+	child := transaction.Child
+	dateStr := transaction.Date
+	parentType := transaction.ParentType
+	childType := transaction.ChildType
+	relType := transaction.RelType
 
 	// Parse the date
 	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
@@ -794,7 +899,7 @@ func (c *Client) TerminatePersonEntity(transaction map[string]interface{}) error
 		},
 		Name: parent,
 	}
-	parentResults, err := c.SearchEntities(searchCriteria)
+	parentResults, err := p.store.SearchEntities(searchCriteria)
 	if err != nil {
 		return fmt.Errorf("failed to search for parent entity: %w", err)
 	}
@@ -812,7 +917,7 @@ func (c *Client) TerminatePersonEntity(transaction map[string]interface{}) error
 		Name: child,
 	}
 
-	childResults, err := c.SearchEntities(childSearchCriteria)
+	childResults, err := p.store.SearchEntities(childSearchCriteria)
 	if err != nil {
 		return fmt.Errorf("failed to search for child entity: %w", err)
 	}
@@ -821,32 +926,18 @@ func (c *Client) TerminatePersonEntity(transaction map[string]interface{}) error
 	}
 	childID := childResults[0].ID
 
-	// Get the specific relationship that is still active (no end date) -> this should give us the relationship(s) active for dateISO
-	relations, err := c.GetRelatedEntities(parentID, &models.Relationship{
-		RelatedEntityID: childID,
-		Name:            relType,
-		StartTime:       dateISO,
-	})
+	// Get the specific relationship that is still active (no end date) at dateISO
+	activeRel, err := p.activeRelationship(parentID, childID, relType, dateISO)
 	if err != nil {
 		return fmt.Errorf("failed to get relationship: %w", err)
 	}
 
-	// FIXME: Is it possible to have more than one active relationship? For orgchart case only it won't happen
-	// Find the active relationship (no end time)
-	var activeRel *models.Relationship
-	for _, rel := range relations {
-		if rel.RelatedEntityID == childID && rel.EndTime == "" {
-			activeRel = &rel
-			break
-		}
-	}
-
 	if activeRel == nil {
 		return fmt.Errorf("no active relationship found between %s and %s with type %s", parentID, childID, relType)
 	}
 
 	// Update the relationship to set the end date
-	_, err = c.UpdateEntity(parentID, &models.Entity{
+	_, err = p.store.UpdateEntity(parentID, &models.Entity{
 		ID: parentID,
 		Relationships: []models.RelationshipEntry{
 			{
@@ -869,13 +960,13 @@ func (c *Client) TerminatePersonEntity(transaction map[string]interface{}) error
 // TODO: Take the parent type from the transaction such that this function can be used generic
 //
 //	for moving person from any institution to another
-func (c *Client) MovePerson(transaction map[string]interface{}) error {
+func (p *Processor) MovePerson(transaction MoveTransaction) error {
 	// Extract details from the transaction
-	newParent := transaction["new_parent"].(string)
-	oldParent := transaction["old_parent"].(string)
-	child := transaction["child"].(string)
-	dateStr := transaction["date"].(string)
-	relType := transaction["type"].(string)
+	newParent := transaction.NewParent
+	oldParent := transaction.OldParent
+	child := transaction.Child
+	dateStr := transaction.Date
+	relType := transaction.Type
 
 	// Parse the date
 	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
@@ -885,7 +976,7 @@ func (c *Client) MovePerson(transaction map[string]interface{}) error {
 	dateISO := date.Format(time.RFC3339)
 
 	// Get the new minister (parent) entity ID
-	newParentResults, err := c.SearchEntities(&models.SearchCriteria{
+	newParentResults, err := p.store.SearchEntities(&models.SearchCriteria{
 		Kind: &models.Kind{
 			Major: "Organisation",
 			Minor: "minister",
@@ -901,7 +992,7 @@ func (c *Client) MovePerson(transaction map[string]interface{}) error {
 	newParentID := newParentResults[0].ID
 
 	// Get the department (child) entity ID
-	childResults, err := c.SearchEntities(&models.SearchCriteria{
+	childResults, err := p.store.SearchEntities(&models.SearchCriteria{
 		Kind: &models.Kind{
 			Major: "Person",
 			Minor: "citizen",
@@ -933,22 +1024,23 @@ func (c *Client) MovePerson(transaction map[string]interface{}) error {
 		},
 	}
 
-	_, err = c.UpdateEntity(newParentID, newRelationship)
+	_, err = p.store.UpdateEntity(newParentID, newRelationship)
 	if err != nil {
 		return fmt.Errorf("failed to create new relationship: %w", err)
 	}
 
 	// Terminate the old relationship
-	terminateTransaction := map[string]interface{}{
-		"parent":      oldParent,
-		"child":       child,
-		"date":        dateStr,
-		"parent_type": "minister",
-		"child_type":  "citizen",
-		"rel_type":    relType,
+	terminateTransaction := TerminateTransaction{
+		TransactionID: transaction.TransactionID,
+		Parent:        oldParent,
+		Child:         child,
+		Date:          dateStr,
+		ParentType:    "minister",
+		ChildType:     "citizen",
+		RelType:       relType,
 	}
 
-	err = c.TerminatePersonEntity(terminateTransaction)
+	err = p.TerminatePersonEntity(terminateTransaction)
 	if err != nil {
 		return fmt.Errorf("failed to terminate old relationship: %w", err)
 	}
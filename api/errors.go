@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodySize limits how much of a response body is kept in a RequestError
+const maxErrorBodySize = 4096
+
+// RequestError describes a request to Nexoan that got an unsuccessful response. The more
+// specific NotFoundError, ConflictError, ValidationError and ServerError wrap it, so errors.As
+// with a *RequestError matches all of them.
+type RequestError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *RequestError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s %s: unexpected status code: %d", e.Method, e.URL, e.StatusCode)
+	}
+	return fmt.Sprintf("%s %s: unexpected status code: %d, body: %s", e.Method, e.URL, e.StatusCode, e.Body)
+}
+
+// NotFoundError is returned when Nexoan answers 404 Not Found. It matches ErrEntityNotFound
+// with errors.Is, like the errors of MemoryStore.
+type NotFoundError struct {
+	RequestError
+}
+
+func (e *NotFoundError) Unwrap() error { return &e.RequestError }
+
+func (e *NotFoundError) Is(target error) bool { return target == ErrEntityNotFound }
+
+// ConflictError is returned when Nexoan answers 409 Conflict, e.g. because an entity with the
+// same ID already exists. It matches ErrDuplicateEntity with errors.Is.
+type ConflictError struct {
+	RequestError
+}
+
+func (e *ConflictError) Unwrap() error { return &e.RequestError }
+
+func (e *ConflictError) Is(target error) bool { return target == ErrDuplicateEntity }
+
+// ValidationError is returned when Nexoan rejects a request as invalid (400 or 422)
+type ValidationError struct {
+	RequestError
+}
+
+func (e *ValidationError) Unwrap() error { return &e.RequestError }
+
+// ServerError is returned when Nexoan fails to handle a request (5xx). Requests that are safe
+// to repeat are retried on it, see RetryPolicy.
+type ServerError struct {
+	RequestError
+}
+
+func (e *ServerError) Unwrap() error { return &e.RequestError }
+
+// newRequestError reads the body of an unsuccessful response and returns the error type
+// matching its status code
+func newRequestError(req *http.Request, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	reqErr := RequestError{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return &NotFoundError{reqErr}
+	case resp.StatusCode == http.StatusConflict:
+		return &ConflictError{reqErr}
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity:
+		return &ValidationError{reqErr}
+	case resp.StatusCode >= 500:
+		return &ServerError{reqErr}
+	default:
+		return &reqErr
+	}
+}
@@ -1,31 +1,134 @@
 package api
 
 import (
-	"encoding/csv"
+	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
+	"time"
 )
 
-// ProcessTransactions processes all transactions from CSV files in the specified directory
-func (c *Client) ProcessTransactions(dataDir string, processType string) error {
+// ProcessOptions holds the state that is carried between runs of ProcessTransactions
+type ProcessOptions struct {
+	// EntityCounters holds the last number used for generated entity IDs of each child type and
+	// is updated in place, so callers can persist it (see SaveEntityCounters) and pass it to the
+	// next run to keep IDs unique. A nil map starts every counter at zero.
+	EntityCounters map[string]int
+	// CountersFile, if set, is where EntityCounters are saved after every transaction that
+	// changes them, before the transaction is journaled, so that a crash cannot lose the IDs of
+	// journaled entities. Nothing is saved in dry-run mode.
+	CountersFile string
+	// Journal, if set, records every transaction that is applied successfully, and the writes a
+	// failing transaction made before it failed. Nothing is recorded in dry-run mode.
+	Journal *Journal
+	// Resume skips the transactions that Journal already records as applied from the same data
+	// directory
+	Resume bool
+	// Term, if set, is related to every minister created by organisation transactions. If it is
+	// nil and the data directory is a dated gazette folder, the term manifest of the presidency
+	// directory above it is used.
+	Term *Term
+	// KindMapping maps parent_type and child_type values of the data, and the type values of
+	// RENAME and MERGE rows, to the minor kinds of entities, e.g. "ministry" to "minister". Values
+	// that are not mapped are used as they are.
+	KindMapping map[string]string
+}
+
+// ProcessTransactionTree processes every dated gazette folder under rootDir in date order.
+// rootDir is expected to follow the data/<category>/<president>/<YYYY-MM-DD>/ layout, for
+// example data/orgchart/rw. Processing stops at the first folder that fails.
+// The options are shared by all folders; see ProcessTransactions.
+func (p *Processor) ProcessTransactionTree(rootDir string, processType string, opts *ProcessOptions) error {
+	return p.ProcessTransactionTreeContext(context.Background(), rootDir, processType, opts)
+}
+
+// ProcessTransactionTreeContext is like ProcessTransactionTree but stops between transactions
+// once ctx is done; see ProcessTransactionsContext
+func (p *Processor) ProcessTransactionTreeContext(ctx context.Context, rootDir string, processType string, opts *ProcessOptions) error {
+	folders, err := listGazetteFolders(rootDir)
+	if err != nil {
+		return err
+	}
+	if len(folders) == 0 {
+		return fmt.Errorf("no dated gazette folders found in %s", rootDir)
+	}
+
+	for _, folder := range folders {
+		fmt.Printf("Processing gazette folder: %s\n", folder)
+		if err := p.ProcessTransactionsContext(ctx, filepath.Join(rootDir, folder), processType, opts); err != nil {
+			return fmt.Errorf("failed to process gazette folder %s: %w", folder, err)
+		}
+	}
+
+	return nil
+}
+
+// listGazetteFolders returns the names of the sub-directories of rootDir that are named
+// after a date (YYYY-MM-DD), sorted in chronological order. Other entries are ignored.
+func listGazetteFolders(rootDir string) ([]string, error) {
+	entries, err := os.ReadDir(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", rootDir, err)
+	}
+
+	var folders []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", entry.Name()); err != nil {
+			fmt.Printf("Skipping folder %s: name is not a date (YYYY-MM-DD)\n", entry.Name())
+			continue
+		}
+		folders = append(folders, entry.Name())
+	}
+
+	// The YYYY-MM-DD layout sorts lexically in date order
+	sort.Strings(folders)
+
+	return folders, nil
+}
+
+// ProcessTransactions processes all transactions from CSV files in the specified directory.
+// opts may be nil, in which case entity counters start at zero and nothing is journaled.
+func (p *Processor) ProcessTransactions(dataDir string, processType string, opts *ProcessOptions) error {
+	return p.ProcessTransactionsContext(context.Background(), dataDir, processType, opts)
+}
+
+// ProcessTransactionsContext is like ProcessTransactions but stops once ctx is done. The context
+// is only checked before each transaction: the requests of a transaction are sent with
+// context.WithoutCancel(ctx), so a transaction that has started is finished and the entity
+// counters and the journal describe what was applied, and processing can be resumed. The
+// returned error wraps ctx.Err(). A Processor must not process several directories at once,
+// since its store is bound to the context while processing.
+func (p *Processor) ProcessTransactionsContext(ctx context.Context, dataDir string, processType string, opts *ProcessOptions) error {
+	if opts == nil {
+		opts = &ProcessOptions{}
+	}
+	defer p.bindContext(context.WithoutCancel(ctx))()
+
 	// Initialize entity counters based on process type
-	var entityCounters map[string]int
+	var counterTypes []string
 	if processType == "organisation" {
-		entityCounters = map[string]int{
-			"minister":   0,
-			"department": 0,
-		}
+		counterTypes = []string{"minister", "department"}
 	} else if processType == "person" {
-		entityCounters = map[string]int{
-			"citizen": 0,
-		}
+		counterTypes = []string{"citizen"}
 	} else {
 		return fmt.Errorf("invalid process type: %s", processType)
 	}
+	if opts.EntityCounters == nil {
+		opts.EntityCounters = map[string]int{}
+	}
+	entityCounters := opts.EntityCounters
+	for _, counterType := range counterTypes {
+		if _, exists := entityCounters[counterType]; !exists {
+			entityCounters[counterType] = 0
+		}
+	}
 
 	// Get all CSV files in the directory
 	files, err := os.ReadDir(dataDir)
@@ -34,23 +137,11 @@ func (c *Client) ProcessTransactions(dataDir string, processType string) error {
 	}
 
 	// Collect all transactions from all files
-	var allTransactions []map[string]interface{}
+	var allTransactions []Transaction
 	for _, file := range files {
 		if !file.IsDir() && strings.HasSuffix(file.Name(), ".csv") {
-			// Extract file type from filename (e.g., "ADD" from "2403-38_ADD.csv" or "ADD.csv")
-			fileName := strings.TrimSuffix(file.Name(), ".csv")
-			fileType := "ADD" // Default to ADD
-			if strings.Contains(fileName, "TERMINATE") {
-				fileType = "TERMINATE"
-			} else if strings.Contains(fileName, "MOVE") {
-				fileType = "MOVE"
-			} else if strings.Contains(fileName, "MERGE") {
-				fileType = "MERGE"
-			} else if strings.Contains(fileName, "RENAME") {
-				fileType = "RENAME"
-			}
-
 			// Load transactions from the CSV file
+			fileType := transactionFileType(file.Name())
 			transactions, err := loadTransactions(filepath.Join(dataDir, file.Name()), fileType)
 			if err != nil {
 				return fmt.Errorf("failed to load transactions from %s: %w", file.Name(), err)
@@ -61,152 +152,269 @@ func (c *Client) ProcessTransactions(dataDir string, processType string) error {
 
 	// Sort transactions by transaction_id, handling numeric parts correctly
 	sort.Slice(allTransactions, func(i, j int) bool {
-		idI := allTransactions[i]["transaction_id"].(string)
-		idJ := allTransactions[j]["transaction_id"].(string)
-
-		// Split the IDs into parts
-		partsI := strings.Split(idI, "_")
-		partsJ := strings.Split(idJ, "_")
-
-		// Compare the first part (e.g., "2153/12")
-		if partsI[0] != partsJ[0] {
-			return partsI[0] < partsJ[0]
-		}
-
-		// Compare the second part (e.g., "tr")
-		if partsI[1] != partsJ[1] {
-			return partsI[1] < partsJ[1]
-		}
-
-		// Compare the numeric part by converting to integers
-		numI := strings.TrimPrefix(partsI[2], "tr_")
-		numJ := strings.TrimPrefix(partsJ[2], "tr_")
-
-		// Convert to integers for numeric comparison
-		valI, _ := strconv.Atoi(numI)
-		valJ, _ := strconv.Atoi(numJ)
-		return valI < valJ
+		return lessTransactionID(allTransactions[i].ID(), allTransactions[j].ID())
 	})
 
+	term, err := p.gazetteTerm(dataDir, processType, opts)
+	if err != nil {
+		return err
+	}
+
 	// Process transactions in order
 	for _, transaction := range allTransactions {
-		fmt.Printf("Processing transaction: %s (Type: %s)\n", transaction["transaction_id"], transaction["file_type"])
-
-		switch transaction["file_type"] {
-		case "ADD":
-			// Check if the transaction type matches the process type
-			childType := transaction["child_type"].(string)
-			if (processType == "organisation" && (childType == "minister" || childType == "department")) ||
-				(processType == "person" && childType == "citizen") {
-				var err error
-
-				if processType == "person" && childType == "citizen" {
-					entityCounters[childType], err = c.AddPersonEntity(transaction, entityCounters)
-				} else {
-					entityCounters[childType], err = c.AddOrgEntity(transaction, entityCounters)
-				}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("stopped before transaction %s: %w", transaction.ID(), err)
+		}
 
-				if err != nil {
-					return fmt.Errorf("failed to process add transaction %s: %w", transaction["transaction_id"], err)
+		if opts.Resume && opts.Journal != nil && opts.Journal.IsApplied(processType, dataDir, transaction.ID()) {
+			fmt.Printf("Skipping transaction %s: already applied according to the journal\n", transaction.ID())
+			continue
+		}
+		if opts.Resume && opts.Journal != nil {
+			for _, attempt := range opts.Journal.FailedAttempts(processType, dataDir, transaction.ID()) {
+				fmt.Printf("Retrying transaction %s, which failed before with: %s\n", transaction.ID(), attempt.Error)
+				for _, change := range attempt.Changes {
+					fmt.Printf("  The failed attempt left change, which undo reverses: %s\n", change)
 				}
-				//entityCounters[childType] = newCounter
-				fmt.Printf("Processed Add transaction: %s\n", transaction["transaction_id"])
-			} else {
-				fmt.Printf("Skipping transaction %s: type %s does not match process type %s\n",
-					transaction["transaction_id"], childType, processType)
 			}
+		}
 
-		case "TERMINATE":
-			if processType == "organisation" {
-				err := c.TerminateOrgEntity(transaction)
-				if err != nil {
-					return fmt.Errorf("failed to process terminate transaction %s: %w", transaction["transaction_id"], err)
-				}
-				fmt.Printf("Processed Terminate transaction: %s\n", transaction["transaction_id"])
-			} else if processType == "person" {
-				err := c.TerminatePersonEntity(transaction)
-				if err != nil {
-					return fmt.Errorf("failed to process terminate transaction %s: %w", transaction["transaction_id"], err)
-				}
-				fmt.Printf("Processed Terminate transaction: %s\n", transaction["transaction_id"])
-			}
+		fmt.Printf("Processing transaction: %s (Type: %s)\n", transaction.ID(), transaction.FileType())
 
-		case "MOVE":
-			if processType == "organisation" {
-				err := c.MoveDepartment(transaction)
-				if err != nil {
-					return fmt.Errorf("failed to process move transaction %s: %w", transaction["transaction_id"], err)
-				}
-				fmt.Printf("Processed Move transaction: %s\n", transaction["transaction_id"])
-			} else if processType == "person" {
-				err := c.MovePerson(transaction)
-				if err != nil {
-					return fmt.Errorf("failed to process move transaction %s: %w", transaction["transaction_id"], err)
-				}
-				fmt.Printf("Processed Move transaction: %s\n", transaction["transaction_id"])
+		if p.plan != nil {
+			// In a dry run a failing transaction is recorded in the plan and the rest are still planned
+			p.plan.attribute(transaction.ID(), transaction.FileType())
+			if _, err := p.applyTransaction(mapTransactionKinds(transaction, opts.KindMapping), processType, entityCounters, term); err != nil {
+				p.plan.addError(transaction, err)
 			}
+			p.plan.attribute("", "")
+			continue
+		}
 
-		case "MERGE":
-			if processType == "organisation" {
-				newCounter, err := c.MergeMinisters(transaction, entityCounters)
-				if err != nil {
-					return fmt.Errorf("failed to process merge transaction %s: %w", transaction["transaction_id"], err)
-				}
-				entityCounters["minister"] = newCounter
-				fmt.Printf("Processed Merge transaction: %s\n", transaction["transaction_id"])
+		savedCounters := maps.Clone(entityCounters)
+		changes, err := p.applyTransaction(mapTransactionKinds(transaction, opts.KindMapping), processType, entityCounters, term)
+		// The counters are saved before the journal record, so they never lag behind it
+		var countersErr error
+		if opts.CountersFile != "" && !maps.Equal(savedCounters, entityCounters) {
+			countersErr = SaveEntityCounters(opts.CountersFile, entityCounters)
+		}
+		entry := JournalEntry{
+			TransactionID: transaction.ID(),
+			FileType:      transaction.FileType(),
+			ProcessType:   processType,
+			DataDir:       dataDir,
+			AppliedAt:     time.Now().UTC().Format(time.RFC3339),
+			Changes:       changes,
+		}
+		if err != nil {
+			// The writes made before the failure are journaled as a failed attempt, so that undo
+			// can reverse them while resuming retries the transaction
+			for _, change := range changes {
+				fmt.Printf("Transaction %s left change: %s\n", transaction.ID(), change)
 			}
-
-		case "RENAME":
-			if processType == "organisation" {
-				newCounter, err := c.RenameMinister(transaction, entityCounters)
-				if err != nil {
-					return fmt.Errorf("failed to process rename transaction %s: %w", transaction["transaction_id"], err)
+			if opts.Journal != nil && len(changes) > 0 {
+				entry.Failed = true
+				entry.Error = err.Error()
+				if journalErr := opts.Journal.Record(entry); journalErr != nil {
+					return fmt.Errorf("%w (the changes it left could not be journaled: %v)", err, journalErr)
 				}
-				entityCounters["minister"] = newCounter
-				fmt.Printf("Processed Rename transaction: %s\n", transaction["transaction_id"])
 			}
+			if countersErr != nil {
+				return fmt.Errorf("%w (the entity counters could not be saved: %v)", err, countersErr)
+			}
+			return err
+		}
 
-		default:
-			fmt.Printf("Skipping unknown transaction type: %s\n", transaction["file_type"])
+		if countersErr != nil {
+			return fmt.Errorf("transaction %s was applied but the entity counters could not be saved: %w", transaction.ID(), countersErr)
+		}
+		if opts.Journal != nil {
+			if err := opts.Journal.Record(entry); err != nil {
+				return fmt.Errorf("transaction %s was applied but could not be journaled: %w", transaction.ID(), err)
+			}
 		}
 	}
 
 	return nil
 }
 
-// loadTransactions reads and processes transactions from a CSV file
-func loadTransactions(filePath string, fileType string) ([]map[string]interface{}, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open file %s: %w", filePath, err)
+// gazetteTerm returns the term of the organisation transactions in dataDir, creating its entity if
+// needed: opts.Term if set, and otherwise the term of the presidency directory holding the gazette
+// folder. Person transactions create no ministers, so they have no term. A term whose root does
+// not exist is skipped, so the folder is processed without relating its ministers to a term.
+// Creating the term is journaled as an entry of its own, with the term ID as transaction ID and
+// TermFileType as file type, so that undoing the folder also removes the term.
+func (p *Processor) gazetteTerm(dataDir string, processType string, opts *ProcessOptions) (*Term, error) {
+	if processType != "organisation" {
+		return nil, nil
+	}
+	term := opts.Term
+	if term == nil {
+		if _, err := time.Parse("2006-01-02", filepath.Base(dataDir)); err != nil {
+			return nil, nil
+		}
+		var err error
+		if term, err = LoadTerm(filepath.Dir(dataDir)); err != nil || term == nil {
+			return nil, err
+		}
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
+	// In a dry run the term is planned as a step of its own rather than of the previous transaction
+	if p.plan != nil {
+		p.plan.attribute(term.ID, TermFileType)
+		defer p.plan.attribute("", "")
+	}
 
-	// Read header
-	header, err := reader.Read()
+	var created bool
+	changes, err := p.recordChanges(func() error {
+		var err error
+		_, created, err = p.CreateTermNode(term)
+		return err
+	})
+	if errors.Is(err, ErrTermRootNotFound) {
+		fmt.Printf("Skipping term %s: %v\n", term.ID, err)
+		return nil, nil
+	}
+	if p.plan == nil && opts.Journal != nil && len(changes) > 0 {
+		entry := JournalEntry{
+			TransactionID: term.ID,
+			FileType:      TermFileType,
+			ProcessType:   processType,
+			DataDir:       dataDir,
+			AppliedAt:     time.Now().UTC().Format(time.RFC3339),
+			Changes:       changes,
+		}
+		if err != nil {
+			entry.Failed = true
+			entry.Error = err.Error()
+		}
+		if journalErr := opts.Journal.Record(entry); journalErr != nil {
+			if err != nil {
+				return nil, fmt.Errorf("%w (the changes it left could not be journaled: %v)", err, journalErr)
+			}
+			return nil, fmt.Errorf("term %s was created but could not be journaled: %w", term.ID, journalErr)
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read header from %s: %w", filePath, err)
+		return nil, err
 	}
+	if created {
+		fmt.Printf("Created term %s: %s\n", term.ID, term.Name)
+	}
+	return term, nil
+}
 
-	// Read all records
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read records from %s: %w", filePath, err)
+// applyTransaction applies a single transaction, relates the ministers it creates to the term if
+// there is one, and returns the writes it made
+func (p *Processor) applyTransaction(transaction Transaction, processType string, entityCounters map[string]int, term *Term) ([]Change, error) {
+	changes, err := p.recordChanges(func() error {
+		return p.processTransaction(transaction, processType, entityCounters)
+	})
+	if err != nil || term == nil {
+		return changes, err
 	}
 
-	var transactions []map[string]interface{}
-	// Process each record
-	for _, record := range records {
-		transaction := make(map[string]interface{})
-		for i, value := range record {
-			transaction[header[i]] = value
+	termChanges, err := p.recordChanges(func() error {
+		return p.linkTermMinisters(term, changes)
+	})
+	return append(changes, termChanges...), err
+}
+
+// processTransaction applies a single transaction, updating entityCounters for any entity it creates
+func (p *Processor) processTransaction(transaction Transaction, processType string, entityCounters map[string]int) error {
+	switch transaction := transaction.(type) {
+	case AddTransaction:
+		// Check if the transaction type matches the process type
+		childType := transaction.ChildType
+		if (processType == "organisation" && (childType == "minister" || childType == "department")) ||
+			(processType == "person" && childType == "citizen") {
+			var newCounter int
+			var err error
+
+			if processType == "person" && childType == "citizen" {
+				newCounter, err = p.AddPersonEntity(transaction, entityCounters)
+			} else {
+				newCounter, err = p.AddOrgEntity(transaction, entityCounters)
+			}
+			// A failed add may still have used up an ID, so the counter is kept even on error
+			keepEntityCounter(entityCounters, childType, newCounter)
+
+			// Rerunning a gazette must not create a second entity with the same name
+			var existsErr *EntityExistsError
+			if errors.As(err, &existsErr) {
+				fmt.Printf("Skipping Add transaction %s: %v\n", transaction.TransactionID, existsErr)
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to process add transaction %s: %w", transaction.TransactionID, err)
+			}
+			fmt.Printf("Processed Add transaction: %s\n", transaction.TransactionID)
+		} else {
+			fmt.Printf("Skipping transaction %s: type %s does not match process type %s\n",
+				transaction.TransactionID, childType, processType)
 		}
-		// Add file type to transaction
-		transaction["file_type"] = fileType
-		transactions = append(transactions, transaction)
+
+	case TerminateTransaction:
+		if processType == "organisation" {
+			err := p.TerminateOrgEntity(transaction)
+			if err != nil {
+				return fmt.Errorf("failed to process terminate transaction %s: %w", transaction.TransactionID, err)
+			}
+			fmt.Printf("Processed Terminate transaction: %s\n", transaction.TransactionID)
+		} else if processType == "person" {
+			err := p.TerminatePersonEntity(transaction)
+			if err != nil {
+				return fmt.Errorf("failed to process terminate transaction %s: %w", transaction.TransactionID, err)
+			}
+			fmt.Printf("Processed Terminate transaction: %s\n", transaction.TransactionID)
+		}
+
+	case MoveTransaction:
+		if processType == "organisation" {
+			err := p.MoveDepartment(transaction)
+			if err != nil {
+				return fmt.Errorf("failed to process move transaction %s: %w", transaction.TransactionID, err)
+			}
+			fmt.Printf("Processed Move transaction: %s\n", transaction.TransactionID)
+		} else if processType == "person" {
+			err := p.MovePerson(transaction)
+			if err != nil {
+				return fmt.Errorf("failed to process move transaction %s: %w", transaction.TransactionID, err)
+			}
+			fmt.Printf("Processed Move transaction: %s\n", transaction.TransactionID)
+		}
+
+	case MergeTransaction:
+		if processType == "organisation" {
+			newCounter, err := p.MergeMinisters(transaction, entityCounters)
+			keepEntityCounter(entityCounters, "minister", newCounter)
+			if err != nil {
+				return fmt.Errorf("failed to process merge transaction %s: %w", transaction.TransactionID, err)
+			}
+			fmt.Printf("Processed Merge transaction: %s\n", transaction.TransactionID)
+		}
+
+	case RenameTransaction:
+		if processType == "organisation" {
+			newCounter, err := p.RenameMinister(transaction, entityCounters)
+			keepEntityCounter(entityCounters, "minister", newCounter)
+			if err != nil {
+				return fmt.Errorf("failed to process rename transaction %s: %w", transaction.TransactionID, err)
+			}
+			fmt.Printf("Processed Rename transaction: %s\n", transaction.TransactionID)
+		}
+
+	default:
+		fmt.Printf("Skipping unknown transaction type: %s\n", transaction.FileType())
 	}
 
-	return transactions, nil
+	return nil
+}
+
+// keepEntityCounter raises the counter of kind to counter, leaving it alone when an operation
+// returned a lower one, e.g. because it failed before using up an ID
+func keepEntityCounter(entityCounters map[string]int, kind string, counter int) {
+	if counter > entityCounters[kind] {
+		entityCounters[kind] = counter
+	}
 }
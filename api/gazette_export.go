@@ -0,0 +1,483 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// gazetteFileTypes lists the files that can be written to a gazette folder of a process type,
+// matching the layout of data/orgchart and data/people
+var gazetteFileTypes = map[string][]string{
+	"organisation": {"ADD", "TERMINATE", "MOVE", "RENAME", "MERGE"},
+	"person":       {"ADD", "TERMINATE", "MOVE"},
+}
+
+// GazetteFolder is a dated gazette folder rebuilt from the relationship history
+type GazetteFolder struct {
+	// Date is the name of the folder, in YYYY-MM-DD format
+	Date         string
+	Transactions []Transaction
+	// EmptyFileTypes lists the file types written as a file with only a header, because the folder
+	// the gazettes were loaded from had one (see MatchGazetteFileSet)
+	EmptyFileTypes []string
+}
+
+// gazetteEvents holds the relationships that started or ended on one date, by edge index
+type gazetteEvents struct {
+	starts []int
+	ends   []int
+	// lineage holds the RENAMED_TO and MERGED_INTO relationships that started on the date
+	lineage []int
+}
+
+// ReconstructGazettes rebuilds the transactions of a process type ("organisation" or "person")
+// from the relationship history of a graph loaded without a date. Relationships starting and ending
+// on the same date are recognised as the RENAME, MERGE and MOVE transactions that produce them, and
+// the rest become ADD and TERMINATE transactions. Transactions keep the transaction_id the graph
+// records for their relationships (see LoadGraph) and are ordered by it, which is the order they
+// were applied in. Transactions without one are numbered in the order they must be replayed:
+// renames, merges, additions, moves, then terminations, skipping the IDs already taken.
+//
+// The gazette number of the generated IDs is taken from the IDs of the entities created on the
+// date, which start with it; when no entity was created the date itself is used.
+func ReconstructGazettes(graph *Graph, processType string) ([]GazetteFolder, error) {
+	if _, ok := gazetteFileTypes[processType]; !ok {
+		return nil, fmt.Errorf("invalid process type: %s", processType)
+	}
+	if graph.Date != "" {
+		return nil, fmt.Errorf("the graph must hold every relationship, but it was loaded for %s", graph.Date)
+	}
+
+	nodes := map[string]GraphNode{}
+	for _, node := range graph.Nodes {
+		nodes[node.ID] = node
+	}
+
+	// Group the relationships of the process type by the dates they started and ended on
+	targetKind := "Organisation"
+	if processType == "person" {
+		targetKind = "Person"
+	}
+	events := map[string]*gazetteEvents{}
+	eventsOn := func(date string) *gazetteEvents {
+		if events[date] == nil {
+			events[date] = &gazetteEvents{}
+		}
+		return events[date]
+	}
+	for i, edge := range graph.Edges {
+		// Terms are created from the term manifests, not from gazettes
+		if nodes[edge.To].Kind.Major != targetKind || nodes[edge.From].Kind.Minor == TermKind || nodes[edge.To].Kind.Minor == TermKind {
+			continue
+		}
+		if _, ok := lineageRelationships[edge.Name]; ok {
+			eventsOn(dateOf(edge.StartTime)).lineage = append(eventsOn(dateOf(edge.StartTime)).lineage, i)
+			continue
+		}
+		eventsOn(dateOf(edge.StartTime)).starts = append(eventsOn(dateOf(edge.StartTime)).starts, i)
+		if edge.EndTime != "" {
+			eventsOn(dateOf(edge.EndTime)).ends = append(eventsOn(dateOf(edge.EndTime)).ends, i)
+		}
+	}
+
+	dates := make([]string, 0, len(events))
+	for date := range events {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	folders := []GazetteFolder{}
+	for _, date := range dates {
+		builder := &gazetteBuilder{
+			graph:       graph,
+			nodes:       nodes,
+			processType: processType,
+			date:        date,
+			events:      events[date],
+			started:     map[int]bool{},
+			ended:       map[int]bool{},
+		}
+		transactions := builder.transactions()
+		if len(transactions) > 0 {
+			folders = append(folders, GazetteFolder{Date: date, Transactions: transactions})
+		}
+	}
+	return folders, nil
+}
+
+// dateOf returns the date part of an RFC 3339 time
+func dateOf(t string) string {
+	if len(t) >= len("2006-01-02") {
+		return t[:len("2006-01-02")]
+	}
+	return t
+}
+
+// gazetteBuilder turns the relationship changes of one date into transactions. started and ended
+// mark the edges whose start or end has been explained by a transaction.
+type gazetteBuilder struct {
+	graph       *Graph
+	nodes       map[string]GraphNode
+	processType string
+	date        string
+	events      *gazetteEvents
+	started     map[int]bool
+	ended       map[int]bool
+}
+
+// name returns the name of an entity
+func (b *gazetteBuilder) name(id string) string {
+	return b.nodes[id].Name
+}
+
+// takeStart marks and returns the first unexplained relationship starting on the date that
+// matches, or -1
+func (b *gazetteBuilder) takeStart(match func(edge GraphEdge) bool) int {
+	for _, i := range b.events.starts {
+		if !b.started[i] && match(b.graph.Edges[i]) {
+			b.started[i] = true
+			return i
+		}
+	}
+	return -1
+}
+
+// takeEnd marks and returns the first unexplained relationship ending on the date that matches, or -1
+func (b *gazetteBuilder) takeEnd(match func(edge GraphEdge) bool) int {
+	for _, i := range b.events.ends {
+		if !b.ended[i] && match(b.graph.Edges[i]) {
+			b.ended[i] = true
+			return i
+		}
+	}
+	return -1
+}
+
+// takeHandover marks the departments an old minister handed to a new minister on the date
+func (b *gazetteBuilder) takeHandover(oldID string, newID string) {
+	for _, i := range b.events.ends {
+		edge := b.graph.Edges[i]
+		if b.ended[i] || edge.From != oldID || edge.Name != "AS_DEPARTMENT" {
+			continue
+		}
+		if b.takeStart(func(next GraphEdge) bool { return next.From == newID && next.To == edge.To && next.Name == edge.Name }) >= 0 {
+			b.ended[i] = true
+		}
+	}
+}
+
+// takeReplacement marks the end of the parent relationship of an old minister and the start of
+// the one of the minister replacing it
+func (b *gazetteBuilder) takeReplacement(oldID string, newID string) {
+	b.takeEnd(func(edge GraphEdge) bool { return edge.To == oldID })
+	b.takeStart(func(edge GraphEdge) bool { return edge.To == newID })
+}
+
+// transactions returns the transactions of the date, ordered by their IDs
+func (b *gazetteBuilder) transactions() []Transaction {
+	var renames, merges, adds, moves, terminates []Transaction
+
+	// Renames and merges, grouping the ministers merged into the same one
+	mergeIndex := map[string]int{}
+	mergeNames := [][]string{}
+	for _, i := range b.events.lineage {
+		edge := b.graph.Edges[i]
+		b.takeReplacement(edge.From, edge.To)
+		b.takeHandover(edge.From, edge.To)
+
+		// Like the organisation data, the type column holds the kind, so the new minister is
+		// related to the parent by the relationship of the old one
+		kind := b.nodes[edge.To].Kind.Minor
+		if edge.Name == "RENAMED_TO" {
+			renames = append(renames, RenameTransaction{TransactionID: edge.StartTransaction, Old: b.name(edge.From), New: b.name(edge.To), Type: kind, Date: b.date})
+			continue
+		}
+		index, ok := mergeIndex[edge.To]
+		if !ok {
+			index = len(merges)
+			mergeIndex[edge.To] = index
+			merges = append(merges, MergeTransaction{TransactionID: edge.StartTransaction, New: b.name(edge.To), Type: kind, Date: b.date})
+			mergeNames = append(mergeNames, nil)
+		}
+		mergeNames[index] = append(mergeNames[index], b.name(edge.From))
+	}
+	for i := range merges {
+		merge := merges[i].(MergeTransaction)
+		merge.Old = formatMergeNames(mergeNames[i])
+		merges[i] = merge
+	}
+
+	// A relationship to a child that started while a relationship of the same type to it ended
+	// is a move. The new relationships are in the order the moves were made.
+	for _, i := range b.events.starts {
+		if b.started[i] {
+			continue
+		}
+		next := b.graph.Edges[i]
+		old := b.takeEnd(func(edge GraphEdge) bool {
+			return edge.To == next.To && edge.Name == next.Name && edge.From != next.From
+		})
+		if old < 0 {
+			continue
+		}
+		b.started[i] = true
+		// MovePerson relates the person with the type column, while MoveDepartment ignores it and
+		// the organisation data holds the kind of the child there
+		moveType := next.Name
+		if b.processType == "organisation" {
+			moveType = b.nodes[next.To].Kind.Minor
+		}
+		moves = append(moves, MoveTransaction{
+			TransactionID: next.StartTransaction,
+			OldParent:     b.name(b.graph.Edges[old].From),
+			NewParent:     b.name(next.From),
+			Child:         b.name(next.To),
+			Type:          moveType,
+			Date:          b.date,
+		})
+	}
+
+	for _, i := range b.events.starts {
+		if b.started[i] {
+			continue
+		}
+		edge := b.graph.Edges[i]
+		adds = append(adds, AddTransaction{
+			TransactionID: edge.StartTransaction,
+			Parent:        b.name(edge.From),
+			ParentType:    b.nodes[edge.From].Kind.Minor,
+			Child:         b.name(edge.To),
+			ChildType:     b.nodes[edge.To].Kind.Minor,
+			RelType:       edge.Name,
+			Date:          b.date,
+		})
+	}
+	for _, i := range b.events.ends {
+		if b.ended[i] {
+			continue
+		}
+		edge := b.graph.Edges[i]
+		terminates = append(terminates, TerminateTransaction{
+			TransactionID: edge.EndTransaction,
+			Parent:        b.name(edge.From),
+			ParentType:    b.nodes[edge.From].Kind.Minor,
+			Child:         b.name(edge.To),
+			ChildType:     b.nodes[edge.To].Kind.Minor,
+			RelType:       edge.Name,
+			Date:          b.date,
+		})
+	}
+
+	var transactions []Transaction
+	taken := map[string]bool{}
+	for _, group := range [][]Transaction{renames, merges, adds, moves, terminates} {
+		for _, transaction := range group {
+			transactions = append(transactions, transaction)
+			taken[transaction.ID()] = true
+		}
+	}
+
+	gazette := b.gazetteNumber()
+	number := 0
+	for i, transaction := range transactions {
+		if transaction.ID() != "" {
+			continue
+		}
+		id := ""
+		for id == "" || taken[id] {
+			number++
+			id = fmt.Sprintf("%s_tr_%02d", gazette, number)
+		}
+		taken[id] = true
+		transactions[i] = withTransactionID(transaction, id)
+	}
+
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return lessTransactionID(transactions[i].ID(), transactions[j].ID())
+	})
+	return transactions
+}
+
+// gazetteNumber returns the gazette number most of the entities created on the date start with,
+// or the date without dashes if none was created
+func (b *gazetteBuilder) gazetteNumber() string {
+	counts := map[string]int{}
+	for _, i := range append(append([]int{}, b.events.starts...), b.events.lineage...) {
+		node := b.nodes[b.graph.Edges[i].To]
+		if dateOf(node.Created) != b.date {
+			continue
+		}
+		if prefix, _, found := strings.Cut(node.ID, "_"); found && prefix != "" {
+			counts[prefix]++
+		}
+	}
+
+	gazette := ""
+	for prefix, count := range counts {
+		if gazette == "" || count > counts[gazette] || (count == counts[gazette] && prefix < gazette) {
+			gazette = prefix
+		}
+	}
+	if gazette == "" {
+		gazette = strings.ReplaceAll(b.date, "-", "")
+	}
+	return gazette
+}
+
+// formatMergeNames writes the old column of a MERGE row, e.g. ["Minister of A", "Minister of B"]
+func formatMergeNames(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		encoded, _ := json.Marshal(name)
+		quoted[i] = string(encoded)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// withTransactionID returns a copy of a transaction with its transaction_id set
+func withTransactionID(transaction Transaction, id string) Transaction {
+	switch t := transaction.(type) {
+	case AddTransaction:
+		t.TransactionID = id
+		return t
+	case TerminateTransaction:
+		t.TransactionID = id
+		return t
+	case MoveTransaction:
+		t.TransactionID = id
+		return t
+	case RenameTransaction:
+		t.TransactionID = id
+		return t
+	case MergeTransaction:
+		t.TransactionID = id
+		return t
+	}
+	return transaction
+}
+
+// MatchGazetteFileSet records in every folder the header-only files of the gazette folders of the
+// same date under sourceDir, which may be a presidency directory or a tree of them, so that
+// WriteGazetteFolders writes the same set of files as the data the gazettes were loaded from
+func MatchGazetteFileSet(folders []GazetteFolder, sourceDir string, processType string) error {
+	fileTypes, ok := gazetteFileTypes[processType]
+	if !ok {
+		return fmt.Errorf("invalid process type: %s", processType)
+	}
+
+	// The file types of the source folders, by date
+	sourceFiles := map[string]map[string]bool{}
+	err := filepath.WalkDir(sourceDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if _, err := time.Parse("2006-01-02", entry.Name()); err != nil {
+			return nil
+		}
+		for _, fileType := range fileTypes {
+			if _, err := os.Stat(filepath.Join(path, fileType+".csv")); err == nil {
+				if sourceFiles[entry.Name()] == nil {
+					sourceFiles[entry.Name()] = map[string]bool{}
+				}
+				sourceFiles[entry.Name()][fileType] = true
+			}
+		}
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list gazette folders of %s: %w", sourceDir, err)
+	}
+
+	for i := range folders {
+		folder := &folders[i]
+		for _, fileType := range fileTypes {
+			if sourceFiles[folder.Date][fileType] && !folder.hasRows(fileType) {
+				folder.EmptyFileTypes = append(folder.EmptyFileTypes, fileType)
+			}
+		}
+	}
+	return nil
+}
+
+// hasRows reports whether the folder has transactions of the given file type
+func (f GazetteFolder) hasRows(fileType string) bool {
+	for _, transaction := range f.Transactions {
+		if transaction.FileType() == fileType {
+			return true
+		}
+	}
+	return false
+}
+
+// hasEmptyFile reports whether the folder is written with a header-only file of the given type
+func (f GazetteFolder) hasEmptyFile(fileType string) bool {
+	for _, emptyType := range f.EmptyFileTypes {
+		if emptyType == fileType {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteGazetteFolders writes every folder under dir as <dir>/<date>/<TYPE>.csv in the format
+// loadTransactions reads, with a file for each file type of the process type that has rows and a
+// header-only file for each of its EmptyFileTypes. Lines end with \r\n if useCRLF is set, as in
+// most of the files in data/, and with \n otherwise, and like the files in data/ no file ends
+// with a newline.
+func WriteGazetteFolders(dir string, processType string, folders []GazetteFolder, useCRLF bool) error {
+	fileTypes, ok := gazetteFileTypes[processType]
+	if !ok {
+		return fmt.Errorf("invalid process type: %s", processType)
+	}
+
+	for _, folder := range folders {
+		folderPath := filepath.Join(dir, folder.Date)
+		if err := os.MkdirAll(folderPath, 0755); err != nil {
+			return fmt.Errorf("failed to create folder %s: %w", folderPath, err)
+		}
+
+		for _, fileType := range fileTypes {
+			var rows []Transaction
+			for _, transaction := range folder.Transactions {
+				if transaction.FileType() == fileType {
+					rows = append(rows, transaction)
+				}
+			}
+			if len(rows) == 0 && !folder.hasEmptyFile(fileType) {
+				continue
+			}
+
+			var buf bytes.Buffer
+			writer := csv.NewWriter(&buf)
+			writer.UseCRLF = useCRLF
+			if err := writer.Write(transactionColumns[fileType]); err != nil {
+				return fmt.Errorf("failed to write CSV header: %w", err)
+			}
+			for _, transaction := range rows {
+				if err := writer.Write(transactionValues(transaction)); err != nil {
+					return fmt.Errorf("failed to write transaction %s: %w", transaction.ID(), err)
+				}
+			}
+			writer.Flush()
+			if err := writer.Error(); err != nil {
+				return fmt.Errorf("failed to write CSV: %w", err)
+			}
+
+			filePath := filepath.Join(folderPath, fileType+".csv")
+			if err := os.WriteFile(filePath, bytes.TrimSuffix(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\r")), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", filePath, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"orgchart_nexoan/models"
+)
+
+// GraphNode is an entity of a Graph
+type GraphNode struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Kind       models.Kind `json:"kind"`
+	Created    string      `json:"created,omitempty"`
+	Terminated string      `json:"terminated,omitempty"`
+}
+
+// GraphEdge is a relationship of a Graph, from the entity holding it to the related entity
+type GraphEdge struct {
+	ID        string `json:"id"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Name      string `json:"name"`
+	StartTime string `json:"start_time"`
+	EndTime   string `json:"end_time,omitempty"`
+	// StartTransaction and EndTransaction are the transaction_id of the gazette rows that added and
+	// ended the relationship, when the journal the graph was loaded with records them
+	StartTransaction string `json:"start_transaction,omitempty"`
+	EndTransaction   string `json:"end_transaction,omitempty"`
+}
+
+// Graph is the part of the org chart reachable from the root entities, with nodes and edges in
+// the order they were found
+type Graph struct {
+	// Date is the date the relationships were active on, or empty for all relationships
+	Date  string      `json:"date,omitempty"`
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// Node returns the node with the given ID
+func (g *Graph) Node(id string) (GraphNode, bool) {
+	for _, node := range g.Nodes {
+		if node.ID == id {
+			return node, true
+		}
+	}
+	return GraphNode{}, false
+}
+
+// listRootEntities returns the root entities of the given major kind, looking through the Plan
+// and RecordingStore wrappers, and false if the store cannot list them
+func listRootEntities(store Store, kind string) ([]string, bool, error) {
+	switch s := store.(type) {
+	case *RecordingStore:
+		return listRootEntities(s.Store, kind)
+	case *Plan:
+		return s.rootEntities(kind)
+	case rootLister:
+		roots, err := s.GetRootEntities(kind)
+		return roots, true, err
+	}
+	return nil, false, nil
+}
+
+// graphRoots returns the Organisation root entities of the store, or the default government if
+// the store cannot list them
+func graphRoots(store Store) ([]string, error) {
+	roots, ok, err := listRootEntities(store, "Organisation")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get root entities: %w", err)
+	}
+	if !ok {
+		return []string{DefaultGovernmentID}, nil
+	}
+	return roots, nil
+}
+
+// graphNode looks up an entity and converts it to a node
+func graphNode(store Store, id string) (GraphNode, error) {
+	results, err := store.SearchEntities(&models.SearchCriteria{ID: id})
+	if err != nil {
+		return GraphNode{}, fmt.Errorf("failed to search for entity %s: %w", id, err)
+	}
+	if len(results) == 0 {
+		return GraphNode{}, fmt.Errorf("entity not found: %s", id)
+	}
+	return GraphNode{
+		ID:         results[0].ID,
+		Name:       results[0].Name,
+		Kind:       results[0].Kind,
+		Created:    results[0].Created,
+		Terminated: results[0].Terminated,
+	}, nil
+}
+
+// LoadGraph reads the entities reachable from the root entities through GetAllRelatedEntities.
+// If date (YYYY-MM-DD) is not empty, only the relationships active on that date are followed.
+// The journal, which may be nil, gives the transactions behind every relationship, and the
+// relationships it records as removed by undo are left out.
+func LoadGraph(store Store, date string, journal *Journal) (*Graph, error) {
+	var query *models.Relationship
+	graph := &Graph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	if date != "" {
+		parsedDate, err := time.Parse("2006-01-02", strings.TrimSpace(date))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse date: %w", err)
+		}
+		graph.Date = parsedDate.Format("2006-01-02")
+		query = &models.Relationship{StartTime: parsedDate.Format(time.RFC3339)}
+	}
+
+	roots, err := graphRoots(store)
+	if err != nil {
+		return nil, err
+	}
+
+	undone := map[string]bool{}
+	if journal != nil {
+		undone = journal.UndoneRelationships()
+	}
+	transactions := newJournalTransactions(journal)
+
+	visited := map[string]bool{}
+	queue := []string{}
+	visit := func(id string) error {
+		if visited[id] {
+			return nil
+		}
+		node, err := graphNode(store, id)
+		if err != nil {
+			return err
+		}
+		visited[id] = true
+		graph.Nodes = append(graph.Nodes, node)
+		queue = append(queue, id)
+		return nil
+	}
+
+	for _, id := range roots {
+		if err := visit(id); err != nil {
+			return nil, err
+		}
+	}
+
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		relations, err := store.GetAllRelatedEntities(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get relationships of %s: %w", id, err)
+		}
+		for _, rel := range relations {
+			if !matchesRelationshipQuery(rel, query) || undone[id+"/"+rel.ID+"/"+rel.StartTime] {
+				continue
+			}
+			if err := visit(rel.RelatedEntityID); err != nil {
+				return nil, err
+			}
+			edge := GraphEdge{
+				ID:               rel.ID,
+				From:             id,
+				To:               rel.RelatedEntityID,
+				Name:             rel.Name,
+				StartTime:        rel.StartTime,
+				EndTime:          rel.EndTime,
+				StartTransaction: transactions.added[id+"/"+rel.ID+"/"+rel.StartTime],
+			}
+			if rel.EndTime != "" {
+				edge.EndTransaction = transactions.ended[id+"/"+rel.ID+"/"+rel.EndTime]
+			}
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+
+	return graph, nil
+}
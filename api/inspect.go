@@ -0,0 +1,220 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"orgchart_nexoan/models"
+)
+
+// metadataReader is implemented by the stores that can read the metadata of an entity
+type metadataReader interface {
+	GetEntityMetadata(entityID string) (map[string]interface{}, error)
+}
+
+// Directions of an InspectedRelationship
+const (
+	DirectionOutgoing = "OUTGOING"
+	DirectionIncoming = "INCOMING"
+)
+
+// InspectedRelationship is a relationship of an inspected entity, with the entity at its other end
+type InspectedRelationship struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Direction is OUTGOING for the relationships the entity holds and INCOMING for the ones
+	// other entities hold to it
+	Direction   string `json:"direction"`
+	RelatedID   string `json:"relatedId"`
+	RelatedKind string `json:"relatedKind,omitempty"`
+	RelatedName string `json:"relatedName,omitempty"`
+	StartDate   string `json:"startDate"`
+	EndDate     string `json:"endDate,omitempty"`
+}
+
+// EntityInspection describes an entity with its metadata and all its relationships, split into
+// the active and the ended ones
+type EntityInspection struct {
+	ID         string `json:"id"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Created    string `json:"created"`
+	Terminated string `json:"terminated,omitempty"`
+	// Metadata is nil if the store cannot read metadata
+	Metadata map[string]interface{}  `json:"metadata,omitempty"`
+	Active   []InspectedRelationship `json:"active"`
+	Ended    []InspectedRelationship `json:"ended"`
+}
+
+// findEntities returns the entity with the given ID or, if there is none, the entities with the
+// given name
+func findEntities(store Store, nameOrID string) ([]models.SearchResult, error) {
+	results, err := store.SearchEntities(&models.SearchCriteria{ID: nameOrID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for entity: %w", err)
+	}
+	if len(results) > 0 {
+		return results, nil
+	}
+
+	results, err = store.SearchEntities(&models.SearchCriteria{Name: nameOrID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for entity: %w", err)
+	}
+	return results, nil
+}
+
+// InspectEntities describes the entity with the given ID or, if there is none, every entity with
+// the given name. Relationships that end after now are active.
+func InspectEntities(store Store, nameOrID string, now time.Time) ([]*EntityInspection, error) {
+	results, err := findEntities(store, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no entity found with ID or name %q", nameOrID)
+	}
+
+	// The entities at the other end of relationships, by ID
+	related := map[string]models.SearchResult{}
+	for _, result := range results {
+		related[result.ID] = result
+	}
+
+	inspections := []*EntityInspection{}
+	for _, result := range results {
+		inspection, err := inspectEntity(store, result, related, now)
+		if err != nil {
+			return nil, err
+		}
+		inspections = append(inspections, inspection)
+	}
+	return inspections, nil
+}
+
+// inspectEntity describes a single entity, looking up the related entities missing from related
+func inspectEntity(store Store, entity models.SearchResult, related map[string]models.SearchResult, now time.Time) (*EntityInspection, error) {
+	inspection := &EntityInspection{
+		ID:         entity.ID,
+		Kind:       entity.Kind.Major + "/" + entity.Kind.Minor,
+		Name:       entity.Name,
+		Created:    strings.TrimSuffix(entity.Created, "T00:00:00Z"),
+		Terminated: strings.TrimSuffix(entity.Terminated, "T00:00:00Z"),
+		Active:     []InspectedRelationship{},
+		Ended:      []InspectedRelationship{},
+	}
+
+	if reader, ok := store.(metadataReader); ok {
+		metadata, err := reader.GetEntityMetadata(entity.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metadata of %s: %w", entity.ID, err)
+		}
+		inspection.Metadata = metadata
+	}
+
+	outgoing, err := store.GetAllRelatedEntities(entity.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relationships of %s: %w", entity.ID, err)
+	}
+	holders, err := relationshipHolders(store, entity)
+	if err != nil {
+		return nil, err
+	}
+	incoming, err := incomingRelationships(store, entity.ID, holders, models.Relationship{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incoming relationships of %s: %w", entity.ID, err)
+	}
+
+	nowISO := now.UTC().Format(time.RFC3339)
+	add := func(rel models.Relationship, direction string) error {
+		other, exists := related[rel.RelatedEntityID]
+		if !exists {
+			results, err := store.SearchEntities(&models.SearchCriteria{ID: rel.RelatedEntityID})
+			if err != nil {
+				return fmt.Errorf("failed to search for entity %s: %w", rel.RelatedEntityID, err)
+			}
+			// An entity that cannot be found is shown by its ID alone
+			if len(results) > 0 {
+				other = results[0]
+			}
+			related[rel.RelatedEntityID] = other
+		}
+
+		inspected := InspectedRelationship{
+			ID:          rel.ID,
+			Name:        rel.Name,
+			Direction:   direction,
+			RelatedID:   rel.RelatedEntityID,
+			RelatedName: other.Name,
+			StartDate:   strings.TrimSuffix(rel.StartTime, "T00:00:00Z"),
+			EndDate:     strings.TrimSuffix(rel.EndTime, "T00:00:00Z"),
+		}
+		if other.ID != "" {
+			inspected.RelatedKind = other.Kind.Major + "/" + other.Kind.Minor
+		}
+		if rel.EndTime != "" && rel.EndTime <= nowISO {
+			inspection.Ended = append(inspection.Ended, inspected)
+		} else {
+			inspection.Active = append(inspection.Active, inspected)
+		}
+		return nil
+	}
+	for _, rel := range incoming {
+		if err := add(rel, DirectionIncoming); err != nil {
+			return nil, err
+		}
+	}
+	for _, rel := range outgoing {
+		if err := add(rel, DirectionOutgoing); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, relationships := range [][]InspectedRelationship{inspection.Active, inspection.Ended} {
+		sort.SliceStable(relationships, func(i, j int) bool {
+			if relationships[i].StartDate != relationships[j].StartDate {
+				return relationships[i].StartDate < relationships[j].StartDate
+			}
+			return relationships[i].Name < relationships[j].Name
+		})
+	}
+	return inspection, nil
+}
+
+// Print writes a readable description of the entity
+func (e *EntityInspection) Print(w io.Writer) {
+	fmt.Fprintf(w, "%s %q (%s)\n", e.Kind, e.Name, e.ID)
+	fmt.Fprintf(w, "  Created:    %s\n", valueOrUnknown(e.Created))
+	fmt.Fprintf(w, "  Terminated: %s\n", valueOrPresent(e.Terminated))
+
+	if len(e.Metadata) > 0 {
+		fmt.Fprintf(w, "  Metadata:\n")
+		for _, key := range sortedKeys(e.Metadata) {
+			fmt.Fprintf(w, "    %s: %v\n", key, e.Metadata[key])
+		}
+	}
+
+	fmt.Fprintf(w, "  Active relationships (%d):\n", len(e.Active))
+	for _, rel := range e.Active {
+		fmt.Fprintf(w, "    %s\n", rel)
+	}
+	fmt.Fprintf(w, "  Ended relationships (%d):\n", len(e.Ended))
+	for _, rel := range e.Ended {
+		fmt.Fprintf(w, "    %s\n", rel)
+	}
+}
+
+func (r InspectedRelationship) String() string {
+	arrow := "->"
+	if r.Direction == DirectionIncoming {
+		arrow = "<-"
+	}
+	other := r.RelatedID
+	if r.RelatedKind != "" {
+		other = fmt.Sprintf("%s %q (%s)", r.RelatedKind, r.RelatedName, r.RelatedID)
+	}
+	return fmt.Sprintf("%s %s %s, %s to %s", arrow, r.Name, other, valueOrUnknown(r.StartDate), valueOrPresent(r.EndDate))
+}
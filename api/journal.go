@@ -0,0 +1,255 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// JournalEntry records a transaction that was applied successfully, or the writes a transaction
+// made before it failed
+type JournalEntry struct {
+	TransactionID string `json:"transaction_id"`
+	FileType      string `json:"file_type"`
+	ProcessType   string `json:"process_type"`
+	DataDir       string `json:"data_dir"`
+	AppliedAt     string `json:"applied_at"`
+	// Changes lists the writes the transaction made and the existing entities it used, in the
+	// order they happened. An ADD of an entity that already existed only lists the reused entity.
+	// It is nil only for entries journaled before change logs were recorded.
+	Changes []Change `json:"changes"`
+	// Failed marks a transaction that failed after making the writes in Changes, e.g. one whose
+	// entity was created before the request relating it to its parent timed out. It does not count
+	// as applied, so resuming retries the transaction, but undo still reverses its writes.
+	Failed bool `json:"failed,omitempty"`
+	// Error is the error the transaction failed with
+	Error string `json:"error,omitempty"`
+	// Undo marks an entry recording an undo rather than a transaction. Its Changes are the added
+	// relationships the undo ended at their start time, which are left out of the graph from then on.
+	Undo bool `json:"undo,omitempty"`
+}
+
+// Operations recorded in a Change
+const (
+	ChangeCreateEntity    = "create_entity"
+	ChangeAddRelationship = "add_relationship"
+	ChangeEndRelationship = "end_relationship"
+	// ChangeReuseEntity records an existing entity a transaction used instead of creating one. It
+	// writes nothing, so undo has nothing to reverse.
+	ChangeReuseEntity = "reuse_entity"
+)
+
+// Change records a single write made to Nexoan while applying a transaction, with enough
+// detail to reverse it
+type Change struct {
+	Operation string `json:"operation"`
+	// EntityID is the created or reused entity, or the entity holding the relationship
+	EntityID        string `json:"entity_id"`
+	RelationshipID  string `json:"relationship_id,omitempty"`
+	RelatedEntityID string `json:"related_entity_id,omitempty"`
+	Name            string `json:"name,omitempty"`
+	StartTime       string `json:"start_time,omitempty"`
+	EndTime         string `json:"end_time,omitempty"`
+}
+
+// Journal is an append-only file with one JSON line per applied transaction, and per failed
+// transaction that made writes. It lets a failed import be resumed without replaying the
+// transactions that already reached Nexoan.
+type Journal struct {
+	path    string
+	file    *os.File
+	entries []JournalEntry
+	applied map[journalKey]bool
+}
+
+// OpenJournal opens the journal at the given path, creating it if it does not exist, and reads
+// the entries recorded by previous runs. A truncated last line, left by a crash while writing
+// it, is ignored.
+func OpenJournal(path string) (*Journal, error) {
+	journal := &Journal{
+		path:    path,
+		applied: map[journalKey]bool{},
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read journal %s: %w", path, err)
+	}
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			fmt.Printf("Ignoring unreadable journal line %d in %s: %v\n", i+1, path, err)
+			continue
+		}
+		journal.add(entry)
+	}
+
+	journal.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal %s: %w", path, err)
+	}
+
+	// Terminate a truncated last line so that new entries start on a line of their own
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		if _, err := journal.file.Write([]byte("\n")); err != nil {
+			journal.file.Close()
+			return nil, fmt.Errorf("failed to write journal %s: %w", path, err)
+		}
+	}
+
+	return journal, nil
+}
+
+// journalKey identifies a transaction in the journal. Organisation and person data are
+// numbered independently, and data trees such as data/gota_gazettes and data/orgchart/gr reuse
+// the same transaction IDs, so the process type and the data directory are part of the key.
+type journalKey struct {
+	processType   string
+	dataDir       string
+	transactionID string
+}
+
+// keyOf returns the key of a journal entry
+func keyOf(entry JournalEntry) journalKey {
+	return journalKey{processType: entry.ProcessType, dataDir: entry.DataDir, transactionID: entry.TransactionID}
+}
+
+// add indexes an entry read from or written to the journal file
+func (j *Journal) add(entry JournalEntry) {
+	j.entries = append(j.entries, entry)
+	if !entry.Failed && !entry.Undo {
+		j.applied[keyOf(entry)] = true
+	}
+}
+
+// IsApplied reports whether the journal records the transaction of the data directory as applied
+func (j *Journal) IsApplied(processType string, dataDir string, transactionID string) bool {
+	return j.applied[journalKey{processType: processType, dataDir: dataDir, transactionID: transactionID}]
+}
+
+// FailedAttempts returns the entries of the failed attempts to apply the transaction of the data
+// directory, in the order they were recorded
+func (j *Journal) FailedAttempts(processType string, dataDir string, transactionID string) []JournalEntry {
+	key := journalKey{processType: processType, dataDir: dataDir, transactionID: transactionID}
+	var attempts []JournalEntry
+	for _, entry := range j.entries {
+		if entry.Failed && keyOf(entry) == key {
+			attempts = append(attempts, entry)
+		}
+	}
+	return attempts
+}
+
+// Entries returns the entries of the journal in the order they were recorded
+func (j *Journal) Entries() []JournalEntry {
+	return j.entries
+}
+
+// UndoneRelationships returns the relationships removed by undo and not added again since, keyed
+// by the entity holding the relationship, the relationship ID and the start time
+func (j *Journal) UndoneRelationships() map[string]bool {
+	undone := map[string]bool{}
+	for _, entry := range j.entries {
+		for _, change := range entry.Changes {
+			if change.Operation != ChangeAddRelationship {
+				continue
+			}
+			key := change.EntityID + "/" + change.RelationshipID + "/" + change.StartTime
+			if entry.Undo {
+				undone[key] = true
+			} else {
+				delete(undone, key)
+			}
+		}
+	}
+	return undone
+}
+
+// Record appends an entry to the journal and flushes it to disk before returning
+func (j *Journal) Record(entry JournalEntry) error {
+	// An empty change log is written as [] so it is not mistaken for a missing one when read back
+	if entry.Changes == nil {
+		entry.Changes = []Change{}
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal %s: %w", j.path, err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal %s: %w", j.path, err)
+	}
+
+	j.add(entry)
+	return nil
+}
+
+// Remove drops the entries at the given positions of Entries from the journal and rewrites the
+// file without them. Other entries of the same transactions, e.g. earlier failed attempts, are kept.
+func (j *Journal) Remove(indices []int) error {
+	removed := map[int]bool{}
+	for _, i := range indices {
+		removed[i] = true
+	}
+
+	var kept []JournalEntry
+	var buf bytes.Buffer
+	for i, entry := range j.entries {
+		if removed[i] {
+			continue
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode journal entry: %w", err)
+		}
+		buf.Write(append(data, '\n'))
+		kept = append(kept, entry)
+	}
+
+	// Write to a temporary file first so that a crash cannot leave a half written journal
+	tmpFile, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", j.path, err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(buf.Bytes()); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write journal %s: %w", tmpFile.Name(), err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to write journal %s: %w", tmpFile.Name(), err)
+	}
+	if err := j.file.Close(); err != nil {
+		return fmt.Errorf("failed to close journal %s: %w", j.path, err)
+	}
+	if err := os.Rename(tmpFile.Name(), j.path); err != nil {
+		return fmt.Errorf("failed to replace journal %s: %w", j.path, err)
+	}
+
+	file, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal %s: %w", j.path, err)
+	}
+	j.file = file
+
+	j.entries = nil
+	j.applied = map[journalKey]bool{}
+	for _, entry := range kept {
+		j.add(entry)
+	}
+	return nil
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"orgchart_nexoan/models"
+)
+
+// lineageRelationships are the relationships RenameMinister and MergeMinisters leave from an old
+// minister to the minister that replaced it
+var lineageRelationships = map[string]string{
+	"RENAMED_TO":  "renamed to",
+	"MERGED_INTO": "merged into",
+}
+
+// LineageMinister identifies a minister in a Lineage
+type LineageMinister struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func (m LineageMinister) String() string {
+	return fmt.Sprintf("%s (%s)", m.Name, m.ID)
+}
+
+// LineageLink is a rename or merge of one minister into another
+type LineageLink struct {
+	From         LineageMinister `json:"from"`
+	To           LineageMinister `json:"to"`
+	Relationship string          `json:"relationship"`
+	Date         string          `json:"date"`
+}
+
+// Lineage is the history of a minister across renames and merges. Ministers lists the entities
+// matching the name or ID that was asked for; there can be several when a ministry of the same
+// name was created again, e.g. by a later presidency. Predecessors holds every link leading to
+// them and Successors every link leading away from them, both in date order.
+type Lineage struct {
+	Ministers    []LineageMinister `json:"ministers"`
+	Predecessors []LineageLink     `json:"predecessors"`
+	Successors   []LineageLink     `json:"successors"`
+}
+
+// findMinisters returns the ministers with the given ID or, if there is none, the given name
+func findMinisters(store Store, nameOrID string) ([]LineageMinister, error) {
+	results, err := store.SearchEntities(&models.SearchCriteria{ID: nameOrID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for minister: %w", err)
+	}
+	if len(results) == 0 || results[0].Kind.Minor != "minister" {
+		results, err = store.SearchEntities(&models.SearchCriteria{
+			Kind: &models.Kind{
+				Major: "Organisation",
+				Minor: "minister",
+			},
+			Name: nameOrID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search for minister: %w", err)
+		}
+	}
+
+	ministers := []LineageMinister{}
+	for _, result := range results {
+		if result.Kind.Minor == "minister" {
+			ministers = append(ministers, LineageMinister{ID: result.ID, Name: result.Name})
+		}
+	}
+	return ministers, nil
+}
+
+// BuildLineage traces a minister, given by name or ID, through the RENAMED_TO and MERGED_INTO
+// relationships to the ministers it came from and the ones it became
+func BuildLineage(store Store, nameOrID string) (*Lineage, error) {
+	ministers, err := findMinisters(store, strings.TrimSpace(nameOrID))
+	if err != nil {
+		return nil, err
+	}
+	if len(ministers) == 0 {
+		return nil, fmt.Errorf("minister not found: %s", nameOrID)
+	}
+
+	lineage := &Lineage{Ministers: ministers}
+	names := map[string]string{}
+	for _, minister := range ministers {
+		names[minister.ID] = minister.Name
+	}
+
+	// The relationships to a minister are held by its predecessors, so every minister is a
+	// candidate when walking backwards
+	candidates, err := searchEntityIDs(store, models.Kind{Major: "Organisation", Minor: "minister"})
+	if err != nil {
+		return nil, err
+	}
+	lineage.Predecessors, err = walkLineage(store, ministers, candidates, names)
+	if err != nil {
+		return nil, err
+	}
+	lineage.Successors, err = walkLineage(store, ministers, nil, names)
+	if err != nil {
+		return nil, err
+	}
+	return lineage, nil
+}
+
+// walkLineage follows the lineage relationships from the given ministers in one direction: with
+// no predecessor candidates the relationships they hold (successors), otherwise the ones the
+// candidates hold to them (predecessors). names caches the names of the ministers seen so far.
+func walkLineage(store Store, start []LineageMinister, predecessorCandidates []string, names map[string]string) ([]LineageLink, error) {
+	incoming := predecessorCandidates != nil
+	visited := map[string]bool{}
+	queue := []string{}
+	for _, minister := range start {
+		visited[minister.ID] = true
+		queue = append(queue, minister.ID)
+	}
+
+	links := []LineageLink{}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		var relations []models.Relationship
+		var err error
+		if incoming {
+			relations, err = incomingRelationships(store, id, predecessorCandidates, models.Relationship{})
+		} else {
+			relations, err = store.GetAllRelatedEntities(id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get relationships of %s: %w", id, err)
+		}
+
+		for _, rel := range relations {
+			if _, ok := lineageRelationships[rel.Name]; !ok {
+				continue
+			}
+
+			otherID := rel.RelatedEntityID
+			if _, ok := names[otherID]; !ok {
+				node, err := snapshotNode(store, otherID)
+				if err != nil {
+					return nil, err
+				}
+				names[otherID] = node.Name
+			}
+
+			link := LineageLink{
+				From:         LineageMinister{ID: id, Name: names[id]},
+				To:           LineageMinister{ID: otherID, Name: names[otherID]},
+				Relationship: rel.Name,
+				Date:         strings.TrimSuffix(rel.StartTime, "T00:00:00Z"),
+			}
+			if incoming {
+				link.From, link.To = link.To, link.From
+			}
+			links = append(links, link)
+
+			if !visited[otherID] {
+				visited[otherID] = true
+				queue = append(queue, otherID)
+			}
+		}
+	}
+
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].Date != links[j].Date {
+			return links[i].Date < links[j].Date
+		}
+		return links[i].From.Name < links[j].From.Name
+	})
+	return links, nil
+}
+
+// Print writes the lineage with one line per rename or merge
+func (l *Lineage) Print(w io.Writer) {
+	for _, minister := range l.Ministers {
+		fmt.Fprintf(w, "Lineage of %s\n", minister)
+	}
+
+	printLinks := func(title string, links []LineageLink) {
+		fmt.Fprintf(w, "\n%s:\n", title)
+		if len(links) == 0 {
+			fmt.Fprintln(w, "  none")
+			return
+		}
+		for _, link := range links {
+			fmt.Fprintf(w, "  %s  %s %s %s\n", link.Date, link.From, lineageRelationships[link.Relationship], link.To)
+		}
+	}
+	printLinks("Predecessors", l.Predecessors)
+	printLinks("Successors", l.Successors)
+}
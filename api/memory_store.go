@@ -0,0 +1,227 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"orgchart_nexoan/models"
+)
+
+// Errors returned by MemoryStore
+var (
+	ErrEntityNotFound       = errors.New("entity not found")
+	ErrDuplicateEntity      = errors.New("entity already exists")
+	ErrRelationshipNotFound = errors.New("relationship not found")
+)
+
+// MemoryStore is a Store that keeps entities and their relationships in memory. It follows the
+// semantics of the Nexoan APIs, so transactions can be replayed and checked without a server.
+type MemoryStore struct {
+	mu       sync.Mutex
+	entities map[string]*models.Entity
+	// order holds the entity IDs in creation order, which is the order search results are returned in
+	order []string
+	// relationships holds the outgoing relationships of each entity, keyed by entity ID
+	relationships map[string][]models.Relationship
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entities:      map[string]*models.Entity{},
+		relationships: map[string][]models.Relationship{},
+	}
+}
+
+// addRelationships adds new relationships to an entity and sets the end time of existing ones
+func (m *MemoryStore) addRelationships(entityID string, entries []models.RelationshipEntry) error {
+	for _, entry := range entries {
+		rel := entry.Value
+		if rel.ID == "" {
+			rel.ID = entry.Key
+		}
+
+		existing := -1
+		for i, current := range m.relationships[entityID] {
+			if current.ID == rel.ID {
+				existing = i
+				break
+			}
+		}
+
+		if existing >= 0 {
+			// An update of an existing relationship sets its end time, which may reopen it
+			current := &m.relationships[entityID][existing]
+			current.EndTime = rel.EndTime
+			if rel.StartTime != "" {
+				current.StartTime = rel.StartTime
+			}
+			continue
+		}
+
+		if rel.RelatedEntityID == "" {
+			return fmt.Errorf("%w: %s on entity %s", ErrRelationshipNotFound, rel.ID, entityID)
+		}
+		m.relationships[entityID] = append(m.relationships[entityID], rel)
+	}
+	return nil
+}
+
+// CreateEntity creates a new entity together with the relationships it carries
+func (m *MemoryStore) CreateEntity(entity *models.Entity) (*models.Entity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entity.ID == "" {
+		return nil, fmt.Errorf("entity id is required")
+	}
+	if _, exists := m.entities[entity.ID]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateEntity, entity.ID)
+	}
+
+	if err := m.addRelationships(entity.ID, entity.Relationships); err != nil {
+		return nil, err
+	}
+	stored := *entity
+	stored.Relationships = nil
+	m.entities[entity.ID] = &stored
+	m.order = append(m.order, entity.ID)
+
+	created := *entity
+	return &created, nil
+}
+
+// UpdateEntity adds or ends relationships of an existing entity and updates its termination
+// date, metadata and attributes
+func (m *MemoryStore) UpdateEntity(id string, entity *models.Entity) (*models.Entity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, exists := m.entities[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrEntityNotFound, id)
+	}
+
+	if err := m.addRelationships(id, entity.Relationships); err != nil {
+		return nil, err
+	}
+	if entity.Terminated != "" {
+		stored.Terminated = entity.Terminated
+	}
+	stored.Metadata = append(stored.Metadata, entity.Metadata...)
+	stored.Attributes = append(stored.Attributes, entity.Attributes...)
+
+	updated := *stored
+	updated.Relationships = entity.Relationships
+	return &updated, nil
+}
+
+// DeleteEntity deletes an entity and its outgoing relationships
+func (m *MemoryStore) DeleteEntity(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.entities[id]; !exists {
+		return fmt.Errorf("%w: %s", ErrEntityNotFound, id)
+	}
+
+	delete(m.entities, id)
+	delete(m.relationships, id)
+	for i, current := range m.order {
+		if current == id {
+			m.order = append(m.order[:i], m.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// SearchEntities returns the entities matching the criteria in creation order
+func (m *MemoryStore) SearchEntities(criteria *models.SearchCriteria) ([]models.SearchResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := []models.SearchResult{}
+	for _, id := range m.order {
+		if entity := m.entities[id]; matchesSearchCriteria(entity, criteria) {
+			results = append(results, searchResult(entity))
+		}
+	}
+	return results, nil
+}
+
+// GetRelatedEntities returns the relationships of an entity matching the query
+func (m *MemoryStore) GetRelatedEntities(entityID string, query *models.Relationship) ([]models.Relationship, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.entities[entityID]; !exists {
+		return nil, fmt.Errorf("%w: %s", ErrEntityNotFound, entityID)
+	}
+
+	relations := []models.Relationship{}
+	for _, rel := range m.relationships[entityID] {
+		if matchesRelationshipQuery(rel, query) {
+			relations = append(relations, rel)
+		}
+	}
+	return relations, nil
+}
+
+// GetAllRelatedEntities returns all relationships of an entity
+func (m *MemoryStore) GetAllRelatedEntities(entityID string) ([]models.Relationship, error) {
+	return m.GetRelatedEntities(entityID, nil)
+}
+
+// Entity returns a copy of a stored entity without its relationships
+func (m *MemoryStore) Entity(id string) (models.Entity, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entity, exists := m.entities[id]
+	if !exists {
+		return models.Entity{}, false
+	}
+	return *entity, true
+}
+
+// GetEntityMetadata returns the metadata of an entity as a map of keys to values
+func (m *MemoryStore) GetEntityMetadata(entityID string) (map[string]interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entity, exists := m.entities[entityID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrEntityNotFound, entityID)
+	}
+	metadata := map[string]interface{}{}
+	for _, entry := range entity.Metadata {
+		metadata[entry.Key] = entry.Value
+	}
+	return metadata, nil
+}
+
+// GetRootEntities returns the IDs of the entities of the given major kind that no other entity
+// has a relationship to. An empty kind matches all entities.
+func (m *MemoryStore) GetRootEntities(kind string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	related := map[string]bool{}
+	for _, relations := range m.relationships {
+		for _, rel := range relations {
+			related[rel.RelatedEntityID] = true
+		}
+	}
+
+	roots := []string{}
+	for _, id := range m.order {
+		if !related[id] && (kind == "" || m.entities[id].Kind.Major == kind) {
+			roots = append(roots, id)
+		}
+	}
+	return roots, nil
+}
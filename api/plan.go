@@ -0,0 +1,282 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"orgchart_nexoan/models"
+)
+
+// PlanStep is a write that would have been sent to the Update API
+type PlanStep struct {
+	TransactionID string
+	FileType      string
+	// Method is the client method that would have been called: CreateEntity or UpdateEntity
+	Method   string
+	EntityID string
+	Entity   models.Entity
+}
+
+// PlanError is a transaction that could not be planned, e.g. because its parent entity was not found
+type PlanError struct {
+	TransactionID string
+	FileType      string
+	Err           error
+}
+
+// Plan records the writes of a dry run. It is a Store wrapping the real store (see
+// Processor.EnableDryRun): CreateEntity and UpdateEntity append to the plan instead of writing,
+// and the read methods merge the planned entities and relationships into the results of the
+// wrapped store so that later transactions can find what earlier ones would have created.
+type Plan struct {
+	Steps  []PlanStep
+	Errors []PlanError
+
+	store         Store
+	transactionID string
+	fileType      string
+	entities      map[string]*models.Entity
+	// relationships holds the planned relationships of each entity, keyed by entity ID
+	relationships map[string][]models.Relationship
+	// endTimes holds planned end times of existing relationships, keyed by entity ID and relationship ID
+	endTimes map[string]map[string]string
+}
+
+var _ Store = (*Plan)(nil)
+
+// NewPlan creates an empty plan that reads from the given store
+func NewPlan(store Store) *Plan {
+	return &Plan{
+		store:         store,
+		entities:      map[string]*models.Entity{},
+		relationships: map[string][]models.Relationship{},
+		endTimes:      map[string]map[string]string{},
+	}
+}
+
+// attribute attributes the steps recorded from now on to the given transaction ID and file type.
+// Empty values attribute them to the initialisation, such as the creation of the root nodes.
+func (p *Plan) attribute(transactionID string, fileType string) {
+	p.transactionID = transactionID
+	p.fileType = fileType
+}
+
+// addError records that the given transaction could not be planned
+func (p *Plan) addError(transaction Transaction, err error) {
+	p.Errors = append(p.Errors, PlanError{
+		TransactionID: transaction.ID(),
+		FileType:      transaction.FileType(),
+		Err:           err,
+	})
+}
+
+// CreateEntity records the creation of an entity
+func (p *Plan) CreateEntity(entity *models.Entity) (*models.Entity, error) {
+	if _, exists := p.entities[entity.ID]; exists {
+		return nil, fmt.Errorf("entity %s is already created earlier in the plan", entity.ID)
+	}
+
+	created := *entity
+	created.Relationships = nil
+	p.entities[entity.ID] = &created
+	p.Steps = append(p.Steps, PlanStep{
+		TransactionID: p.transactionID,
+		FileType:      p.fileType,
+		Method:        "CreateEntity",
+		EntityID:      entity.ID,
+		Entity:        *entity,
+	})
+
+	return &created, nil
+}
+
+// UpdateEntity records an update of an entity. A relationship entry with a related entity
+// adds a relationship; one that only carries an ID and an end time ends an existing relationship.
+func (p *Plan) UpdateEntity(id string, entity *models.Entity) (*models.Entity, error) {
+	for _, entry := range entity.Relationships {
+		rel := entry.Value
+		if rel.RelatedEntityID != "" {
+			p.relationships[id] = append(p.relationships[id], rel)
+			continue
+		}
+
+		// End a relationship planned earlier, or one that already exists in Nexoan
+		ended := false
+		for i := range p.relationships[id] {
+			if p.relationships[id][i].ID == rel.ID && p.relationships[id][i].EndTime == "" {
+				p.relationships[id][i].EndTime = rel.EndTime
+				ended = true
+				break
+			}
+		}
+		if !ended {
+			if p.endTimes[id] == nil {
+				p.endTimes[id] = map[string]string{}
+			}
+			p.endTimes[id][rel.ID] = rel.EndTime
+		}
+	}
+
+	p.Steps = append(p.Steps, PlanStep{
+		TransactionID: p.transactionID,
+		FileType:      p.fileType,
+		Method:        "UpdateEntity",
+		EntityID:      id,
+		Entity:        *entity,
+	})
+
+	return entity, nil
+}
+
+// DeleteEntity is not part of any transaction, so it cannot be planned
+func (p *Plan) DeleteEntity(id string) error {
+	return fmt.Errorf("cannot delete entity %s in a dry run", id)
+}
+
+// SearchEntities returns the entities of the wrapped store that match the search criteria,
+// followed by the matching planned entities
+func (p *Plan) SearchEntities(criteria *models.SearchCriteria) ([]models.SearchResult, error) {
+	results, err := p.store.SearchEntities(criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	var planned []models.SearchResult
+	for _, entity := range p.entities {
+		if matchesSearchCriteria(entity, criteria) {
+			planned = append(planned, searchResult(entity))
+		}
+	}
+	sort.Slice(planned, func(i, j int) bool {
+		return planned[i].ID < planned[j].ID
+	})
+	return append(results, planned...), nil
+}
+
+// GetRelatedEntities returns the relationships of an entity matching the query, including the
+// planned ones
+func (p *Plan) GetRelatedEntities(entityID string, query *models.Relationship) ([]models.Relationship, error) {
+	if p.isPlannedEntity(entityID) {
+		return p.mergeRelationships(entityID, nil, query), nil
+	}
+
+	relations, err := p.store.GetRelatedEntities(entityID, query)
+	if err != nil {
+		return nil, err
+	}
+	return p.mergeRelationships(entityID, relations, query), nil
+}
+
+// GetAllRelatedEntities returns all relationships of an entity, including the planned ones
+func (p *Plan) GetAllRelatedEntities(entityID string) ([]models.Relationship, error) {
+	if p.isPlannedEntity(entityID) {
+		return p.mergeRelationships(entityID, nil, nil), nil
+	}
+
+	relations, err := p.store.GetAllRelatedEntities(entityID)
+	if err != nil {
+		return nil, err
+	}
+	return p.mergeRelationships(entityID, relations, nil), nil
+}
+
+// isPlannedEntity reports whether the entity only exists in the plan
+func (p *Plan) isPlannedEntity(id string) bool {
+	_, exists := p.entities[id]
+	return exists
+}
+
+// mergeRelationships applies the planned end times to the given relationships of an entity and
+// appends the planned relationships of the entity that match the query. A nil query matches all.
+func (p *Plan) mergeRelationships(entityID string, relations []models.Relationship, query *models.Relationship) []models.Relationship {
+	for i := range relations {
+		if endTime, exists := p.endTimes[entityID][relations[i].ID]; exists && relations[i].EndTime == "" {
+			relations[i].EndTime = endTime
+		}
+	}
+
+	for _, rel := range p.relationships[entityID] {
+		if !matchesRelationshipQuery(rel, query) {
+			continue
+		}
+		relations = append(relations, rel)
+	}
+
+	return relations
+}
+
+// rootEntities returns the root entities of the wrapped store followed by the planned ones,
+// leaving out those a planned relationship points to, and false if the wrapped store cannot
+// list root entities
+func (p *Plan) rootEntities(kind string) ([]string, bool, error) {
+	existing, ok, err := listRootEntities(p.store, kind)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	related := map[string]bool{}
+	for _, relations := range p.relationships {
+		for _, rel := range relations {
+			related[rel.RelatedEntityID] = true
+		}
+	}
+
+	roots := []string{}
+	for _, id := range existing {
+		if !related[id] {
+			roots = append(roots, id)
+		}
+	}
+	var planned []string
+	for id, entity := range p.entities {
+		if !related[id] && (kind == "" || entity.Kind.Major == kind) {
+			planned = append(planned, id)
+		}
+	}
+	sort.Strings(planned)
+	return append(roots, planned...), true, nil
+}
+
+// Print writes the plan in a human readable form, grouped by transaction
+func (p *Plan) Print(w io.Writer) {
+	created, added, ended := 0, 0, 0
+	for i, step := range p.Steps {
+		if i == 0 || step.TransactionID != p.Steps[i-1].TransactionID {
+			if step.TransactionID == "" {
+				fmt.Fprintf(w, "Initialisation\n")
+			} else {
+				fmt.Fprintf(w, "%s (%s)\n", step.TransactionID, step.FileType)
+			}
+		}
+
+		if step.Method == "CreateEntity" {
+			created++
+			fmt.Fprintf(w, "  CreateEntity %s: %s/%s %q created %s\n",
+				step.EntityID, step.Entity.Kind.Major, step.Entity.Kind.Minor, step.Entity.Name.Value, step.Entity.Created)
+			continue
+		}
+
+		for _, entry := range step.Entity.Relationships {
+			rel := entry.Value
+			if rel.RelatedEntityID != "" {
+				added++
+				fmt.Fprintf(w, "  UpdateEntity %s: add relationship %s %s -> %s from %s\n",
+					step.EntityID, rel.ID, rel.Name, rel.RelatedEntityID, rel.StartTime)
+			} else {
+				ended++
+				fmt.Fprintf(w, "  UpdateEntity %s: end relationship %s at %s\n", step.EntityID, rel.ID, rel.EndTime)
+			}
+		}
+	}
+
+	if len(p.Errors) > 0 {
+		fmt.Fprintf(w, "\nErrors:\n")
+		for _, planError := range p.Errors {
+			fmt.Fprintf(w, "  %s (%s): %v\n", planError.TransactionID, planError.FileType, planError.Err)
+		}
+	}
+
+	fmt.Fprintf(w, "\nPlan: %d entities to create, %d relationships to add, %d relationships to end, %d errors\n",
+		created, added, ended, len(p.Errors))
+}
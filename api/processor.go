@@ -0,0 +1,51 @@
+package api
+
+// Processor applies transactions to a Store. The operations in entity_operations.go and the
+// processing of transaction files are methods on it, so they run against the Nexoan HTTP APIs
+// (Client), an in-memory graph (MemoryStore) or any wrapper of those alike.
+type Processor struct {
+	store Store
+	// plan is set in dry-run mode, see EnableDryRun
+	plan *Plan
+	// root is the entity terms belong to unless their manifest names one, see SetRootNode
+	root RootNode
+}
+
+// NewProcessor creates a processor that reads from and writes to the given store
+func NewProcessor(store Store) *Processor {
+	return &Processor{store: store, root: DefaultRootNode()}
+}
+
+// SetRootNode sets the root entity the terms created by the processor belong to when their
+// manifest names no root. It is the default government node unless set.
+func (p *Processor) SetRootNode(root RootNode) {
+	p.root = root
+}
+
+// EnableDryRun switches the processor to dry-run mode and returns the plan the writes are
+// recorded in. Reads are still sent to the underlying store.
+func (p *Processor) EnableDryRun() *Plan {
+	p.plan = NewPlan(p.store)
+	p.store = p.plan
+	return p.plan
+}
+
+// recordChanges runs fn and returns the writes it made to the store, including those made
+// before fn failed
+func (p *Processor) recordChanges(fn func() error) ([]Change, error) {
+	store := p.store
+	recorder := NewRecordingStore(store)
+	p.store = recorder
+	defer func() { p.store = store }()
+
+	err := fn()
+	return recorder.Changes(), err
+}
+
+// recordReuse notes in the changes being recorded, if any, that an existing entity was used
+// instead of creating one
+func (p *Processor) recordReuse(id string) {
+	if recorder, ok := p.store.(*RecordingStore); ok {
+		recorder.recordReuse(id)
+	}
+}
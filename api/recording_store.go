@@ -0,0 +1,75 @@
+package api
+
+import "orgchart_nexoan/models"
+
+// RecordingStore wraps a Store and records every successful write made through it as a Change,
+// with enough detail for the write to be reversed
+type RecordingStore struct {
+	Store
+	changes []Change
+}
+
+// NewRecordingStore creates a recording wrapper around store
+func NewRecordingStore(store Store) *RecordingStore {
+	return &RecordingStore{
+		Store:   store,
+		changes: []Change{},
+	}
+}
+
+// Changes returns the recorded writes in the order they were made
+func (r *RecordingStore) Changes() []Change {
+	return r.changes
+}
+
+// recordReuse records that an existing entity was used instead of creating one
+func (r *RecordingStore) recordReuse(id string) {
+	r.changes = append(r.changes, Change{Operation: ChangeReuseEntity, EntityID: id})
+}
+
+// recordRelationships records the relationships added or ended by a write to an entity
+func (r *RecordingStore) recordRelationships(id string, entries []models.RelationshipEntry) {
+	for _, entry := range entries {
+		rel := entry.Value
+		if rel.RelatedEntityID != "" {
+			r.changes = append(r.changes, Change{
+				Operation:       ChangeAddRelationship,
+				EntityID:        id,
+				RelationshipID:  rel.ID,
+				RelatedEntityID: rel.RelatedEntityID,
+				Name:            rel.Name,
+				StartTime:       rel.StartTime,
+			})
+		} else if rel.EndTime != "" {
+			r.changes = append(r.changes, Change{
+				Operation:      ChangeEndRelationship,
+				EntityID:       id,
+				RelationshipID: rel.ID,
+				EndTime:        rel.EndTime,
+			})
+		}
+	}
+}
+
+// CreateEntity creates the entity in the wrapped store and records it
+func (r *RecordingStore) CreateEntity(entity *models.Entity) (*models.Entity, error) {
+	created, err := r.Store.CreateEntity(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	r.changes = append(r.changes, Change{Operation: ChangeCreateEntity, EntityID: entity.ID})
+	r.recordRelationships(entity.ID, entity.Relationships)
+	return created, nil
+}
+
+// UpdateEntity updates the entity in the wrapped store and records the relationship changes
+func (r *RecordingStore) UpdateEntity(id string, entity *models.Entity) (*models.Entity, error) {
+	updated, err := r.Store.UpdateEntity(id, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	r.recordRelationships(id, entity.Relationships)
+	return updated, nil
+}
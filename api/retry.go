@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how the client retries requests that are safe to repeat: reads, PUT
+// and DELETE. Creating an entity is never retried. A request is retried when it fails to reach
+// Nexoan or Nexoan answers with a server error (5xx); the wait between attempts starts at
+// InitialBackoff and is multiplied by Multiplier after every attempt, up to MaxBackoff.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one; 1 or less disables retries
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	Multiplier     float64       `yaml:"multiplier"`
+}
+
+// DefaultRetryPolicy returns the retry policy used by NewClient unless WithRetryPolicy is given
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+	}
+}
+
+// NoRetry is a retry policy that sends every request once
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// backoff returns the wait before the given retry, counting from 1
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	wait := p.InitialBackoff
+	for i := 1; i < retry; i++ {
+		wait = time.Duration(float64(wait) * p.Multiplier)
+		if p.MaxBackoff > 0 && wait >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return wait
+}
+
+// shouldRetry reports whether a failed attempt is worth repeating
+func shouldRetry(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		return true
+	}
+	// Any other error that is not about the response is a failure to reach Nexoan
+	var reqErr *RequestError
+	return !errors.As(err, &reqErr)
+}
+
+// isIdempotent reports whether a request can be repeated without changing its effect. The
+// query endpoints are read-only even though some of them use POST.
+func isIdempotent(method string, readOnly bool) bool {
+	return readOnly || method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ClientOption configures a Client created by NewClient
+type ClientOption func(*Client)
+
+// WithRetryPolicy sets the retry policy of the client
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests, e.g. to change the timeout
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"orgchart_nexoan/models"
+)
+
+// RootNode describes a root entity of the org chart, such as the national government or a
+// provincial council. Ministers are added under it by ADD rows naming it as the parent, with
+// its Kind as the parent_type.
+type RootNode struct {
+	ID   string `json:"id" yaml:"id"`
+	Name string `json:"name" yaml:"name"`
+	// Created is the date the root entity was created on, in YYYY-MM-DD format
+	Created string `json:"created" yaml:"created"`
+	// Kind is the minor kind of the entity; the major kind is always Organisation
+	Kind string `json:"kind" yaml:"kind"`
+}
+
+// DefaultRootNode returns the government node created by CreateGovernmentNode. It is created on
+// 2015-01-09, the start of the earliest presidency in data/, so that the relationships of every
+// gazette there start after it.
+func DefaultRootNode() RootNode {
+	return RootNode{
+		ID:      DefaultGovernmentID,
+		Name:    "Government of Sri Lanka",
+		Created: "2015-01-09",
+		Kind:    "government",
+	}
+}
+
+// LoadRootNodes reads a JSON array of root nodes from the given file. Fields left empty take
+// their value from DefaultRootNode, except the ID and name, which are required.
+func LoadRootNodes(path string) ([]RootNode, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read root nodes from %s: %w", path, err)
+	}
+
+	var roots []RootNode
+	if err := json.Unmarshal(data, &roots); err != nil {
+		return nil, fmt.Errorf("failed to decode root nodes from %s: %w", path, err)
+	}
+	if err := completeRootNodes(roots, path); err != nil {
+		return nil, err
+	}
+
+	return roots, nil
+}
+
+// completeRootNodes fills in the empty fields of root nodes read from the file at path from
+// DefaultRootNode, and checks that each has an ID and a name
+func completeRootNodes(roots []RootNode, path string) error {
+	defaults := DefaultRootNode()
+	for i := range roots {
+		if roots[i].ID == "" || roots[i].Name == "" {
+			return fmt.Errorf("root node %d in %s needs an id and a name", i+1, path)
+		}
+		if roots[i].Created == "" {
+			roots[i].Created = defaults.Created
+		}
+		if roots[i].Kind == "" {
+			roots[i].Kind = defaults.Kind
+		}
+	}
+	return nil
+}
+
+// CreateGovernmentNode creates the initial government node, or returns it if it already exists
+func (p *Processor) CreateGovernmentNode() (*models.Entity, error) {
+	entity, _, err := p.CreateRootNode(DefaultRootNode())
+	return entity, err
+}
+
+// CreateRootNode creates a root entity and reports whether it was created. An entity that
+// already exists with the root's ID is returned as is, so initialising twice is harmless, unless
+// it has another name or kind, which is an error.
+func (p *Processor) CreateRootNode(root RootNode) (*models.Entity, bool, error) {
+	created, err := time.Parse("2006-01-02", strings.TrimSpace(root.Created))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse created date of root %s: %w", root.ID, err)
+	}
+	createdISO := created.Format(time.RFC3339)
+	kind := models.Kind{Major: "Organisation", Minor: root.Kind}
+
+	existing, err := p.store.SearchEntities(&models.SearchCriteria{ID: root.ID})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to search for root entity %s: %w", root.ID, err)
+	}
+	if len(existing) > 0 {
+		if existing[0].Name != root.Name || existing[0].Kind != kind {
+			return nil, false, fmt.Errorf("root entity %s already exists as %s/%s %q",
+				root.ID, existing[0].Kind.Major, existing[0].Kind.Minor, existing[0].Name)
+		}
+		return &models.Entity{
+			ID:         existing[0].ID,
+			Kind:       existing[0].Kind,
+			Created:    existing[0].Created,
+			Terminated: existing[0].Terminated,
+			Name:       models.TimeBasedValue{StartTime: existing[0].Created, Value: existing[0].Name},
+		}, false, nil
+	}
+
+	rootEntity := &models.Entity{
+		ID:      root.ID,
+		Created: createdISO,
+		Kind:    kind,
+		Name: models.TimeBasedValue{
+			StartTime: createdISO,
+			Value:     root.Name,
+		},
+	}
+
+	createdEntity, err := p.store.CreateEntity(rootEntity)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create root entity %s: %w", root.ID, err)
+	}
+
+	return createdEntity, true, nil
+}
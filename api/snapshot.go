@@ -0,0 +1,180 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"orgchart_nexoan/models"
+)
+
+// DefaultGovernmentID is the ID of the government node created by CreateGovernmentNode
+const DefaultGovernmentID = "gov_01"
+
+// snapshotRelationships lists, for each kind of entity, the relationships followed to its
+// children when building a snapshot. The data relates the government to its ministers with both
+// AS_MINISTER and HAS_MINISTER, e.g. the state ministers of data/orgchart/gr.
+var snapshotRelationships = map[string][]string{
+	"government": {"AS_MINISTER", "HAS_MINISTER"},
+	TermKind:     {TermMinisterRelationship},
+	"minister":   {"AS_DEPARTMENT", "AS_APPOINTED"},
+}
+
+// SnapshotNode is an entity of the org chart together with the entities it is related to on
+// the snapshot date
+type SnapshotNode struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Relationship is the name of the relationship from the parent node, and Since its start time
+	Relationship string          `json:"relationship,omitempty"`
+	Since        string          `json:"since,omitempty"`
+	Children     []*SnapshotNode `json:"children,omitempty"`
+}
+
+// Snapshot is the org chart as it was on a given date: the government, its ministers, and
+// their departments and appointed people
+type Snapshot struct {
+	Date string        `json:"date"`
+	Root *SnapshotNode `json:"root"`
+}
+
+// rootLister is implemented by the stores that can list root entities
+type rootLister interface {
+	GetRootEntities(kind string) ([]string, error)
+}
+
+// findGovernmentRoot returns the ID of the government root entity of the store. It uses
+// GetRootEntities when the store supports it and falls back to DefaultGovernmentID.
+func findGovernmentRoot(store Store) (string, error) {
+	roots, ok, err := listRootEntities(store, "Organisation")
+	if err != nil {
+		return "", fmt.Errorf("failed to get root entities: %w", err)
+	}
+	if !ok {
+		return DefaultGovernmentID, nil
+	}
+	for _, id := range roots {
+		results, err := store.SearchEntities(&models.SearchCriteria{ID: id})
+		if err != nil {
+			return "", fmt.Errorf("failed to search for root entity: %w", err)
+		}
+		if len(results) > 0 && results[0].Kind.Minor == "government" {
+			return id, nil
+		}
+	}
+	return DefaultGovernmentID, nil
+}
+
+// BuildSnapshot walks the org chart from the government root and keeps the relationships
+// active on date (YYYY-MM-DD). An empty rootID selects the government root of the store.
+func BuildSnapshot(store Store, rootID string, date string) (*Snapshot, error) {
+	parsedDate, err := time.Parse("2006-01-02", strings.TrimSpace(date))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse date: %w", err)
+	}
+	dateISO := parsedDate.Format(time.RFC3339)
+
+	if rootID == "" {
+		rootID, err = findGovernmentRoot(store)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	root, err := snapshotNode(store, rootID)
+	if err != nil {
+		return nil, err
+	}
+	if err := addSnapshotChildren(store, root, dateISO, map[string]bool{root.ID: true}); err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{Date: parsedDate.Format("2006-01-02"), Root: root}, nil
+}
+
+// snapshotNode looks up the name and kind of an entity
+func snapshotNode(store Store, id string) (*SnapshotNode, error) {
+	results, err := store.SearchEntities(&models.SearchCriteria{ID: id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for entity %s: %w", id, err)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("entity not found: %s", id)
+	}
+	return &SnapshotNode{
+		ID:   results[0].ID,
+		Name: results[0].Name,
+		Kind: results[0].Kind.Minor,
+	}, nil
+}
+
+// addSnapshotChildren adds the children of node that are related to it on dateISO, and their
+// children in turn. ancestors holds the IDs on the path from the root and guards against cycles
+// in the data; an entity related to several nodes, such as a person holding two portfolios,
+// appears under each of them.
+func addSnapshotChildren(store Store, node *SnapshotNode, dateISO string, ancestors map[string]bool) error {
+	relNames := snapshotRelationships[node.Kind]
+	if node.Relationship == "" && relNames == nil {
+		// A root of another kind is walked like a government, e.g. a provincial council
+		relNames = snapshotRelationships["government"]
+	}
+	for _, relName := range relNames {
+		query := &models.Relationship{Name: relName, StartTime: dateISO}
+		relations, err := store.GetRelatedEntities(node.ID, query)
+		if err != nil {
+			return fmt.Errorf("failed to get %s relationships of %s: %w", relName, node.ID, err)
+		}
+
+		for _, rel := range relations {
+			if !matchesRelationshipQuery(rel, query) || ancestors[rel.RelatedEntityID] {
+				continue
+			}
+
+			child, err := snapshotNode(store, rel.RelatedEntityID)
+			if err != nil {
+				return err
+			}
+			child.Relationship = rel.Name
+			child.Since = rel.StartTime
+
+			ancestors[child.ID] = true
+			err = addSnapshotChildren(store, child, dateISO, ancestors)
+			delete(ancestors, child.ID)
+			if err != nil {
+				return err
+			}
+			node.Children = append(node.Children, child)
+		}
+	}
+
+	// Order children by relationship, then name, so that output is stable
+	sort.Slice(node.Children, func(i, j int) bool {
+		if node.Children[i].Relationship != node.Children[j].Relationship {
+			return node.Children[i].Relationship < node.Children[j].Relationship
+		}
+		return node.Children[i].Name < node.Children[j].Name
+	})
+	return nil
+}
+
+// Print writes the snapshot as an indented tree
+func (s *Snapshot) Print(w io.Writer) {
+	fmt.Fprintf(w, "Org chart on %s\n", s.Date)
+	printSnapshotNode(w, s.Root, 0)
+}
+
+// printSnapshotNode writes a node and its children, indented by depth
+func printSnapshotNode(w io.Writer, node *SnapshotNode, depth int) {
+	fmt.Fprintf(w, "%s%s [%s] (%s)", strings.Repeat("  ", depth), node.Name, node.Kind, node.ID)
+	if node.Since != "" {
+		fmt.Fprintf(w, " since %s", strings.TrimSuffix(node.Since, "T00:00:00Z"))
+	}
+	fmt.Fprintln(w)
+
+	for _, child := range node.Children {
+		printSnapshotNode(w, child, depth+1)
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"fmt"
+
+	"orgchart_nexoan/models"
+)
+
+// Store is the backend the transaction operations read entities and relationships from and
+// write them to. Client implements it over the Nexoan HTTP APIs; MemoryStore keeps a graph in
+// memory, and Plan and RecordingStore wrap another Store.
+type Store interface {
+	// CreateEntity creates a new entity together with the relationships it carries
+	CreateEntity(entity *models.Entity) (*models.Entity, error)
+	// UpdateEntity updates an existing entity. A relationship entry with a related entity adds a
+	// relationship; one that only carries an ID and an end time sets the end time of an existing one.
+	UpdateEntity(id string, entity *models.Entity) (*models.Entity, error)
+	// DeleteEntity deletes an entity
+	DeleteEntity(id string) error
+	// SearchEntities returns the entities matching the criteria, with plain names
+	SearchEntities(criteria *models.SearchCriteria) ([]models.SearchResult, error)
+	// GetRelatedEntities returns the relationships of an entity matching the query. The start
+	// time of the query selects the relationships active at that time.
+	GetRelatedEntities(entityID string, query *models.Relationship) ([]models.Relationship, error)
+	// GetAllRelatedEntities returns all relationships of an entity
+	GetAllRelatedEntities(entityID string) ([]models.Relationship, error)
+}
+
+var _ Store = (*Client)(nil)
+
+// matchesSearchCriteria reports whether an entity matches search criteria
+func matchesSearchCriteria(entity *models.Entity, criteria *models.SearchCriteria) bool {
+	if criteria.ID != "" && criteria.ID != entity.ID {
+		return false
+	}
+	if criteria.Kind != nil {
+		if criteria.Kind.Major != "" && criteria.Kind.Major != entity.Kind.Major {
+			return false
+		}
+		if criteria.Kind.Minor != "" && criteria.Kind.Minor != entity.Kind.Minor {
+			return false
+		}
+	}
+	name, _ := entity.Name.Value.(string)
+	if criteria.Name != "" && criteria.Name != name {
+		return false
+	}
+	if criteria.Created != "" && criteria.Created != entity.Created {
+		return false
+	}
+	if criteria.Terminated != "" && criteria.Terminated != entity.Terminated {
+		return false
+	}
+	return true
+}
+
+// searchResult converts an entity to a search result with a plain name
+func searchResult(entity *models.Entity) models.SearchResult {
+	name, _ := entity.Name.Value.(string)
+	return models.SearchResult{
+		ID:         entity.ID,
+		Kind:       entity.Kind,
+		Name:       name,
+		Created:    entity.Created,
+		Terminated: entity.Terminated,
+	}
+}
+
+// matchesRelationshipQuery reports whether a relationship matches a relations query. A nil
+// query matches all relationships.
+func matchesRelationshipQuery(rel models.Relationship, query *models.Relationship) bool {
+	if query == nil {
+		return true
+	}
+	if query.ID != "" && query.ID != rel.ID {
+		return false
+	}
+	if query.RelatedEntityID != "" && query.RelatedEntityID != rel.RelatedEntityID {
+		return false
+	}
+	if query.Name != "" && query.Name != rel.Name {
+		return false
+	}
+	if query.StartTime != "" && (rel.StartTime > query.StartTime || (rel.EndTime != "" && rel.EndTime <= query.StartTime)) {
+		return false
+	}
+	return true
+}
+
+// incomingRelationships returns the relationships pointing to entityID that are held by the
+// entities in holderIDs, as seen from entityID: RelatedEntityID is the entity holding the
+// relationship. The Query API only returns the relationships an entity holds, so each holder is
+// asked for its relationships to entityID. The query may also filter on Name and StartTime.
+func incomingRelationships(store Store, entityID string, holderIDs []string, query models.Relationship) ([]models.Relationship, error) {
+	query.RelatedEntityID = entityID
+	relations := []models.Relationship{}
+	for _, holderID := range holderIDs {
+		if holderID == entityID {
+			continue
+		}
+		held, err := store.GetRelatedEntities(holderID, &query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get relationships of %s: %w", holderID, err)
+		}
+		for _, rel := range held {
+			if !matchesRelationshipQuery(rel, &query) {
+				continue
+			}
+			rel.RelatedEntityID = holderID
+			relations = append(relations, rel)
+		}
+	}
+	return relations, nil
+}
+
+// relationshipHolders returns the IDs of the entities that can hold a relationship to the given
+// entity: the roots and the terms hold the relationships to ministers, the roots the ones to
+// terms, and ministers the ones to departments, people and the ministers they were renamed to or
+// merged into. Roots have none.
+func relationshipHolders(store Store, entity models.SearchResult) ([]string, error) {
+	roots, err := graphRoots(store)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range roots {
+		if id == entity.ID {
+			return nil, nil
+		}
+	}
+
+	switch entity.Kind.Minor {
+	case TermKind:
+		return roots, nil
+	case "minister":
+		terms, err := searchEntityIDs(store, models.Kind{Major: "Organisation", Minor: TermKind})
+		if err != nil {
+			return nil, err
+		}
+		ministers, err := searchEntityIDs(store, models.Kind{Major: "Organisation", Minor: "minister"})
+		if err != nil {
+			return nil, err
+		}
+		return append(append(roots, terms...), ministers...), nil
+	default:
+		return searchEntityIDs(store, models.Kind{Major: "Organisation", Minor: "minister"})
+	}
+}
+
+// searchEntityIDs returns the IDs of the entities of the given kind
+func searchEntityIDs(store Store, kind models.Kind) ([]string, error) {
+	results, err := store.SearchEntities(&models.SearchCriteria{Kind: &kind})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for %s entities: %w", kind.Minor, err)
+	}
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return ids, nil
+}
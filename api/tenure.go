@@ -0,0 +1,211 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"orgchart_nexoan/models"
+)
+
+// Tenure is one portfolio held by a person: an appointment relationship from a minister
+type Tenure struct {
+	PersonID     string `json:"person_id"`
+	PersonName   string `json:"person_name"`
+	MinisterID   string `json:"minister_id"`
+	MinisterName string `json:"minister_name"`
+	Relationship string `json:"relationship"`
+	StartDate    string `json:"start_date"`
+	// EndDate is empty while the person still holds the portfolio
+	EndDate string `json:"end_date,omitempty"`
+	// StartTransaction and EndTransaction are the gazette transactions that made the appointment
+	// and ended it, as recorded in the journal. They are empty when no journal was given or the
+	// change was made before the journal recorded changes.
+	StartTransaction string `json:"start_transaction,omitempty"`
+	EndTransaction   string `json:"end_transaction,omitempty"`
+}
+
+// TenureHistory is every portfolio held by the people with a given name, in date order
+type TenureHistory struct {
+	Person  string   `json:"person"`
+	Tenures []Tenure `json:"tenures"`
+}
+
+// tenureCSVHeader lists the columns written by WriteCSV
+var tenureCSVHeader = []string{"person_id", "person", "minister_id", "minister", "relationship", "start_date", "end_date", "start_transaction", "end_transaction"}
+
+// findPeople returns the people with the given ID or, if there is none, the given name
+func findPeople(store Store, nameOrID string) ([]models.SearchResult, error) {
+	results, err := store.SearchEntities(&models.SearchCriteria{ID: nameOrID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for person: %w", err)
+	}
+	if len(results) > 0 && results[0].Kind.Major == "Person" {
+		return results, nil
+	}
+
+	results, err = store.SearchEntities(&models.SearchCriteria{
+		Kind: &models.Kind{
+			Major: "Person",
+		},
+		Name: nameOrID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for person: %w", err)
+	}
+	return results, nil
+}
+
+// journalTransactions maps the relationship changes of a journal to the transactions that made
+// them, keyed by the entity holding the relationship, the relationship ID and the start or end time
+type journalTransactions struct {
+	added map[string]string
+	ended map[string]string
+}
+
+// newJournalTransactions indexes the changes of a journal. A nil journal gives an empty index.
+func newJournalTransactions(journal *Journal) *journalTransactions {
+	index := &journalTransactions{added: map[string]string{}, ended: map[string]string{}}
+	if journal == nil {
+		return index
+	}
+	for _, entry := range journal.Entries() {
+		if entry.Undo {
+			continue
+		}
+		for _, change := range entry.Changes {
+			switch change.Operation {
+			case ChangeAddRelationship:
+				index.added[change.EntityID+"/"+change.RelationshipID+"/"+change.StartTime] = entry.TransactionID
+			case ChangeEndRelationship:
+				index.ended[change.EntityID+"/"+change.RelationshipID+"/"+change.EndTime] = entry.TransactionID
+			}
+		}
+	}
+	return index
+}
+
+// BuildTenureHistory collects the AS_APPOINTED relationships pointing to the person with the given
+// name or ID. The journal, which may be nil, is used to find the transaction behind every change.
+func BuildTenureHistory(store Store, nameOrID string, journal *Journal) (*TenureHistory, error) {
+	nameOrID = strings.TrimSpace(nameOrID)
+	people, err := findPeople(store, nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	if len(people) == 0 {
+		return nil, fmt.Errorf("person not found: %s", nameOrID)
+	}
+
+	transactions := newJournalTransactions(journal)
+	history := &TenureHistory{Person: nameOrID, Tenures: []Tenure{}}
+	ministerNames := map[string]string{}
+	// Appointments are held by the ministers, so every minister is asked for its appointments of
+	// the person
+	ministers, err := searchEntityIDs(store, models.Kind{Major: "Organisation", Minor: "minister"})
+	if err != nil {
+		return nil, err
+	}
+	for _, person := range people {
+		relations, err := incomingRelationships(store, person.ID, ministers, models.Relationship{Name: "AS_APPOINTED"})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get appointments of %s: %w", person.ID, err)
+		}
+
+		for _, rel := range relations {
+			ministerID := rel.RelatedEntityID
+			if _, ok := ministerNames[ministerID]; !ok {
+				node, err := snapshotNode(store, ministerID)
+				if err != nil {
+					return nil, err
+				}
+				ministerNames[ministerID] = node.Name
+			}
+
+			tenure := Tenure{
+				PersonID:         person.ID,
+				PersonName:       person.Name,
+				MinisterID:       ministerID,
+				MinisterName:     ministerNames[ministerID],
+				Relationship:     rel.Name,
+				StartDate:        strings.TrimSuffix(rel.StartTime, "T00:00:00Z"),
+				EndDate:          strings.TrimSuffix(rel.EndTime, "T00:00:00Z"),
+				StartTransaction: transactions.added[ministerID+"/"+rel.ID+"/"+rel.StartTime],
+			}
+			if rel.EndTime != "" {
+				tenure.EndTransaction = transactions.ended[ministerID+"/"+rel.ID+"/"+rel.EndTime]
+			}
+			history.Tenures = append(history.Tenures, tenure)
+		}
+	}
+
+	sort.SliceStable(history.Tenures, func(i, j int) bool {
+		a, b := history.Tenures[i], history.Tenures[j]
+		if a.StartDate != b.StartDate {
+			return a.StartDate < b.StartDate
+		}
+		return a.MinisterName < b.MinisterName
+	})
+	return history, nil
+}
+
+// Print writes the tenure history as a timeline with one line per portfolio
+func (h *TenureHistory) Print(w io.Writer) {
+	fmt.Fprintf(w, "Tenure history of %s\n", h.Person)
+	if len(h.Tenures) == 0 {
+		fmt.Fprintln(w, "  no appointments")
+		return
+	}
+
+	for _, tenure := range h.Tenures {
+		end := tenure.EndDate
+		if end == "" {
+			end = "present"
+		}
+		fmt.Fprintf(w, "  %s to %-10s  %s (%s) %s", tenure.StartDate, end, tenure.MinisterName, tenure.MinisterID, tenure.Relationship)
+		if tenure.StartTransaction != "" || tenure.EndTransaction != "" {
+			fmt.Fprintf(w, " [started by %s", valueOrUnknown(tenure.StartTransaction))
+			if tenure.EndDate != "" {
+				fmt.Fprintf(w, ", ended by %s", valueOrUnknown(tenure.EndTransaction))
+			}
+			fmt.Fprint(w, "]")
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// valueOrUnknown returns value, or "unknown" if it is empty
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+// WriteCSV writes the tenure history as CSV with a header row
+func (h *TenureHistory) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(tenureCSVHeader); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	for _, tenure := range h.Tenures {
+		record := []string{
+			tenure.PersonID,
+			tenure.PersonName,
+			tenure.MinisterID,
+			tenure.MinisterName,
+			tenure.Relationship,
+			tenure.StartDate,
+			tenure.EndDate,
+			tenure.StartTransaction,
+			tenure.EndTransaction,
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"orgchart_nexoan/models"
+)
+
+// TermManifestFile is the name of the manifest describing the term of a presidency directory,
+// e.g. data/orgchart/rw/term.json
+const TermManifestFile = "term.json"
+
+// Kinds and relationships of term entities
+const (
+	TermKind = "term"
+	// TermRelationship relates a root entity to its terms
+	TermRelationship = "AS_TERM"
+	// TermMinisterRelationship relates a term to the ministers created during it
+	TermMinisterRelationship = "AS_TERM_MINISTER"
+)
+
+// TermFileType is the file type of the journal entries recording the creation of terms
+const TermFileType = "TERM"
+
+// ErrTermRootNotFound is returned by CreateTermNode when the root entity of the term does not exist
+var ErrTermRootNotFound = errors.New("root entity of term not found")
+
+// Term is a presidency or cabinet term, such as the Rajapaksa government. The ministers created
+// while its gazettes are processed are related to it, so the ministries of a term can be queried
+// from the term entity.
+type Term struct {
+	// ID defaults to term_<directory name>, e.g. term_rw, so the organisation and people data of
+	// a presidency share one term
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Start and End are in YYYY-MM-DD format; End is empty for the current term
+	Start string `json:"start"`
+	End   string `json:"end,omitempty"`
+	// Root is the ID of the root entity the term belongs to (default: the root node of the
+	// processor creating the term, see Processor.SetRootNode)
+	Root string `json:"root,omitempty"`
+}
+
+// LoadTerm reads the term manifest of a presidency directory. A directory without a manifest
+// has no term, which is not an error.
+func LoadTerm(presidencyDir string) (*Term, error) {
+	path := filepath.Join(presidencyDir, TermManifestFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read term manifest %s: %w", path, err)
+	}
+
+	var term Term
+	if err := json.Unmarshal(data, &term); err != nil {
+		return nil, fmt.Errorf("failed to decode term manifest %s: %w", path, err)
+	}
+	if term.Name == "" || term.Start == "" {
+		return nil, fmt.Errorf("term manifest %s needs a name and a start date", path)
+	}
+	if term.ID == "" {
+		absDir, err := filepath.Abs(presidencyDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get absolute path of %s: %w", presidencyDir, err)
+		}
+		term.ID = "term_" + filepath.Base(absDir)
+	}
+
+	return &term, nil
+}
+
+// termDates returns the start and end of a term in RFC 3339 format
+func termDates(term *Term) (string, string, error) {
+	start, err := time.Parse("2006-01-02", strings.TrimSpace(term.Start))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse start date of term %s: %w", term.ID, err)
+	}
+	if term.End == "" {
+		return start.Format(time.RFC3339), "", nil
+	}
+	end, err := time.Parse("2006-01-02", strings.TrimSpace(term.End))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse end date of term %s: %w", term.ID, err)
+	}
+	return start.Format(time.RFC3339), end.Format(time.RFC3339), nil
+}
+
+// CreateTermNode creates the entity of a term and relates its root to it, and reports whether it
+// was created. Like CreateRootNode it returns an existing term with the same ID and name as is.
+// If the root does not exist the error matches ErrTermRootNotFound.
+func (p *Processor) CreateTermNode(term *Term) (*models.Entity, bool, error) {
+	startISO, endISO, err := termDates(term)
+	if err != nil {
+		return nil, false, err
+	}
+	kind := models.Kind{Major: "Organisation", Minor: TermKind}
+
+	existing, err := p.store.SearchEntities(&models.SearchCriteria{ID: term.ID})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to search for term entity %s: %w", term.ID, err)
+	}
+	if len(existing) > 0 {
+		if existing[0].Name != term.Name || existing[0].Kind != kind {
+			return nil, false, fmt.Errorf("term entity %s already exists as %s/%s %q",
+				term.ID, existing[0].Kind.Major, existing[0].Kind.Minor, existing[0].Name)
+		}
+		return &models.Entity{
+			ID:         existing[0].ID,
+			Kind:       existing[0].Kind,
+			Created:    existing[0].Created,
+			Terminated: existing[0].Terminated,
+			Name:       models.TimeBasedValue{StartTime: existing[0].Created, Value: existing[0].Name},
+		}, false, nil
+	}
+
+	rootID := term.Root
+	if rootID == "" {
+		rootID = p.root.ID
+	}
+	roots, err := p.store.SearchEntities(&models.SearchCriteria{ID: rootID})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to search for root entity %s: %w", rootID, err)
+	}
+	if len(roots) == 0 {
+		return nil, false, fmt.Errorf("%w: %s of term %s", ErrTermRootNotFound, rootID, term.ID)
+	}
+
+	termEntity := &models.Entity{
+		ID:         term.ID,
+		Kind:       kind,
+		Created:    startISO,
+		Terminated: endISO,
+		Name: models.TimeBasedValue{
+			StartTime: startISO,
+			Value:     term.Name,
+		},
+	}
+	createdEntity, err := p.store.CreateEntity(termEntity)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create term entity %s: %w", term.ID, err)
+	}
+
+	_, err = p.store.UpdateEntity(rootID, &models.Entity{
+		ID: rootID,
+		Relationships: []models.RelationshipEntry{
+			{
+				Key: fmt.Sprintf("%s_%s", rootID, term.ID),
+				Value: models.Relationship{
+					RelatedEntityID: term.ID,
+					StartTime:       startISO,
+					EndTime:         endISO,
+					ID:              fmt.Sprintf("%s_%s", rootID, term.ID),
+					Name:            TermRelationship,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to relate root %s to term %s: %w", rootID, term.ID, err)
+	}
+
+	return createdEntity, true, nil
+}
+
+// linkTermMinisters relates a term to the ministers created or reused by the given changes, from
+// the date each was created, or the start of the term if it is later, until the end of the term.
+// Reused ministers already related to the term are left alone.
+func (p *Processor) linkTermMinisters(term *Term, changes []Change) error {
+	startISO, endISO, err := termDates(term)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		if change.Operation != ChangeCreateEntity && change.Operation != ChangeReuseEntity {
+			continue
+		}
+		results, err := p.store.SearchEntities(&models.SearchCriteria{ID: change.EntityID})
+		if err != nil {
+			return fmt.Errorf("failed to search for entity %s: %w", change.EntityID, err)
+		}
+		if len(results) == 0 || results[0].Kind.Minor != "minister" {
+			continue
+		}
+		if change.Operation == ChangeReuseEntity {
+			linked, err := p.isTermMinister(term, change.EntityID)
+			if err != nil {
+				return err
+			}
+			if linked {
+				continue
+			}
+		}
+		since := results[0].Created
+		if since < startISO {
+			since = startISO
+		}
+
+		_, err = p.store.UpdateEntity(term.ID, &models.Entity{
+			ID: term.ID,
+			Relationships: []models.RelationshipEntry{
+				{
+					Key: fmt.Sprintf("%s_%s", term.ID, change.EntityID),
+					Value: models.Relationship{
+						RelatedEntityID: change.EntityID,
+						StartTime:       since,
+						EndTime:         endISO,
+						ID:              fmt.Sprintf("%s_%s", term.ID, change.EntityID),
+						Name:            TermMinisterRelationship,
+					},
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to relate term %s to minister %s: %w", term.ID, change.EntityID, err)
+		}
+	}
+
+	return nil
+}
+
+// isTermMinister reports whether the term is already related to the minister
+func (p *Processor) isTermMinister(term *Term, ministerID string) (bool, error) {
+	relations, err := p.store.GetRelatedEntities(term.ID, &models.Relationship{Name: TermMinisterRelationship})
+	if err != nil {
+		return false, fmt.Errorf("failed to get ministers of term %s: %w", term.ID, err)
+	}
+	for _, rel := range relations {
+		if rel.RelatedEntityID == ministerID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,362 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Transaction is a single row of a gazette CSV file
+type Transaction interface {
+	// ID returns the transaction_id of the row, e.g. "2153-12_tr_01"
+	ID() string
+	// FileType returns the type of file the row belongs to: ADD, TERMINATE, MOVE, RENAME or MERGE
+	FileType() string
+}
+
+// AddTransaction is a row of an ADD file. It creates child and relates it to parent.
+type AddTransaction struct {
+	TransactionID string
+	Parent        string
+	ParentType    string
+	Child         string
+	ChildType     string
+	RelType       string
+	Date          string
+}
+
+// TerminateTransaction is a row of a TERMINATE file. It ends the relationship between parent and child.
+type TerminateTransaction struct {
+	TransactionID string
+	Parent        string
+	ParentType    string
+	Child         string
+	ChildType     string
+	RelType       string
+	Date          string
+}
+
+// MoveTransaction is a row of a MOVE file. It moves child from old parent to new parent.
+type MoveTransaction struct {
+	TransactionID string
+	OldParent     string
+	NewParent     string
+	Child         string
+	Type          string
+	Date          string
+}
+
+// RenameTransaction is a row of a RENAME file. It replaces the entity named Old with one named New.
+type RenameTransaction struct {
+	TransactionID string
+	Old           string
+	New           string
+	Type          string
+	Date          string
+}
+
+// MergeTransaction is a row of a MERGE file. It merges the entities listed in Old into one named New.
+type MergeTransaction struct {
+	TransactionID string
+	Old           string
+	New           string
+	Type          string
+	Date          string
+}
+
+func (t AddTransaction) ID() string       { return t.TransactionID }
+func (t TerminateTransaction) ID() string { return t.TransactionID }
+func (t MoveTransaction) ID() string      { return t.TransactionID }
+func (t RenameTransaction) ID() string    { return t.TransactionID }
+func (t MergeTransaction) ID() string     { return t.TransactionID }
+
+func (t AddTransaction) FileType() string       { return "ADD" }
+func (t TerminateTransaction) FileType() string { return "TERMINATE" }
+func (t MoveTransaction) FileType() string      { return "MOVE" }
+func (t RenameTransaction) FileType() string    { return "RENAME" }
+func (t MergeTransaction) FileType() string     { return "MERGE" }
+
+// OldNames returns the names listed in the old column. The column holds a list such as
+// ["Minister of Agriculture", "Minister of Plantation Industries"]; the quotes are optional.
+func (t MergeTransaction) OldNames() []string {
+	var names []string
+	if err := json.Unmarshal([]byte(t.Old), &names); err == nil {
+		return names
+	}
+
+	names = strings.Split(strings.Trim(t.Old, "[]"), ",")
+	for i := range names {
+		names[i] = strings.Trim(strings.TrimSpace(names[i]), `"`)
+	}
+	return names
+}
+
+// transactionColumns lists the columns each file type must provide
+var transactionColumns = map[string][]string{
+	"ADD":       {"transaction_id", "parent", "parent_type", "child", "child_type", "rel_type", "date"},
+	"TERMINATE": {"transaction_id", "parent", "parent_type", "child", "child_type", "rel_type", "date"},
+	"MOVE":      {"transaction_id", "old_parent", "new_parent", "child", "type", "date"},
+	"RENAME":    {"transaction_id", "old", "new", "type", "date"},
+	"MERGE":     {"transaction_id", "old", "new", "type", "date"},
+}
+
+// TransactionFileError reports a malformed header or row in a gazette CSV file
+type TransactionFileError struct {
+	File   string
+	Line   int
+	Column string
+	Reason string
+}
+
+func (e *TransactionFileError) Error() string {
+	return fmt.Sprintf("%s:%d: %s %q", e.File, e.Line, e.Reason, e.Column)
+}
+
+// transactionFileType infers the file type from a file name (e.g. "ADD" from "2403-38_ADD.csv" or "ADD.csv").
+// Files that name no other type are treated as ADD files.
+func transactionFileType(fileName string) string {
+	fileName = strings.TrimSuffix(fileName, ".csv")
+	fileType := "ADD" // Default to ADD
+	if strings.Contains(fileName, "TERMINATE") {
+		fileType = "TERMINATE"
+	} else if strings.Contains(fileName, "MOVE") {
+		fileType = "MOVE"
+	} else if strings.Contains(fileName, "MERGE") {
+		fileType = "MERGE"
+	} else if strings.Contains(fileName, "RENAME") {
+		fileType = "RENAME"
+	}
+	return fileType
+}
+
+// lessTransactionID orders transaction IDs of the form <gazette>_tr_<n> by gazette and then
+// numerically by n, so that "2153-12_tr_2" comes before "2153-12_tr_10"
+func lessTransactionID(idI, idJ string) bool {
+	// Split the IDs into parts
+	partsI := strings.Split(idI, "_")
+	partsJ := strings.Split(idJ, "_")
+	if len(partsI) != 3 || len(partsJ) != 3 {
+		return idI < idJ
+	}
+
+	// Compare the first part (e.g., "2153/12")
+	if partsI[0] != partsJ[0] {
+		return partsI[0] < partsJ[0]
+	}
+
+	// Compare the second part (e.g., "tr")
+	if partsI[1] != partsJ[1] {
+		return partsI[1] < partsJ[1]
+	}
+
+	// Convert the numeric part to integers for numeric comparison
+	valI, _ := strconv.Atoi(partsI[2])
+	valJ, _ := strconv.Atoi(partsJ[2])
+	return valI < valJ
+}
+
+// transactionRow is a data row of a gazette CSV file together with its line number
+type transactionRow struct {
+	Line   int
+	Fields []string
+}
+
+// readTransactionRows reads the header and the data rows of a gazette CSV file.
+// Rows may have a different number of fields than the header, and rows repeating the header are
+// skipped.
+func readTransactionRows(filePath string) ([]string, []transactionRow, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+
+	// Read header
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read header from %s: %w", filePath, err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	var rows []transactionRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read records from %s: %w", filePath, err)
+		}
+		// Some files repeat the header, so a row naming the first column is not a transaction
+		if len(record) > 0 && len(header) > 0 && strings.TrimSpace(record[0]) == header[0] {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, transactionRow{Line: line, Fields: record})
+	}
+
+	return header, rows, nil
+}
+
+// optionalColumns are the columns of a file type whose value may be empty
+var optionalColumns = map[string]bool{
+	"type": true,
+}
+
+// rowValues returns the value of each column of the file type in the given row, or the first
+// column that has no value. Values are returned as they are written in the file, so that names
+// match the entities loaded from it before.
+func rowValues(columns []string, index map[string]int, row transactionRow) (map[string]string, string) {
+	values := make(map[string]string, len(columns))
+	for _, column := range columns {
+		i := index[column]
+		if i >= len(row.Fields) || (strings.TrimSpace(row.Fields[i]) == "" && !optionalColumns[column]) {
+			return nil, column
+		}
+		values[column] = row.Fields[i]
+	}
+	return values, ""
+}
+
+// loadTransactions reads the transactions of a CSV file of the given file type. The header must
+// contain every column of the file type and each row must give a value for each of them, except
+// for the optional type column.
+func loadTransactions(filePath string, fileType string) ([]Transaction, error) {
+	columns, ok := transactionColumns[fileType]
+	if !ok {
+		return nil, fmt.Errorf("unknown file type %s for %s", fileType, filePath)
+	}
+
+	header, rows, err := readTransactionRows(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Locate each expected column in the header
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[name] = i
+	}
+	for _, column := range columns {
+		if _, exists := index[column]; !exists {
+			return nil, &TransactionFileError{File: filePath, Line: 1, Column: column, Reason: "missing column"}
+		}
+	}
+
+	var transactions []Transaction
+	for _, row := range rows {
+		values, missing := rowValues(columns, index, row)
+		if missing != "" {
+			return nil, &TransactionFileError{File: filePath, Line: row.Line, Column: missing, Reason: "missing value for column"}
+		}
+		transactions = append(transactions, newTransaction(fileType, values))
+	}
+
+	return transactions, nil
+}
+
+// transactionValues returns the column values of a transaction in the order of transactionColumns,
+// the inverse of newTransaction
+func transactionValues(transaction Transaction) []string {
+	switch t := transaction.(type) {
+	case AddTransaction:
+		return []string{t.TransactionID, t.Parent, t.ParentType, t.Child, t.ChildType, t.RelType, t.Date}
+	case TerminateTransaction:
+		return []string{t.TransactionID, t.Parent, t.ParentType, t.Child, t.ChildType, t.RelType, t.Date}
+	case MoveTransaction:
+		return []string{t.TransactionID, t.OldParent, t.NewParent, t.Child, t.Type, t.Date}
+	case RenameTransaction:
+		return []string{t.TransactionID, t.Old, t.New, t.Type, t.Date}
+	case MergeTransaction:
+		return []string{t.TransactionID, t.Old, t.New, t.Type, t.Date}
+	}
+	return nil
+}
+
+// newTransaction builds the typed transaction of the given file type from the column values of a row
+func newTransaction(fileType string, values map[string]string) Transaction {
+	switch fileType {
+	case "TERMINATE":
+		return TerminateTransaction{
+			TransactionID: values["transaction_id"],
+			Parent:        values["parent"],
+			ParentType:    values["parent_type"],
+			Child:         values["child"],
+			ChildType:     values["child_type"],
+			RelType:       values["rel_type"],
+			Date:          values["date"],
+		}
+	case "MOVE":
+		return MoveTransaction{
+			TransactionID: values["transaction_id"],
+			OldParent:     values["old_parent"],
+			NewParent:     values["new_parent"],
+			Child:         values["child"],
+			Type:          values["type"],
+			Date:          values["date"],
+		}
+	case "RENAME":
+		return RenameTransaction{
+			TransactionID: values["transaction_id"],
+			Old:           values["old"],
+			New:           values["new"],
+			Type:          values["type"],
+			Date:          values["date"],
+		}
+	case "MERGE":
+		return MergeTransaction{
+			TransactionID: values["transaction_id"],
+			Old:           values["old"],
+			New:           values["new"],
+			Type:          values["type"],
+			Date:          values["date"],
+		}
+	default:
+		return AddTransaction{
+			TransactionID: values["transaction_id"],
+			Parent:        values["parent"],
+			ParentType:    values["parent_type"],
+			Child:         values["child"],
+			ChildType:     values["child_type"],
+			RelType:       values["rel_type"],
+			Date:          values["date"],
+		}
+	}
+}
+
+// mapTransactionKinds returns the transaction with its types replaced by the kinds they are
+// mapped to: the parent and child types of ADD and TERMINATE rows and the type of RENAME and
+// MERGE rows. MOVE rows have no types that are mapped.
+func mapTransactionKinds(transaction Transaction, kinds map[string]string) Transaction {
+	mapKind := func(kind string) string {
+		if mapped, ok := kinds[kind]; ok {
+			return mapped
+		}
+		return kind
+	}
+
+	switch t := transaction.(type) {
+	case AddTransaction:
+		t.ParentType, t.ChildType = mapKind(t.ParentType), mapKind(t.ChildType)
+		return t
+	case TerminateTransaction:
+		t.ParentType, t.ChildType = mapKind(t.ParentType), mapKind(t.ChildType)
+		return t
+	case RenameTransaction:
+		t.Type = mapKind(t.Type)
+		return t
+	case MergeTransaction:
+		t.Type = mapKind(t.Type)
+		return t
+	}
+	return transaction
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"orgchart_nexoan/models"
+)
+
+// String describes the change in a human readable form
+func (c Change) String() string {
+	switch c.Operation {
+	case ChangeCreateEntity:
+		return fmt.Sprintf("created entity %s", c.EntityID)
+	case ChangeAddRelationship:
+		return fmt.Sprintf("added %s relationship %s from %s to %s at %s", c.Name, c.RelationshipID, c.EntityID, c.RelatedEntityID, c.StartTime)
+	case ChangeReuseEntity:
+		return fmt.Sprintf("reused entity %s", c.EntityID)
+	case ChangeEndRelationship:
+		return fmt.Sprintf("ended relationship %s of %s at %s", c.RelationshipID, c.EntityID, c.EndTime)
+	default:
+		return fmt.Sprintf("unknown change %s of %s", c.Operation, c.EntityID)
+	}
+}
+
+// UndoTransactions reverses the journaled transactions for which selected returns true. The
+// transactions are undone from the most recently applied one backwards, and the changes of each
+// transaction in reverse order: created entities are deleted, ended relationships are reopened
+// and added relationships are closed at their start time so they are never active. The closed
+// relationships are journaled so the graph leaves them out. Each undone transaction is removed
+// from the journal, so it can be applied again. The undone entries are returned; on error the
+// entries undone before the failure are still removed.
+func (p *Processor) UndoTransactions(journal *Journal, selected func(JournalEntry) bool) ([]JournalEntry, error) {
+	entries := journal.Entries()
+
+	// Check every selected transaction has a change log before changing anything
+	var pending []int
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Undo || !selected(entries[i]) {
+			continue
+		}
+		if entries[i].Changes == nil {
+			return nil, fmt.Errorf("transaction %s was journaled without a change log and must be undone by hand", entries[i].TransactionID)
+		}
+		pending = append(pending, i)
+	}
+
+	var undone []JournalEntry
+	var undoneIndices []int
+	var closed []Change
+	var undoErr error
+	for _, index := range pending {
+		entry := entries[index]
+		fmt.Printf("Undoing transaction: %s (Type: %s)\n", entry.TransactionID, entry.FileType)
+		for i := len(entry.Changes) - 1; i >= 0; i-- {
+			if err := p.undoChange(entry.Changes[i]); err != nil {
+				undoErr = fmt.Errorf("failed to undo transaction %s: %w", entry.TransactionID, err)
+				break
+			}
+			if entry.Changes[i].Operation == ChangeAddRelationship {
+				closed = append(closed, entry.Changes[i])
+			}
+			fmt.Printf("  Undid: %s\n", entry.Changes[i])
+		}
+		if undoErr != nil {
+			break
+		}
+		undone = append(undone, entry)
+		undoneIndices = append(undoneIndices, index)
+	}
+
+	if len(closed) > 0 {
+		entry := JournalEntry{AppliedAt: time.Now().UTC().Format(time.RFC3339), Changes: closed, Undo: true}
+		if err := journal.Record(entry); err != nil {
+			return undone, fmt.Errorf("relationships were removed but could not be journaled: %w", err)
+		}
+	}
+	if len(undone) > 0 {
+		if err := journal.Remove(undoneIndices); err != nil {
+			return undone, fmt.Errorf("transactions were undone but could not be removed from the journal: %w", err)
+		}
+	}
+	return undone, undoErr
+}
+
+// undoChange reverses a single change
+func (p *Processor) undoChange(change Change) error {
+	switch change.Operation {
+	case ChangeCreateEntity:
+		if err := p.store.DeleteEntity(change.EntityID); err != nil {
+			return fmt.Errorf("failed to delete entity %s: %w", change.EntityID, err)
+		}
+
+	case ChangeAddRelationship:
+		// Relationships cannot be deleted, so the relationship is ended at the moment it started
+		_, err := p.store.UpdateEntity(change.EntityID, &models.Entity{
+			ID: change.EntityID,
+			Relationships: []models.RelationshipEntry{
+				{
+					Key: change.RelationshipID,
+					Value: models.Relationship{
+						EndTime: change.StartTime,
+						ID:      change.RelationshipID,
+					},
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove relationship %s: %w", change.RelationshipID, err)
+		}
+
+	case ChangeEndRelationship:
+		_, err := p.store.UpdateEntity(change.EntityID, &models.Entity{
+			ID: change.EntityID,
+			Relationships: []models.RelationshipEntry{
+				{
+					Key: change.RelationshipID,
+					Value: models.Relationship{
+						EndTime: "",
+						ID:      change.RelationshipID,
+					},
+				},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to reopen relationship %s: %w", change.RelationshipID, err)
+		}
+
+	case ChangeReuseEntity:
+		// Nothing was written
+
+	default:
+		return fmt.Errorf("unknown change operation: %s", change.Operation)
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// transactionIDPattern matches transaction IDs of the form <gazette>_tr_<n>, e.g. "2153-12_tr_01"
+var transactionIDPattern = regexp.MustCompile(`^[^_\s]+_tr_[0-9]+$`)
+
+// ValidationIssue is a problem found in a gazette CSV file by ValidateTransactions
+type ValidationIssue struct {
+	File          string
+	Line          int
+	TransactionID string
+	Message       string
+}
+
+func (i ValidationIssue) String() string {
+	if i.TransactionID == "" {
+		return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", i.File, i.Line, i.TransactionID, i.Message)
+}
+
+// validatedTransaction is a transaction together with the place it was read from
+type validatedTransaction struct {
+	transaction Transaction
+	file        string
+	line        int
+}
+
+// ValidateTransactions checks the gazette CSV files in dataDir without contacting Nexoan.
+// It checks that each file has the columns of the file type inferred from its name, that dates
+// use the 2006-01-02 layout, that transaction IDs have the <gazette>_tr_<n> shape and that every
+// referenced entity is either created earlier in the folder or listed in known.
+// known maps entity names to true; the names created by the folder are added to it, so the same
+// map can be passed on when validating the next folder. An error is only returned if the
+// folder cannot be read.
+func ValidateTransactions(dataDir string, known map[string]bool) ([]ValidationIssue, error) {
+	files, err := os.ReadDir(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dataDir, err)
+	}
+
+	var issues []ValidationIssue
+	var transactions []validatedTransaction
+	seen := map[string]string{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".csv") {
+			continue
+		}
+		filePath := filepath.Join(dataDir, file.Name())
+		fileIssues, fileTransactions := validateTransactionFile(filePath, transactionFileType(file.Name()))
+		issues = append(issues, fileIssues...)
+
+		for _, vt := range fileTransactions {
+			if previous, exists := seen[vt.transaction.ID()]; exists {
+				issues = append(issues, ValidationIssue{
+					File:          vt.file,
+					Line:          vt.line,
+					TransactionID: vt.transaction.ID(),
+					Message:       fmt.Sprintf("duplicate transaction_id, also used at %s", previous),
+				})
+				continue
+			}
+			seen[vt.transaction.ID()] = fmt.Sprintf("%s:%d", vt.file, vt.line)
+			transactions = append(transactions, vt)
+		}
+	}
+
+	// Check references in the order ProcessTransactions applies the transactions
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return lessTransactionID(transactions[i].transaction.ID(), transactions[j].transaction.ID())
+	})
+	for _, vt := range transactions {
+		issues = append(issues, validateReferences(vt, known)...)
+	}
+
+	return issues, nil
+}
+
+// ValidateTransactionTree validates every dated gazette folder under rootDir in date order,
+// so that entities created by an earlier folder are known to the later ones
+func ValidateTransactionTree(rootDir string, known map[string]bool) ([]ValidationIssue, error) {
+	folders, err := listGazetteFolders(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(folders) == 0 {
+		return nil, fmt.Errorf("no dated gazette folders found in %s", rootDir)
+	}
+
+	var issues []ValidationIssue
+	for _, folder := range folders {
+		folderIssues, err := ValidateTransactions(filepath.Join(rootDir, folder), known)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, folderIssues...)
+	}
+
+	return issues, nil
+}
+
+// validateTransactionFile checks the header and rows of a single file and returns the issues
+// found together with the transactions of the rows that have a value for every column
+func validateTransactionFile(filePath string, fileType string) ([]ValidationIssue, []validatedTransaction) {
+	header, rows, err := readTransactionRows(filePath)
+	if err != nil {
+		return []ValidationIssue{{File: filePath, Line: 1, Message: err.Error()}}, nil
+	}
+
+	var issues []ValidationIssue
+	columns := transactionColumns[fileType]
+
+	// Check the header against the schema of the file type. Like the loader, other columns and
+	// rows with more or fewer fields than the header are accepted as long as every column of the
+	// file type has a value.
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[name] = i
+	}
+	for _, column := range columns {
+		if _, exists := index[column]; !exists {
+			issues = append(issues, ValidationIssue{
+				File:    filePath,
+				Line:    1,
+				Message: fmt.Sprintf("missing column %q for a %s file (expected %s)", column, fileType, strings.Join(columns, ",")),
+			})
+		}
+	}
+	if len(issues) > 0 {
+		return issues, nil
+	}
+
+	// Check each row
+	var transactions []validatedTransaction
+	for _, row := range rows {
+		values, missing := rowValues(columns, index, row)
+		if missing != "" {
+			issues = append(issues, ValidationIssue{
+				File:    filePath,
+				Line:    row.Line,
+				Message: fmt.Sprintf("missing value for column %q", missing),
+			})
+			continue
+		}
+
+		// Like the transaction operations, the checks ignore the spaces around the ID and the date
+		transactionID := strings.TrimSpace(values["transaction_id"])
+		rowValid := true
+		if !transactionIDPattern.MatchString(transactionID) {
+			issues = append(issues, ValidationIssue{
+				File:          filePath,
+				Line:          row.Line,
+				TransactionID: transactionID,
+				Message:       "transaction_id does not have the <gazette>_tr_<n> shape",
+			})
+			rowValid = false
+		}
+		if _, err := time.Parse("2006-01-02", strings.TrimSpace(values["date"])); err != nil {
+			issues = append(issues, ValidationIssue{
+				File:          filePath,
+				Line:          row.Line,
+				TransactionID: transactionID,
+				Message:       fmt.Sprintf("date %q does not use the 2006-01-02 layout", values["date"]),
+			})
+			rowValid = false
+		}
+
+		if rowValid {
+			transactions = append(transactions, validatedTransaction{
+				transaction: newTransaction(fileType, values),
+				file:        filePath,
+				line:        row.Line,
+			})
+		}
+	}
+
+	return issues, transactions
+}
+
+// validateReferences checks that the entities a transaction refers to are known and then
+// records the entities it creates as known
+func validateReferences(vt validatedTransaction, known map[string]bool) []ValidationIssue {
+	var issues []ValidationIssue
+	check := func(role string, name string) {
+		if !known[name] {
+			message := fmt.Sprintf("%s %q is neither created earlier nor a known entity", role, name)
+			// Names are matched as written, so point out the ones that only differ in spaces
+			for knownName := range known {
+				if strings.TrimSpace(knownName) == strings.TrimSpace(name) {
+					message += fmt.Sprintf(" (%q differs only in the spaces around it)", knownName)
+					break
+				}
+			}
+			issues = append(issues, ValidationIssue{
+				File:          vt.file,
+				Line:          vt.line,
+				TransactionID: vt.transaction.ID(),
+				Message:       message,
+			})
+		}
+	}
+
+	switch transaction := vt.transaction.(type) {
+	case AddTransaction:
+		check("parent", transaction.Parent)
+		known[transaction.Child] = true
+	case TerminateTransaction:
+		check("parent", transaction.Parent)
+		check("child", transaction.Child)
+	case MoveTransaction:
+		check("old parent", transaction.OldParent)
+		check("new parent", transaction.NewParent)
+		check("child", transaction.Child)
+	case RenameTransaction:
+		check("old entity", transaction.Old)
+		known[transaction.New] = true
+	case MergeTransaction:
+		for _, name := range transaction.OldNames() {
+			check("old entity", name)
+		}
+		known[transaction.New] = true
+	}
+
+	return issues
+}
@@ -0,0 +1,361 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DataTree is a directory of transactions of one process type ("organisation" or "person"). Dir
+// may be a gazette folder, a presidency directory such as data/orgchart/rw, or a category
+// directory such as data/orgchart holding presidency directories.
+type DataTree struct {
+	Dir         string
+	ProcessType string
+	// KindMapping maps the parent_type and child_type values of the tree; see ProcessOptions
+	KindMapping map[string]string
+}
+
+// replayFolder is a gazette folder of a data tree
+type replayFolder struct {
+	path        string
+	date        string
+	processType string
+	kinds       map[string]string
+}
+
+// listDataTreeFolders returns the gazette folders of a data tree: the directory itself if it
+// holds CSV files, and otherwise every directory under it that is named after a date (YYYY-MM-DD)
+func listDataTreeFolders(tree DataTree) ([]replayFolder, error) {
+	entries, err := os.ReadDir(tree.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", tree.Dir, err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".csv") {
+			return []replayFolder{{path: tree.Dir, date: filepath.Base(tree.Dir), processType: tree.ProcessType, kinds: tree.KindMapping}}, nil
+		}
+	}
+
+	var folders []replayFolder
+	err = filepath.WalkDir(tree.Dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if _, err := time.Parse("2006-01-02", entry.Name()); err == nil {
+			folders = append(folders, replayFolder{path: path, date: entry.Name(), processType: tree.ProcessType, kinds: tree.KindMapping})
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list gazette folders of %s: %w", tree.Dir, err)
+	}
+	return folders, nil
+}
+
+// ReplayDataTrees replays data trees into a new MemoryStore holding the given root nodes, or the
+// default government node if roots is empty. Terms belong to the first root unless their manifest
+// names one. The gazette folders of all trees are processed in
+// date order, and on the same date organisation folders are processed before person folders so
+// that people can be appointed to new ministers.
+func ReplayDataTrees(trees []DataTree, roots []RootNode) (*MemoryStore, error) {
+	var folders []replayFolder
+	for _, tree := range trees {
+		if tree.ProcessType != "organisation" && tree.ProcessType != "person" {
+			return nil, fmt.Errorf("invalid process type: %s", tree.ProcessType)
+		}
+		treeFolders, err := listDataTreeFolders(tree)
+		if err != nil {
+			return nil, err
+		}
+		if len(treeFolders) == 0 {
+			return nil, fmt.Errorf("no gazette folders found in %s", tree.Dir)
+		}
+		folders = append(folders, treeFolders...)
+	}
+	sort.SliceStable(folders, func(i, j int) bool {
+		if folders[i].date != folders[j].date {
+			return folders[i].date < folders[j].date
+		}
+		return folders[i].processType == "organisation" && folders[j].processType == "person"
+	})
+
+	store := NewMemoryStore()
+	processor := NewProcessor(store)
+	if len(roots) == 0 {
+		roots = []RootNode{DefaultRootNode()}
+	}
+	processor.SetRootNode(roots[0])
+	for _, root := range roots {
+		if _, _, err := processor.CreateRootNode(root); err != nil {
+			return nil, err
+		}
+	}
+	opts := &ProcessOptions{EntityCounters: map[string]int{}}
+	for _, folder := range folders {
+		opts.KindMapping = folder.kinds
+		if err := processor.ProcessTransactions(folder.path, folder.processType, opts); err != nil {
+			return nil, fmt.Errorf("failed to replay gazette folder %s: %w", folder.path, err)
+		}
+	}
+	return store, nil
+}
+
+// ReconciledEntity identifies an entity in a Reconciliation
+type ReconciledEntity struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// ReconciledRelationship identifies a relationship in a Reconciliation by the names of the
+// entities it relates, its name and the date it started
+type ReconciledRelationship struct {
+	Parent    string `json:"parent"`
+	Child     string `json:"child"`
+	Name      string `json:"name"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date,omitempty"`
+}
+
+// EndDateMismatch is a relationship that ended on another date than the data says, or that
+// ended in only one of the two graphs
+type EndDateMismatch struct {
+	Relationship ReconciledRelationship `json:"relationship"`
+	ExpectedEnd  string                 `json:"expected_end,omitempty"`
+	ActualEnd    string                 `json:"actual_end,omitempty"`
+}
+
+// DuplicateName is a name that more entities of the same kind have in Nexoan than in the data
+type DuplicateName struct {
+	Kind     string             `json:"kind"`
+	Name     string             `json:"name"`
+	Entities []ReconciledEntity `json:"entities"`
+}
+
+// Reconciliation lists the differences between the graph the data trees produce and the graph
+// Nexoan holds. Entities are matched by kind and name rather than ID, since generated IDs depend
+// on the counters of earlier runs, and relationships by the names of their entities, their name
+// and their start date.
+type Reconciliation struct {
+	MissingEntities      []ReconciledEntity       `json:"missing_entities"`
+	ExtraEntities        []ReconciledEntity       `json:"extra_entities"`
+	DuplicateNames       []DuplicateName          `json:"duplicate_names"`
+	MissingRelationships []ReconciledRelationship `json:"missing_relationships"`
+	ExtraRelationships   []ReconciledRelationship `json:"extra_relationships"`
+	EndDateMismatches    []EndDateMismatch        `json:"end_date_mismatches"`
+}
+
+// Empty reports whether Nexoan holds exactly what the data trees produce
+func (r *Reconciliation) Empty() bool {
+	return len(r.MissingEntities) == 0 && len(r.ExtraEntities) == 0 && len(r.DuplicateNames) == 0 &&
+		len(r.MissingRelationships) == 0 && len(r.ExtraRelationships) == 0 && len(r.EndDateMismatches) == 0
+}
+
+// reconcileSide indexes the entities and relationships of one of the graphs being reconciled
+type reconcileSide struct {
+	// entities holds the entities of each kind and name key, in graph order
+	entities map[string][]ReconciledEntity
+	// relationships holds the relationships by key, with the end dates of the ones sharing a key
+	relationships map[string]ReconciledRelationship
+	ends          map[string][]string
+}
+
+// entityKey identifies an entity by its kind and name
+func entityKey(kind string, name string) string {
+	return kind + "\x00" + name
+}
+
+// newReconcileSide indexes the entities of the graph whose major kind is in kinds and the
+// relationships between them
+func newReconcileSide(graph *Graph, kinds map[string]bool) *reconcileSide {
+	side := &reconcileSide{
+		entities:      map[string][]ReconciledEntity{},
+		relationships: map[string]ReconciledRelationship{},
+		ends:          map[string][]string{},
+	}
+	nodes := map[string]GraphNode{}
+	for _, node := range graph.Nodes {
+		if !kinds[node.Kind.Major] {
+			continue
+		}
+		nodes[node.ID] = node
+		kind := node.Kind.Major + "/" + node.Kind.Minor
+		key := entityKey(kind, node.Name)
+		side.entities[key] = append(side.entities[key], ReconciledEntity{ID: node.ID, Kind: kind, Name: node.Name})
+	}
+	for _, edge := range graph.Edges {
+		parent, parentOK := nodes[edge.From]
+		child, childOK := nodes[edge.To]
+		if !parentOK || !childOK {
+			continue
+		}
+		rel := ReconciledRelationship{
+			Parent:    parent.Name,
+			Child:     child.Name,
+			Name:      edge.Name,
+			StartDate: dateOf(edge.StartTime),
+		}
+		key := strings.Join([]string{rel.Parent, rel.Child, rel.Name, rel.StartDate}, "\x00")
+		side.relationships[key] = rel
+		side.ends[key] = append(side.ends[key], dateOf(edge.EndTime))
+	}
+	for key := range side.ends {
+		sort.Strings(side.ends[key])
+	}
+	return side
+}
+
+// Reconcile compares the graph the data trees produce (see ReplayDataTrees) with the graph Nexoan
+// holds, both loaded without a date. Only the entities whose major kind is in kinds
+// ("Organisation", "Person") are compared, so that a check of the organisation data does not
+// report every person as extra.
+func Reconcile(expected *Graph, actual *Graph, kinds []string) *Reconciliation {
+	kindSet := map[string]bool{}
+	for _, kind := range kinds {
+		kindSet[kind] = true
+	}
+	want := newReconcileSide(expected, kindSet)
+	got := newReconcileSide(actual, kindSet)
+	result := &Reconciliation{
+		MissingEntities:      []ReconciledEntity{},
+		ExtraEntities:        []ReconciledEntity{},
+		DuplicateNames:       []DuplicateName{},
+		MissingRelationships: []ReconciledRelationship{},
+		ExtraRelationships:   []ReconciledRelationship{},
+		EndDateMismatches:    []EndDateMismatch{},
+	}
+
+	for _, key := range sortedKeys(want.entities) {
+		if len(got.entities[key]) == 0 {
+			result.MissingEntities = append(result.MissingEntities, want.entities[key]...)
+		}
+	}
+	for _, key := range sortedKeys(got.entities) {
+		entities := got.entities[key]
+		switch {
+		case len(want.entities[key]) == 0:
+			result.ExtraEntities = append(result.ExtraEntities, entities...)
+		case len(entities) > len(want.entities[key]):
+			result.DuplicateNames = append(result.DuplicateNames, DuplicateName{
+				Kind:     entities[0].Kind,
+				Name:     entities[0].Name,
+				Entities: entities,
+			})
+		}
+	}
+
+	for _, key := range sortedKeys(want.relationships) {
+		if _, exists := got.relationships[key]; !exists {
+			for _, end := range want.ends[key] {
+				rel := want.relationships[key]
+				rel.EndDate = end
+				result.MissingRelationships = append(result.MissingRelationships, rel)
+			}
+			continue
+		}
+		wantEnds, gotEnds := want.ends[key], got.ends[key]
+		for i := 0; i < len(wantEnds) && i < len(gotEnds); i++ {
+			if wantEnds[i] != gotEnds[i] {
+				result.EndDateMismatches = append(result.EndDateMismatches, EndDateMismatch{
+					Relationship: want.relationships[key],
+					ExpectedEnd:  wantEnds[i],
+					ActualEnd:    gotEnds[i],
+				})
+			}
+		}
+		for _, end := range wantEnds[min(len(wantEnds), len(gotEnds)):] {
+			rel := want.relationships[key]
+			rel.EndDate = end
+			result.MissingRelationships = append(result.MissingRelationships, rel)
+		}
+		for _, end := range gotEnds[min(len(wantEnds), len(gotEnds)):] {
+			rel := got.relationships[key]
+			rel.EndDate = end
+			result.ExtraRelationships = append(result.ExtraRelationships, rel)
+		}
+	}
+	for _, key := range sortedKeys(got.relationships) {
+		if _, exists := want.relationships[key]; exists {
+			continue
+		}
+		for _, end := range got.ends[key] {
+			rel := got.relationships[key]
+			rel.EndDate = end
+			result.ExtraRelationships = append(result.ExtraRelationships, rel)
+		}
+	}
+
+	return result
+}
+
+// Print writes the reconciliation in a human readable form
+func (r *Reconciliation) Print(w io.Writer) {
+	if r.Empty() {
+		fmt.Fprintln(w, "Nexoan matches the data")
+		return
+	}
+
+	section := func(title string, count int, line func(i int) string) {
+		if count == 0 {
+			return
+		}
+		fmt.Fprintf(w, "\n%s (%d):\n", title, count)
+		for i := 0; i < count; i++ {
+			fmt.Fprintf(w, "  %s\n", line(i))
+		}
+	}
+
+	section("Missing entities", len(r.MissingEntities), func(i int) string {
+		return "- " + r.MissingEntities[i].String()
+	})
+	section("Extra entities", len(r.ExtraEntities), func(i int) string {
+		return "+ " + r.ExtraEntities[i].String()
+	})
+	section("Duplicate names", len(r.DuplicateNames), func(i int) string {
+		ids := make([]string, len(r.DuplicateNames[i].Entities))
+		for j, entity := range r.DuplicateNames[i].Entities {
+			ids[j] = entity.ID
+		}
+		return fmt.Sprintf("%s %q: %s", r.DuplicateNames[i].Kind, r.DuplicateNames[i].Name, strings.Join(ids, ", "))
+	})
+	section("Missing relationships", len(r.MissingRelationships), func(i int) string {
+		return "- " + r.MissingRelationships[i].String()
+	})
+	section("Extra relationships", len(r.ExtraRelationships), func(i int) string {
+		return "+ " + r.ExtraRelationships[i].String()
+	})
+	section("Wrong end dates", len(r.EndDateMismatches), func(i int) string {
+		mismatch := r.EndDateMismatches[i]
+		return fmt.Sprintf("%s: expected %s, found %s",
+			mismatch.Relationship, valueOrPresent(mismatch.ExpectedEnd), valueOrPresent(mismatch.ActualEnd))
+	})
+}
+
+// valueOrPresent returns the end date, or "present" for a relationship that has not ended
+func valueOrPresent(end string) string {
+	if end == "" {
+		return "present"
+	}
+	return end
+}
+
+func (e ReconciledEntity) String() string {
+	return fmt.Sprintf("%s %q (%s)", e.Kind, e.Name, e.ID)
+}
+
+func (r ReconciledRelationship) String() string {
+	s := fmt.Sprintf("%s -[%s]-> %s from %s", r.Parent, r.Name, r.Child, r.StartDate)
+	if r.EndDate != "" {
+		s += " to " + r.EndDate
+	}
+	return s
+}
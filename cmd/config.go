@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"orgchart_nexoan/api"
+)
+
+// envConfig names the config file read when -config is not given
+const envConfig = "ORGCHART_CONFIG"
+
+// loadConfig reads the config file at path, or the one named by ORGCHART_CONFIG if path is empty,
+// applies the ORGCHART_* environment variables and finally the connection flags set on the
+// command line, so flags override the environment, which overrides the file
+func loadConfig(fs *flag.FlagSet, path string) (*api.Config, error) {
+	if path == "" {
+		path = os.Getenv(envConfig)
+	}
+	config, err := api.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "update_endpoint":
+			config.UpdateEndpoint = f.Value.(flag.Getter).Get().(string)
+		case "query_endpoint":
+			config.QueryEndpoint = f.Value.(flag.Getter).Get().(string)
+		case "timeout":
+			config.Timeout = f.Value.(flag.Getter).Get().(time.Duration)
+		case "retries":
+			config.Retry.MaxAttempts = f.Value.(flag.Getter).Get().(int)
+		}
+	})
+	return config, nil
+}
+
+// connectionFlags are the flags shared by the commands that talk to Nexoan
+type connectionFlags struct {
+	fs         *flag.FlagSet
+	configFile *string
+}
+
+// addConnectionFlags registers -config, -update_endpoint, -query_endpoint, -timeout and -retries
+// on a command's flag set
+func addConnectionFlags(fs *flag.FlagSet) *connectionFlags {
+	defaults := api.DefaultConfig()
+	fs.String("update_endpoint", defaults.UpdateEndpoint, "Endpoint for the Update API (overrides $"+api.EnvUpdateEndpoint+")")
+	fs.String("query_endpoint", defaults.QueryEndpoint, "Endpoint for the Query API (overrides $"+api.EnvQueryEndpoint+")")
+	fs.Duration("timeout", defaults.Timeout, "Time limit of each request to Nexoan (overrides $"+api.EnvTimeout+")")
+	fs.Int("retries", defaults.Retry.MaxAttempts, "Number of attempts for requests that are safe to repeat when Nexoan is unreachable or answers with a server error; 1 disables retries (overrides $"+api.EnvRetries+")")
+	return &connectionFlags{fs: fs, configFile: fs.String("config", "", configUsage)}
+}
+
+// config returns the settings of the command once its flags are parsed; see loadConfig
+func (c *connectionFlags) config() (*api.Config, error) {
+	return loadConfig(c.fs, *c.configFile)
+}
+
+// newConfiguredClient returns a client for the endpoints, timeout and retry policy of a config
+func newConfiguredClient(config *api.Config) *api.Client {
+	return api.NewClient(config.UpdateEndpoint, config.QueryEndpoint, config.ClientOptions()...)
+}
+
+// openOptionalJournal opens the journal at path, or journal.jsonl in the state directory of the
+// update endpoint if path is empty. A journal that does not exist gives nil rather than an empty one.
+func openOptionalJournal(config *api.Config, path string) (*api.Journal, error) {
+	if path == "" {
+		var err error
+		path, err = config.StateFile("journal.jsonl")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+	journal, err := api.OpenJournal(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	return journal, nil
+}
+
+// configUsage is the help text of the -config flag
+var configUsage = "YAML config file with endpoints, timeout, retry policy, root nodes, presidencies and kind mapping (default: $" + envConfig + "); flags and ORGCHART_* environment variables override it"
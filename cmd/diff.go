@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"orgchart_nexoan/api"
+)
+
+// runDiff implements the diff subcommand, which prints what changed in the org chart between two dates
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	from := fs.String("from", "", "Earlier date in YYYY-MM-DD format (required)")
+	to := fs.String("to", "", "Later date in YYYY-MM-DD format (required)")
+	format := fs.String("format", "text", "Output format: 'text' for a report or 'json'")
+	root := fs.String("root", "", "ID of the root entity to start from, e.g. a provincial council (default: the government root found by the Query API)")
+	connection := addConnectionFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s diff:\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Compare the org chart on two dates: ministers added, terminated, renamed or merged,\n")
+		fmt.Fprintf(os.Stderr, "departments added, terminated or moved between ministers, and people appointed or removed.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  1. Show what changed in a reshuffle:\n")
+		fmt.Fprintf(os.Stderr, "     %s diff -from 2022-07-21 -to 2022-07-23\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  2. Save the changes as JSON:\n")
+		fmt.Fprintf(os.Stderr, "     %s diff -from 2022-07-21 -to 2022-07-23 -format json > reshuffle.json\n\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	if *from == "" || *to == "" {
+		fmt.Fprintf(os.Stderr, "Error: Both -from and -to are required\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: Invalid format. Must be 'text' or 'json'\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	config, err := connection.config()
+	if err != nil {
+		return err
+	}
+	client := newConfiguredClient(config)
+	diff, err := api.BuildDiff(client, *root, *from, *to)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	}
+	diff.Print(os.Stdout)
+	return nil
+}
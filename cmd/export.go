@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/export"
+)
+
+// runExport implements the export subcommand, which writes the org chart graph for other tools
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "dot", fmt.Sprintf("Output format: %s, or csv for gazette folders", strings.Join(export.Formats(), ", ")))
+	date := fs.String("date", "", "Only export the relationships active on this date (YYYY-MM-DD); all relationships if empty")
+	output := fs.String("output", "", "File to write to instead of standard output; for csv, the directory to write the gazette folders to")
+	processType := fs.String("type", "organisation", "Data to rebuild with -format csv: 'organisation' or 'person'")
+	crlf := fs.Bool("crlf", false, "End the lines of the csv files with \\r\\n instead of \\n")
+	sourceDir := fs.String("source", "", "Data directory the gazettes were loaded from, e.g. data/orgchart; with -format csv a header-only file is written wherever it has one")
+	baseURI := fs.String("base_uri", export.DefaultBaseURI, "Base of the entity, relationship and class URIs in the jsonld and turtle formats")
+	journalFile := fs.String("journal", "", "Journal giving the transaction IDs of the csv rows and listing the relationships removed by undo, which are left out; ignored if it does not exist (default: journal.jsonl in the state directory of the update endpoint)")
+	connection := addConnectionFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s export:\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Read the org chart from the Query API, starting at the root entities, and write it as a graph.\n")
+		fmt.Fprintf(os.Stderr, "Nodes are labelled with the entity names and edges with the relationship names and dates.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  1. Render the cabinet on a date with Graphviz:\n")
+		fmt.Fprintf(os.Stderr, "     %s export -format dot -date 2023-01-19 | dot -Tsvg > cabinet.svg\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  2. Export the whole history for Gephi:\n")
+		fmt.Fprintf(os.Stderr, "     %s export -format graphml -output orgchart.graphml\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  3. Publish linked data under your own URIs:\n")
+		fmt.Fprintf(os.Stderr, "     %s export -format turtle -base_uri https://data.example.org/orgchart/ -output orgchart.ttl\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  4. Rebuild the gazette CSV folders of the organisation data:\n")
+		fmt.Fprintf(os.Stderr, "     %s export -format csv -type organisation -output rebuilt/orgchart\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  5. Rebuild the gazette folders with the same files as the data they were loaded from:\n")
+		fmt.Fprintf(os.Stderr, "     %s export -format csv -type organisation -source data/orgchart -crlf -output rebuilt/orgchart\n\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	if *format == "csv" {
+		if *output == "" || *date != "" {
+			fmt.Fprintf(os.Stderr, "Error: -format csv requires -output and exports every relationship, so -date cannot be used\n\n")
+			fs.Usage()
+			os.Exit(2)
+		}
+		if *processType != "organisation" && *processType != "person" {
+			fmt.Fprintf(os.Stderr, "Error: Invalid type. Must be 'organisation' or 'person'\n\n")
+			fs.Usage()
+			os.Exit(2)
+		}
+	}
+
+	formatSupported := *format == "csv"
+	for _, supported := range export.Formats() {
+		formatSupported = formatSupported || supported == *format
+	}
+	if !formatSupported {
+		fmt.Fprintf(os.Stderr, "Error: Invalid format. Must be one of %s or csv\n\n", strings.Join(export.Formats(), ", "))
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	config, err := connection.config()
+	if err != nil {
+		return err
+	}
+	journal, err := openOptionalJournal(config, *journalFile)
+	if err != nil {
+		return err
+	}
+	if journal != nil {
+		defer journal.Close()
+	}
+	client := newConfiguredClient(config)
+	graph, err := api.LoadGraph(client, *date, journal)
+	if err != nil {
+		return fmt.Errorf("failed to load graph: %w", err)
+	}
+
+	if *format == "csv" {
+		folders, err := api.ReconstructGazettes(graph, *processType)
+		if err != nil {
+			return err
+		}
+		if *sourceDir != "" {
+			if err := api.MatchGazetteFileSet(folders, *sourceDir, *processType); err != nil {
+				return err
+			}
+		}
+		if err := api.WriteGazetteFolders(*output, *processType, folders, *crlf); err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d gazette folders to %s\n", len(folders), *output)
+		return nil
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if err := export.Write(w, graph, *format, export.Options{BaseURI: *baseURI}); err != nil {
+		return err
+	}
+	if *output != "" {
+		fmt.Printf("Exported %d entities and %d relationships to %s\n", len(graph.Nodes), len(graph.Edges), *output)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"orgchart_nexoan/api"
+)
+
+// runIngest implements the ingest subcommand, which processes the transactions of a data
+// directory. It is also run when no subcommand is named, so the flags of the single-command
+// CLI keep working.
+func runIngest(args []string) error {
+	fs := flag.NewFlagSet("ingest", flag.ExitOnError)
+	dataDir := fs.String("data", "", "Path to the data directory containing transactions (required unless -presidency is given)")
+	presidency := fs.String("presidency", "", "Presidency of the config file whose data directory for -type is processed recursively, e.g. rw")
+	processType := fs.String("type", "organisation", "Type of data to process: 'organisation' or 'person' (default: organisation)")
+	recursive := fs.Bool("recursive", false, "Treat -data as a presidency directory (e.g. data/orgchart/rw) and process every dated gazette folder (YYYY-MM-DD) in date order")
+	initDB := fs.Bool("init", false, "Initialize the database with the root nodes before processing transactions; roots that already exist are skipped (see the init subcommand)")
+	rootOptions := addRootFlags(fs)
+	countersFile := fs.String("counters", "", "File holding the entity-ID counters persisted between runs so generated IDs stay unique (default: counters.json in the state directory of the update endpoint)")
+	journalFile := fs.String("journal", "", "File recording every successfully applied transaction (default: journal.jsonl in the state directory of the update endpoint)")
+	resume := fs.Bool("resume", false, "Skip transactions that the journal records as applied, to continue an import that failed halfway")
+	dryRun := fs.Bool("dry-run", false, "Query the Query API only and print the CreateEntity/UpdateEntity calls that would be made, without writing anything")
+	connection := addConnectionFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s ingest:\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Process organisation chart transactions from a specified data directory. The flags may also be\n")
+		fmt.Fprintf(os.Stderr, "given without the subcommand name, e.g. %s -data /path/to/data/directory.\n\n", os.Args[0])
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  1. Process organisation data with default settings:\n")
+		fmt.Fprintf(os.Stderr, "     %s ingest -data /path/to/data/directory\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  2. Process person data:\n")
+		fmt.Fprintf(os.Stderr, "     %s ingest -data /path/to/data/directory -type person\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  3. Initialize database and process organisation data:\n")
+		fmt.Fprintf(os.Stderr, "     %s ingest -data /path/to/data/directory -init\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  4. Use custom API endpoints:\n")
+		fmt.Fprintf(os.Stderr, "     %s ingest -data /path/to/data/directory -update_endpoint http://custom:8080/entities -query_endpoint http://custom:8081/v1/entities\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  5. Process all gazette folders of a presidency in date order:\n")
+		fmt.Fprintf(os.Stderr, "     %s ingest -data data/orgchart/rw -recursive\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  6. Preview the changes a gazette folder would make without writing them:\n")
+		fmt.Fprintf(os.Stderr, "     %s ingest -data /path/to/data/directory -dry-run\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  7. Continue an import that failed halfway:\n")
+		fmt.Fprintf(os.Stderr, "     %s ingest -data data/orgchart/rw -recursive -resume\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  8. Initialize the database with the government and the provincial councils:\n")
+		fmt.Fprintf(os.Stderr, "     %s ingest -data /path/to/data/directory -init -roots roots.json\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  9. Process a presidency configured in a config file, against the endpoints of the environment:\n")
+		fmt.Fprintf(os.Stderr, "     ORGCHART_UPDATE_ENDPOINT=http://nexoan:8080/entities %s ingest -config orgchart.yaml -presidency rw\n\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	config, err := connection.config()
+	if err != nil {
+		return err
+	}
+
+	// A presidency of the config file stands for its data directory, processed recursively
+	if *presidency != "" && *dataDir == "" {
+		*dataDir, err = config.DataDir(*presidency, *processType)
+		if err != nil {
+			return fmt.Errorf("failed to find data directory: %w", err)
+		}
+		*recursive = true
+	}
+
+	// Validate data directory
+	if *dataDir == "" {
+		fmt.Fprintf(os.Stderr, "Error: Data directory path is required\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	// Validate process type
+	if *processType != "organisation" && *processType != "person" {
+		fmt.Fprintf(os.Stderr, "Error: Invalid process type. Must be 'organisation' or 'person'\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	// Ensure the data directory exists
+	if _, err := os.Stat(*dataDir); os.IsNotExist(err) {
+		return fmt.Errorf("data directory does not exist: %s", *dataDir)
+	}
+
+	// Convert to absolute path
+	absDataDir, err := filepath.Abs(*dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	// The counters and the journal belong to the database, so by default they are kept per
+	// update endpoint
+	if *countersFile == "" {
+		*countersFile, err = config.StateFile("counters.json")
+		if err != nil {
+			return err
+		}
+	}
+	if *journalFile == "" {
+		*journalFile, err = config.StateFile("journal.jsonl")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Load the entity-ID counters left by previous runs
+	entityCounters, err := api.LoadEntityCounters(*countersFile)
+	if err != nil {
+		return fmt.Errorf("failed to load entity counters: %w", err)
+	}
+
+	// The counters are saved after every transaction, together with its journal record
+	opts := &api.ProcessOptions{
+		EntityCounters: entityCounters,
+		CountersFile:   *countersFile,
+		Resume:         *resume,
+		KindMapping:    config.Kinds,
+	}
+
+	// Open the journal of applied transactions. A dry run only reads it, to skip applied
+	// transactions when resuming.
+	if !*dryRun || *resume {
+		journal, err := api.OpenJournal(*journalFile)
+		if err != nil {
+			return fmt.Errorf("failed to open journal: %w", err)
+		}
+		defer journal.Close()
+		opts.Journal = journal
+	}
+
+	processor := api.NewProcessor(newConfiguredClient(config))
+
+	// Terms belong to the first root unless their manifest names one
+	roots, err := rootOptions.roots(config)
+	if err != nil {
+		return err
+	}
+	processor.SetRootNode(roots[0])
+
+	// In a dry run writes are recorded in a plan instead of being sent to the Update API
+	var plan *api.Plan
+	if *dryRun {
+		plan = processor.EnableDryRun()
+	}
+
+	// Initialize database if requested
+	if *initDB {
+		if err := createRoots(processor, roots); err != nil {
+			return err
+		}
+	}
+
+	// Stop between transactions on Ctrl-C, so that everything applied so far is journaled
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Process transactions
+	fmt.Printf("Processing %s transactions from directory: %s\n", *processType, absDataDir)
+	if *recursive {
+		err = processor.ProcessTransactionTreeContext(ctx, absDataDir, *processType, opts)
+	} else {
+		err = processor.ProcessTransactionsContext(ctx, absDataDir, *processType, opts)
+	}
+
+	if plan != nil {
+		if err != nil {
+			return fmt.Errorf("failed to plan transactions: %w", err)
+		}
+		fmt.Println()
+		plan.Print(os.Stdout)
+		// Returning rather than exiting here lets the journal be closed
+		if len(plan.Errors) > 0 {
+			return fmt.Errorf("%d transactions would fail", len(plan.Errors))
+		}
+		return nil
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("interrupted: %w\nRerun with -resume to continue after the last applied transaction", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to process transactions: %w\nRerun with -resume to continue after the last applied transaction", err)
+	}
+
+	fmt.Println("Successfully processed all transactions")
+	return nil
+}
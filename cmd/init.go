@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"orgchart_nexoan/api"
+)
+
+// rootFlags are the flags choosing the root nodes created by init and ingest -init; the first
+// is also the root ingest relates terms to when their manifest names none
+type rootFlags struct {
+	fs      *flag.FlagSet
+	id      *string
+	name    *string
+	created *string
+	kind    *string
+	file    *string
+}
+
+// addRootFlags registers -root_id, -root_name, -root_created, -root_kind and -roots on a flag set
+func addRootFlags(fs *flag.FlagSet) *rootFlags {
+	defaultRoot := api.DefaultRootNode()
+	return &rootFlags{
+		fs:      fs,
+		id:      fs.String("root_id", defaultRoot.ID, "ID of the government root"),
+		name:    fs.String("root_name", defaultRoot.Name, "Name of the government root"),
+		created: fs.String("root_created", defaultRoot.Created, "Date the government root was created on (YYYY-MM-DD)"),
+		kind:    fs.String("root_kind", defaultRoot.Kind, "Minor kind of the government root; the major kind is Organisation"),
+		file:    fs.String("roots", "", "JSON file listing the root nodes, e.g. the government and provincial councils; replaces the -root_* flags"),
+	}
+}
+
+// roots returns the root nodes to create: those listed by -roots, the government root of the
+// -root_* flags if any of them is set, or else the roots of the config file if it has any
+func (r *rootFlags) roots(config *api.Config) ([]api.RootNode, error) {
+	if *r.file != "" {
+		roots, err := api.LoadRootNodes(*r.file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load root nodes: %w", err)
+		}
+		return roots, nil
+	}
+
+	set := false
+	r.fs.Visit(func(f *flag.Flag) {
+		if strings.HasPrefix(f.Name, "root_") {
+			set = true
+		}
+	})
+	if len(config.Roots) > 0 && !set {
+		return config.Roots, nil
+	}
+	return []api.RootNode{{ID: *r.id, Name: *r.name, Created: *r.created, Kind: *r.kind}}, nil
+}
+
+// createRoots creates the root nodes, skipping the ones that already exist
+func createRoots(processor *api.Processor, roots []api.RootNode) error {
+	fmt.Println("Initializing database with root nodes...")
+	for _, root := range roots {
+		entity, created, err := processor.CreateRootNode(root)
+		if err != nil {
+			return fmt.Errorf("failed to create root node: %w", err)
+		}
+		if created {
+			fmt.Printf("Successfully created root node %q with ID: %s\n", root.Name, entity.ID)
+		} else {
+			fmt.Printf("Skipping root node %q: %s already exists\n", root.Name, entity.ID)
+		}
+	}
+	return nil
+}
+
+// runInit implements the init subcommand, which creates the root nodes of the org chart
+func runInit(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	rootOptions := addRootFlags(fs)
+	connection := addConnectionFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s init:\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Create the root nodes that ministers are added under: the government given by the -root_*\n")
+		fmt.Fprintf(os.Stderr, "flags, the roots listed by -roots, or the roots of the config file. Roots that already exist\n")
+		fmt.Fprintf(os.Stderr, "are skipped, so init can be run again; a root whose ID exists with another name or kind is an error.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  1. Create the government root:\n")
+		fmt.Fprintf(os.Stderr, "     %s init\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  2. Create the government and the provincial councils:\n")
+		fmt.Fprintf(os.Stderr, "     %s init -roots roots.json\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  3. Create a government root dated before gazettes older than the data in data/:\n")
+		fmt.Fprintf(os.Stderr, "     %s init -root_created 1978-09-07\n\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	config, err := connection.config()
+	if err != nil {
+		return err
+	}
+	roots, err := rootOptions.roots(config)
+	if err != nil {
+		return err
+	}
+	return createRoots(api.NewProcessor(newConfiguredClient(config)), roots)
+}
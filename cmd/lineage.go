@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"orgchart_nexoan/api"
+)
+
+// runLineage implements the lineage subcommand, which traces a minister through renames and merges
+func runLineage(args []string) error {
+	fs := flag.NewFlagSet("lineage", flag.ExitOnError)
+	minister := fs.String("minister", "", "Name or ID of the minister to trace (required)")
+	format := fs.String("format", "text", "Output format: 'text' or 'json'")
+	connection := addConnectionFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s lineage:\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Trace a minister through the RENAMED_TO and MERGED_INTO relationships: the ministers it\n")
+		fmt.Fprintf(os.Stderr, "came from and the ministers it became, with the date of every rename and merge.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  1. Trace a minister by name:\n")
+		fmt.Fprintf(os.Stderr, "     %s lineage -minister \"Minister of Irrigation and Water Resources Management\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  2. Trace a minister by ID as JSON:\n")
+		fmt.Fprintf(os.Stderr, "     %s lineage -minister 2153-12_min_3 -format json\n\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	if *minister == "" {
+		fmt.Fprintf(os.Stderr, "Error: Minister is required\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: Invalid format. Must be 'text' or 'json'\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	config, err := connection.config()
+	if err != nil {
+		return err
+	}
+	client := newConfiguredClient(config)
+	lineage, err := api.BuildLineage(client, *minister)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(lineage)
+	}
+	lineage.Print(os.Stdout)
+	return nil
+}
@@ -3,40 +3,75 @@
 //
 // Usage:
 //
-//	go run cmd/main.go -data <data_directory> [options]
+//	go run ./cmd <command> [options]
+//	go run ./cmd -data <data_directory> [options]
 //
-// Required flags:
+// The second form runs the ingest command, as the CLI did before it had commands.
 //
-//	-data string
-//	      Path to the data directory containing transactions
+// Commands:
 //
-// Optional flags:
+//	ingest
+//	      Process the transactions of a data directory (see go run ./cmd ingest -help)
+//	init
+//	      Create the root nodes of the org chart (see go run ./cmd init -help)
+//	validate
+//	      Check the CSV files of a data directory offline (see go run ./cmd validate -help)
+//	undo
+//	      Reverse the journaled transactions of a data directory (see go run ./cmd undo -help)
+//	search
+//	      List the entities matching a name, ID or kind (see go run ./cmd search -help)
+//	show
+//	      Print an entity with its metadata and relationships (see go run ./cmd show -help)
+//	snapshot
+//	      Print the org chart as it was on a date (see go run ./cmd snapshot -help)
+//	diff
+//	      Print what changed in the org chart between two dates (see go run ./cmd diff -help)
+//	lineage
+//	      Trace a minister through renames and merges (see go run ./cmd lineage -help)
+//	tenure
+//	      Print every portfolio a person has held (see go run ./cmd tenure -help)
+//	export
+//	      Write the org chart as a graph or linked data (see go run ./cmd export -help)
+//	verify
+//	      Compare the data with what Nexoan holds (see go run ./cmd verify -help)
 //
-//	-init
-//	      Initialize the database with government node
-//	-type string
-//	      Type of data to process: 'organisation' or 'person' (default: organisation)
+// Connection flags, accepted by every command that talks to Nexoan:
+//
+//	-config string
+//	      YAML config file with endpoints, timeout, retry policy, root nodes, presidencies and kind mapping (default: $ORGCHART_CONFIG)
 //	-update_endpoint string
 //	      Endpoint for the Update API (default "http://localhost:8080/entities")
 //	-query_endpoint string
 //	      Endpoint for the Query API (default "http://localhost:8081/v1/entities")
+//	-timeout duration
+//	      Time limit of each request to Nexoan (default 30s)
+//	-retries int
+//	      Attempts for requests that are safe to repeat when Nexoan is unreachable or fails (default 3)
+//
+// Settings are taken from the config file, then from the environment variables
+// ORGCHART_UPDATE_ENDPOINT, ORGCHART_QUERY_ENDPOINT, ORGCHART_TIMEOUT and ORGCHART_RETRIES, and
+// finally from the flags given on the command line, each overriding the one before.
 //
 // Examples:
 //
 //  0. Get help:
-//     go run cmd/main.go --help
+//     go run ./cmd help
+//
+//  1. Create the government root and process organisation data:
+//     go run ./cmd init
+//     go run ./cmd ingest -data /path/to/data/directory
 //
-//  1. Process organisation data with default settings:
-//     go run cmd/main.go -data /path/to/data/directory
+//  2. Process all gazette folders of a presidency in date order:
+//     go run ./cmd ingest -data data/orgchart/rw -recursive
 //
-//  2. Process person data:
-//     go run cmd/main.go -data /path/to/data/directory -type person
+//  3. Process a presidency configured in a config file, against the endpoints of the environment:
+//     ORGCHART_UPDATE_ENDPOINT=http://nexoan:8080/entities go run ./cmd ingest -config orgchart.yaml -presidency rw
 //
-//  3. Initialize database and process organisation data:
-//     go run cmd/main.go -data /path/to/data/directory -init
+//  4. Find a minister:
+//     go run ./cmd search -name "Minister of Defence" -kind Organisation/minister
 //
-//  4. Use custom API endpoints:
-//     go run cmd/main.go -data /path/to/data/directory -update_endpoint http://custom:8080/entities -query_endpoint http://custom:8081/v1/entities
+//  5. Inspect the parents and departments of a minister:
+//     go run ./cmd show "Minister of Defence"
 //
 // Process Types:
 //   - organisation: Processes minister and department entities
@@ -44,86 +79,85 @@
 package main
 
 import (
-	"flag"
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
-
-	"orgchart_nexoan/api"
+	"strings"
 )
 
-func main() {
-	// Define command line flags with detailed descriptions
-	dataDir := flag.String("data", "", "Path to the data directory containing transactions (required)")
-	initDB := flag.Bool("init", false, "Initialize the database with government node before processing transactions")
-	updateEndpoint := flag.String("update_endpoint", "http://localhost:8080/entities", "Endpoint for the Update API (default: http://localhost:8080/entities)")
-	queryEndpoint := flag.String("query_endpoint", "http://localhost:8081/v1/entities", "Endpoint for the Query API (default: http://localhost:8081/v1/entities)")
-	processType := flag.String("type", "organisation", "Type of data to process: 'organisation' or 'person' (default: organisation)")
+// command is a subcommand of the CLI
+type command struct {
+	// summary is the one-line description shown in the list of commands
+	summary string
+	run     func(args []string) error
+}
 
-	// Custom usage message
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage of %s:\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Process organisation chart transactions from a specified data directory.\n\n")
-		fmt.Fprintf(os.Stderr, "Required flags:\n")
-		flag.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  1. Process organisation data with default settings:\n")
-		fmt.Fprintf(os.Stderr, "     %s -data /path/to/data/directory\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  2. Process person data:\n")
-		fmt.Fprintf(os.Stderr, "     %s -data /path/to/data/directory -type person\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  3. Initialize database and process organisation data:\n")
-		fmt.Fprintf(os.Stderr, "     %s -data /path/to/data/directory -init\n\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  4. Use custom API endpoints:\n")
-		fmt.Fprintf(os.Stderr, "     %s -data /path/to/data/directory -update_endpoint http://custom:8080/entities -query_endpoint http://custom:8081/v1/entities\n\n", os.Args[0])
-	}
+// commands maps the name of each subcommand to its summary and the function that runs it with
+// the remaining arguments
+var commands = map[string]command{
+	"ingest":   {"Process the transactions of a data directory", runIngest},
+	"init":     {"Create the root nodes of the org chart", runInit},
+	"validate": {"Check the CSV files of a data directory offline", runValidate},
+	"undo":     {"Reverse the journaled transactions of a data directory", runUndo},
+	"search":   {"List the entities matching a name, ID or kind", runSearch},
+	"show":     {"Print an entity with its metadata and relationships", runShow},
+	"snapshot": {"Print the org chart as it was on a date", runSnapshot},
+	"diff":     {"Print what changed in the org chart between two dates", runDiff},
+	"lineage":  {"Trace a minister through renames and merges", runLineage},
+	"tenure":   {"Print every portfolio a person has held", runTenure},
+	"export":   {"Write the org chart as a graph for other tools", runExport},
+	"verify":   {"Compare the data with what Nexoan holds", runVerify},
+}
 
-	flag.Parse()
+// commandOrder is the order in which usage lists the commands
+var commandOrder = []string{"ingest", "init", "validate", "undo", "search", "show", "snapshot", "diff", "lineage", "tenure", "export", "verify"}
 
-	// Validate data directory
-	if *dataDir == "" {
-		fmt.Fprintf(os.Stderr, "Error: Data directory path is required\n\n")
-		flag.Usage()
-		os.Exit(1)
-	}
-
-	// Validate process type
-	if *processType != "organisation" && *processType != "person" {
-		fmt.Fprintf(os.Stderr, "Error: Invalid process type. Must be 'organisation' or 'person'\n\n")
-		flag.Usage()
-		os.Exit(1)
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
 	}
 
-	// Ensure the data directory exists
-	if _, err := os.Stat(*dataDir); os.IsNotExist(err) {
-		log.Fatalf("Data directory does not exist: %s", *dataDir)
+	name, args := os.Args[1], os.Args[2:]
+	switch {
+	case name == "help" || name == "-h" || name == "-help" || name == "--help":
+		// help <command> shows the help of that command
+		if len(args) > 0 {
+			if cmd, exists := commands[args[0]]; exists {
+				cmd.run([]string{"-help"})
+			}
+		}
+		usage()
+		return
+	case strings.HasPrefix(name, "-"):
+		// Flags without a command are those of ingest, as before the CLI had commands
+		name, args = "ingest", os.Args[1:]
 	}
 
-	// Convert to absolute path
-	absDataDir, err := filepath.Abs(*dataDir)
-	if err != nil {
-		log.Fatalf("Failed to get absolute path: %v", err)
+	cmd, exists := commands[name]
+	if !exists {
+		fmt.Fprintf(os.Stderr, "Error: Unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
 	}
-
-	// Create API client with configurable endpoints
-	client := api.NewClient(*updateEndpoint, *queryEndpoint)
-
-	// Initialize database if requested
-	if *initDB {
-		fmt.Println("Initializing database with government node...")
-		government, err := client.CreateGovernmentNode()
-		if err != nil {
-			log.Fatalf("Failed to create government node: %v", err)
-		}
-		fmt.Printf("Successfully created government node with ID: %s\n", government.ID)
+	if err := cmd.run(args); err != nil {
+		log.Fatalf("%s: %v", name, err)
 	}
+}
 
-	// Process transactions
-	fmt.Printf("Processing %s transactions from directory: %s\n", *processType, absDataDir)
-	err = client.ProcessTransactions(absDataDir, *processType)
-	if err != nil {
-		log.Fatalf("Failed to process transactions: %v", err)
+// usage prints the list of commands
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage of %s:\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s <command> [options]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Manage the organisation chart in Nexoan.\n\n")
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	for _, name := range commandOrder {
+		fmt.Fprintf(os.Stderr, "  %-10s  %s\n", name, commands[name].summary)
 	}
-
-	fmt.Println("Successfully processed all transactions")
+	fmt.Fprintf(os.Stderr, "\nRun %s <command> -help for the flags and examples of a command.\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Flags given without a command are passed to ingest, e.g. %s -data /path/to/data/directory.\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Commands that talk to Nexoan share the -config, -update_endpoint, -query_endpoint, -timeout\n")
+	fmt.Fprintf(os.Stderr, "and -retries flags. Settings are taken from the config file, then from the environment variables\n")
+	fmt.Fprintf(os.Stderr, "ORGCHART_UPDATE_ENDPOINT, ORGCHART_QUERY_ENDPOINT, ORGCHART_TIMEOUT and ORGCHART_RETRIES, and\n")
+	fmt.Fprintf(os.Stderr, "finally from the flags given on the command line.\n")
 }
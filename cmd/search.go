@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"orgchart_nexoan/models"
+)
+
+// runSearch implements the search subcommand, which lists the entities matching a name, ID or kind
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	name := fs.String("name", "", "Name of the entities to find")
+	id := fs.String("id", "", "ID of the entity to find")
+	kind := fs.String("kind", "", "Kind of the entities to find: a major kind such as 'Person' or major/minor such as 'Organisation/minister'")
+	format := fs.String("format", "text", "Output format: 'text' or 'json'")
+	connection := addConnectionFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s search:\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "List the entities the Query API finds for a name, an ID or a kind, with their kind, ID and\n")
+		fmt.Fprintf(os.Stderr, "the dates they were created and terminated on.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  1. Find the ministers with a name:\n")
+		fmt.Fprintf(os.Stderr, "     %s search -name \"Minister of Defence\" -kind Organisation/minister\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  2. List every department as JSON:\n")
+		fmt.Fprintf(os.Stderr, "     %s search -kind Organisation/department -format json\n\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	if *name == "" && *id == "" && *kind == "" {
+		fmt.Fprintf(os.Stderr, "Error: At least one of -name, -id and -kind is required\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: Invalid format. Must be 'text' or 'json'\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	criteria := &models.SearchCriteria{ID: *id, Name: *name}
+	if *kind != "" {
+		major, minor, _ := strings.Cut(*kind, "/")
+		criteria.Kind = &models.Kind{Major: major, Minor: minor}
+	}
+
+	config, err := connection.config()
+	if err != nil {
+		return err
+	}
+	results, err := newConfiguredClient(config).SearchEntities(criteria)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+	if len(results) == 0 {
+		fmt.Println("No entities found")
+		return nil
+	}
+	for _, result := range results {
+		fmt.Printf("%s/%s %q (%s) from %s to %s\n", result.Kind.Major, result.Kind.Minor, result.Name, result.ID,
+			strings.TrimSuffix(result.Created, "T00:00:00Z"), valueOrPresent(strings.TrimSuffix(result.Terminated, "T00:00:00Z")))
+	}
+	return nil
+}
+
+// valueOrPresent returns the end date of an entity or relationship, or "present" if it has none
+func valueOrPresent(end string) string {
+	if end == "" {
+		return "present"
+	}
+	return end
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"orgchart_nexoan/api"
+)
+
+// runShow implements the show subcommand, which prints an entity with its metadata and relationships
+func runShow(args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	format := fs.String("format", "text", "Output format: 'text' or 'json'")
+	connection := addConnectionFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s show [options] <name or ID>:\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Print the kind, dates, metadata and relationships of the entity with the given ID or, if there\n")
+		fmt.Fprintf(os.Stderr, "is none, of every entity with the given name. Relationships in both directions are listed with\n")
+		fmt.Fprintf(os.Stderr, "the names of the related entities, split into active and ended ones.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  1. Inspect a minister by name:\n")
+		fmt.Fprintf(os.Stderr, "     %s show \"Minister of Defence\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  2. Inspect an entity by ID as JSON:\n")
+		fmt.Fprintf(os.Stderr, "     %s show -format json 2153-12_min_1\n\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	// Flags may also follow the name or ID
+	if fs.NArg() > 1 {
+		nameOrID := fs.Arg(0)
+		fs.Parse(fs.Args()[1:])
+		args = append([]string{nameOrID}, fs.Args()...)
+	} else {
+		args = fs.Args()
+	}
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Error: Exactly one name or ID is required\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: Invalid format. Must be 'text' or 'json'\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	config, err := connection.config()
+	if err != nil {
+		return err
+	}
+	inspections, err := api.InspectEntities(newConfiguredClient(config), args[0], time.Now())
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(inspections)
+	}
+	for i, inspection := range inspections {
+		if i > 0 {
+			fmt.Println()
+		}
+		inspection.Print(os.Stdout)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"orgchart_nexoan/api"
+)
+
+// runSnapshot implements the snapshot subcommand, which prints the org chart as it was on a date
+func runSnapshot(args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	date := fs.String("date", "", "Date of the snapshot in YYYY-MM-DD format (required)")
+	format := fs.String("format", "text", "Output format: 'text' for an indented tree or 'json'")
+	root := fs.String("root", "", "ID of the root entity to start from, e.g. a provincial council (default: the government root found by the Query API)")
+	connection := addConnectionFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s snapshot:\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Print the ministers of the government on a date, with their departments and appointed people.\n")
+		fmt.Fprintf(os.Stderr, "Only relationships active on that date are shown.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  1. Show the cabinet on 19 January 2023:\n")
+		fmt.Fprintf(os.Stderr, "     %s snapshot -date 2023-01-19\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  2. Save it as JSON:\n")
+		fmt.Fprintf(os.Stderr, "     %s snapshot -date 2023-01-19 -format json > cabinet.json\n\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	if *date == "" {
+		fmt.Fprintf(os.Stderr, "Error: Date is required\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: Invalid format. Must be 'text' or 'json'\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	config, err := connection.config()
+	if err != nil {
+		return err
+	}
+	client := newConfiguredClient(config)
+	snapshot, err := api.BuildSnapshot(client, *root, *date)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(snapshot)
+	}
+	snapshot.Print(os.Stdout)
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"orgchart_nexoan/api"
+)
+
+// runTenure implements the tenure subcommand, which prints every portfolio a person has held
+func runTenure(args []string) error {
+	fs := flag.NewFlagSet("tenure", flag.ExitOnError)
+	person := fs.String("person", "", "Name or ID of the person (required)")
+	format := fs.String("format", "text", "Output format: 'text', 'json' or 'csv'")
+	journalFile := fs.String("journal", "", "Journal used to find the gazette transaction behind every appointment; ignored if it does not exist (default: journal.jsonl in the state directory of the update endpoint)")
+	connection := addConnectionFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s tenure:\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Print every portfolio a person has held, in date order: the minister, the relationship,\n")
+		fmt.Fprintf(os.Stderr, "the start and end dates, and the gazette transactions that started and ended it.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  1. Show the portfolios of a person:\n")
+		fmt.Fprintf(os.Stderr, "     %s tenure -person \"Ranjith Siyambalapitiya\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  2. Save them as CSV:\n")
+		fmt.Fprintf(os.Stderr, "     %s tenure -person \"Ranjith Siyambalapitiya\" -format csv > tenure.csv\n\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	if *person == "" {
+		fmt.Fprintf(os.Stderr, "Error: Person is required\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format != "text" && *format != "json" && *format != "csv" {
+		fmt.Fprintf(os.Stderr, "Error: Invalid format. Must be 'text', 'json' or 'csv'\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	config, err := connection.config()
+	if err != nil {
+		return err
+	}
+	// The journal is optional: without it the transactions are left out
+	journal, err := openOptionalJournal(config, *journalFile)
+	if err != nil {
+		return err
+	}
+	if journal != nil {
+		defer journal.Close()
+	}
+	client := newConfiguredClient(config)
+	history, err := api.BuildTenureHistory(client, *person, journal)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(history)
+	case "csv":
+		return history.WriteCSV(os.Stdout)
+	default:
+		history.Print(os.Stdout)
+		return nil
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"orgchart_nexoan/api"
+)
+
+// runUndo implements the undo subcommand, which reverses journaled transactions
+func runUndo(args []string) error {
+	fs := flag.NewFlagSet("undo", flag.ExitOnError)
+	dataDir := fs.String("data", "", "Undo every journaled transaction applied from this data directory")
+	recursive := fs.Bool("recursive", false, "Treat -data as a presidency directory and undo the transactions of every gazette folder under it")
+	transactionIDs := fs.String("transactions", "", "Comma separated transaction IDs to undo instead of a data directory")
+	processType := fs.String("type", "organisation", "Type of data the -transactions belong to: 'organisation' or 'person'")
+	journalFile := fs.String("journal", "", "File recording every applied transaction together with its changes (default: journal.jsonl in the state directory of the update endpoint)")
+	connection := addConnectionFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s undo:\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Reverse transactions recorded in the journal, most recent first: created entities are deleted,\n")
+		fmt.Fprintf(os.Stderr, "ended relationships are reopened and added relationships are removed. Undone transactions are\n")
+		fmt.Fprintf(os.Stderr, "dropped from the journal so the corrected gazette can be processed again.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  1. Undo a gazette folder:\n")
+		fmt.Fprintf(os.Stderr, "     %s undo -data data/orgchart/rw/2023-01-19\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  2. Undo single transactions of the people data:\n")
+		fmt.Fprintf(os.Stderr, "     %s undo -transactions 2289-43_tr_01,2289-43_tr_02 -type person\n\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	if (*dataDir == "") == (*transactionIDs == "") {
+		fmt.Fprintf(os.Stderr, "Error: Exactly one of -data and -transactions is required\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var selected func(api.JournalEntry) bool
+	if *dataDir != "" {
+		absDataDir, err := filepath.Abs(*dataDir)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		selected = func(entry api.JournalEntry) bool {
+			if !*recursive {
+				return entry.DataDir == absDataDir
+			}
+			rel, err := filepath.Rel(absDataDir, entry.DataDir)
+			return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+		}
+	} else {
+		ids := map[string]bool{}
+		for _, id := range strings.Split(*transactionIDs, ",") {
+			ids[strings.TrimSpace(id)] = true
+		}
+		selected = func(entry api.JournalEntry) bool {
+			return entry.ProcessType == *processType && ids[entry.TransactionID]
+		}
+	}
+
+	config, err := connection.config()
+	if err != nil {
+		return err
+	}
+	if *journalFile == "" {
+		*journalFile, err = config.StateFile("journal.jsonl")
+		if err != nil {
+			return err
+		}
+	}
+
+	journal, err := api.OpenJournal(*journalFile)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer journal.Close()
+
+	processor := api.NewProcessor(newConfiguredClient(config))
+	undone, err := processor.UndoTransactions(journal, selected)
+	if err != nil {
+		return err
+	}
+
+	if len(undone) == 0 {
+		fmt.Println("No journaled transactions matched")
+		return nil
+	}
+	fmt.Printf("Successfully undid %d transactions\n", len(undone))
+	return nil
+}
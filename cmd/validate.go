@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"orgchart_nexoan/api"
+)
+
+// knownFlag collects the values of a repeatable -known flag
+type knownFlag []string
+
+func (k *knownFlag) String() string {
+	return strings.Join(*k, ",")
+}
+
+func (k *knownFlag) Set(value string) error {
+	*k = append(*k, value)
+	return nil
+}
+
+// runValidate implements the validate subcommand, which checks a data directory offline
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	dataDir := fs.String("data", "", "Path to the data directory to validate (required)")
+	recursive := fs.Bool("recursive", false, "Treat -data as a presidency directory and validate every dated gazette folder in date order")
+	var known knownFlag
+	rootsFile := fs.String("roots", "", "JSON file listing the root nodes created by -init, which are known in addition to the government")
+	configFile := fs.String("config", "", configUsage)
+	fs.Var(&known, "known", "Entities that exist before -data is applied: a file with one entity name per line, or a data directory whose transactions create them (repeatable)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s validate:\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Check gazette CSV files without contacting any server. Headers, dates, transaction IDs and\n")
+		fmt.Fprintf(os.Stderr, "references to entities are checked; the command exits with status 1 if any issue is found.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  1. Validate a presidency's organisation data:\n")
+		fmt.Fprintf(os.Stderr, "     %s validate -data data/orgchart/rw -recursive\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  2. Validate people data against the ministers created by the organisation data:\n")
+		fmt.Fprintf(os.Stderr, "     %s validate -data data/people/rw -recursive -known data/orgchart/rw\n\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	if *dataDir == "" {
+		fmt.Fprintf(os.Stderr, "Error: Data directory path is required\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	config, err := loadConfig(fs, *configFile)
+	if err != nil {
+		return err
+	}
+
+	// The root nodes are created by -init, so they are always known
+	knownNames := map[string]bool{api.DefaultRootNode().Name: true}
+	roots := config.Roots
+	if *rootsFile != "" {
+		roots, err = api.LoadRootNodes(*rootsFile)
+		if err != nil {
+			return err
+		}
+	}
+	for _, root := range roots {
+		knownNames[root.Name] = true
+	}
+	for _, source := range known {
+		if err := loadKnownEntities(source, knownNames); err != nil {
+			return err
+		}
+	}
+
+	var issues []api.ValidationIssue
+	if *recursive {
+		issues, err = api.ValidateTransactionTree(*dataDir, knownNames)
+	} else {
+		issues, err = api.ValidateTransactions(*dataDir, knownNames)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		fmt.Printf("\n%d issues found\n", len(issues))
+		os.Exit(1)
+	}
+
+	fmt.Println("No issues found")
+	return nil
+}
+
+// loadKnownEntities adds the entity names of a -known source to known. A directory is validated
+// and the names its transactions create are added; its own issues are ignored.
+func loadKnownEntities(source string, known map[string]bool) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("failed to read known entities: %w", err)
+	}
+
+	if info.IsDir() {
+		// A directory holding CSV files is a single gazette folder, otherwise a presidency directory
+		csvFiles, _ := filepath.Glob(filepath.Join(source, "*.csv"))
+		if len(csvFiles) > 0 {
+			_, err = api.ValidateTransactions(source, known)
+		} else {
+			_, err = api.ValidateTransactionTree(source, known)
+		}
+		return err
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to read known entities: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name != "" && !strings.HasPrefix(name, "#") {
+			known[name] = true
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"orgchart_nexoan/api"
+)
+
+// runVerify implements the verify subcommand, which compares the graph the data trees produce
+// with the graph Nexoan holds
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	orgchartDir := fs.String("orgchart", "", "Organisation data tree: a category directory (data/orgchart), a presidency directory or a gazette folder")
+	peopleDir := fs.String("people", "", "Person data tree: a category directory (data/people), a presidency directory or a gazette folder")
+	format := fs.String("format", "text", "Output format: 'text' for a report or 'json'")
+	rootsFile := fs.String("roots", "", "JSON file listing the root nodes the data is replayed under, as given to -init (default: the roots of the config file, or the government root)")
+	verbose := fs.Bool("verbose", false, "Print the transactions as they are replayed")
+	journalFile := fs.String("journal", "", "Journal listing the relationships removed by undo, which are left out; ignored if it does not exist (default: journal.jsonl in the state directory of the update endpoint)")
+	connection := addConnectionFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s verify:\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Replay the data trees in memory and compare the resulting graph with the one the Query API holds.\n")
+		fmt.Fprintf(os.Stderr, "Lists missing and extra entities and relationships, relationships with wrong end dates and\n")
+		fmt.Fprintf(os.Stderr, "duplicate names. Exits with status 1 if Nexoan does not match the data.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  1. Check all organisation and person data:\n")
+		fmt.Fprintf(os.Stderr, "     %s verify -orgchart data/orgchart -people data/people\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  2. Check the organisation data of one presidency as JSON:\n")
+		fmt.Fprintf(os.Stderr, "     %s verify -orgchart data/orgchart/rw -format json\n\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	config, err := connection.config()
+	if err != nil {
+		return err
+	}
+
+	var trees []api.DataTree
+	if *orgchartDir != "" {
+		trees = append(trees, api.DataTree{Dir: *orgchartDir, ProcessType: "organisation", KindMapping: config.Kinds})
+	}
+	if *peopleDir != "" {
+		trees = append(trees, api.DataTree{Dir: *peopleDir, ProcessType: "person", KindMapping: config.Kinds})
+	}
+	if len(trees) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: At least one of -orgchart and -people is required\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: Invalid format. Must be 'text' or 'json'\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	roots := config.Roots
+	if *rootsFile != "" {
+		roots, err = api.LoadRootNodes(*rootsFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The processor reports every transaction on standard output, which would bury the report
+	stdout := os.Stdout
+	if !*verbose {
+		devNull, err := os.Open(os.DevNull)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", os.DevNull, err)
+		}
+		defer devNull.Close()
+		os.Stdout = devNull
+	}
+	store, err := api.ReplayDataTrees(trees, roots)
+	os.Stdout = stdout
+	if err != nil {
+		return err
+	}
+
+	expected, err := api.LoadGraph(store, "", nil)
+	if err != nil {
+		return fmt.Errorf("failed to load replayed graph: %w", err)
+	}
+	journal, err := openOptionalJournal(config, *journalFile)
+	if err != nil {
+		return err
+	}
+	if journal != nil {
+		defer journal.Close()
+	}
+	client := newConfiguredClient(config)
+	actual, err := api.LoadGraph(client, "", journal)
+	if err != nil {
+		return fmt.Errorf("failed to load graph: %w", err)
+	}
+
+	// People are only compared when their data is given
+	kinds := []string{"Organisation"}
+	if *peopleDir != "" {
+		kinds = append(kinds, "Person")
+	}
+	reconciliation := api.Reconcile(expected, actual, kinds)
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reconciliation); err != nil {
+			return err
+		}
+	} else {
+		reconciliation.Print(os.Stdout)
+	}
+	if !reconciliation.Empty() {
+		os.Exit(1)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"orgchart_nexoan/api"
+)
+
+// dotEscaper escapes a string for a double quoted Graphviz ID
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// dotQuote returns s as a double quoted Graphviz ID
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+// WriteDOT writes the graph as a Graphviz digraph. Nodes are labelled with the entity name and
+// edges with the relationship name and the dates it was active. The minor kind of each node is
+// its class, which SVG output carries and stylesheets can select on.
+func WriteDOT(w io.Writer, graph *api.Graph, opts Options) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph orgchart {")
+	fmt.Fprintln(out, "  rankdir=LR;")
+	fmt.Fprintln(out, "  node [shape=box];")
+	if graph.Date != "" {
+		fmt.Fprintf(out, "  label=%s;\n", dotQuote("Org chart on "+graph.Date))
+	}
+
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(out, "  %s [label=%s, class=%s];\n", dotQuote(node.ID), dotQuote(node.Name), dotQuote(node.Kind.Minor))
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(out, "  %s -> %s [label=%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Name+"\n"+dateRange(edge)))
+	}
+
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
@@ -0,0 +1,100 @@
+// Package export writes the org chart graph loaded by api.LoadGraph in file formats used by other
+// tools. Every format has a Writer registered under its name; Write selects one by name.
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"orgchart_nexoan/api"
+)
+
+// DefaultBaseURI is the base of the URIs given to entities and relationships in the linked data
+// formats unless Options.BaseURI is set
+const DefaultBaseURI = "http://example.org/orgchart/"
+
+// Options configures an export
+type Options struct {
+	// BaseURI is the base of the URIs of entities, relationships and classes in the linked data
+	// formats; DefaultBaseURI if empty
+	BaseURI string
+}
+
+// baseURI returns the base URI of the options, ending with a slash or hash
+func (o Options) baseURI() string {
+	base := o.BaseURI
+	if base == "" {
+		base = DefaultBaseURI
+	}
+	if !strings.HasSuffix(base, "/") && !strings.HasSuffix(base, "#") {
+		base += "/"
+	}
+	return base
+}
+
+// Writer writes a graph in one format
+type Writer func(w io.Writer, graph *api.Graph, opts Options) error
+
+// writers holds the Writer of each format, keyed by format name
+var writers = map[string]Writer{
+	"dot":     WriteDOT,
+	"graphml": WriteGraphML,
+	"jsonld":  WriteJSONLD,
+	"mermaid": WriteMermaid,
+	"turtle":  WriteTurtle,
+}
+
+// Formats returns the names of the supported formats in alphabetical order
+func Formats() []string {
+	formats := make([]string, 0, len(writers))
+	for format := range writers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// Write writes the graph in the named format
+func Write(w io.Writer, graph *api.Graph, format string, opts Options) error {
+	writer, ok := writers[format]
+	if !ok {
+		return fmt.Errorf("unknown export format %q, must be one of %s", format, strings.Join(Formats(), ", "))
+	}
+	return writer(w, graph, opts)
+}
+
+// date returns the date part of an RFC 3339 time
+func date(t string) string {
+	if len(t) >= len("2006-01-02") {
+		return t[:len("2006-01-02")]
+	}
+	return t
+}
+
+// dateRange describes when a relationship was active, e.g. "2019-12-10 to present"
+func dateRange(edge api.GraphEdge) string {
+	end := "present"
+	if edge.EndTime != "" {
+		end = date(edge.EndTime)
+	}
+	return fmt.Sprintf("%s to %s", date(edge.StartTime), end)
+}
+
+// edgeIDs returns an ID for each edge of the graph that is unique within it. A relationship that
+// recurs, e.g. a department that returns to a minister, keeps its ID in Nexoan, so the start date
+// is appended to every ID, and a number as well when edges with the same ID start on the same date.
+func edgeIDs(edges []api.GraphEdge) []string {
+	ids := make([]string, len(edges))
+	seen := map[string]int{}
+	for i, edge := range edges {
+		id := edge.ID + "_" + date(edge.StartTime)
+		seen[id]++
+		if seen[id] > 1 {
+			id = fmt.Sprintf("%s_%d", id, seen[id])
+		}
+		ids[i] = id
+	}
+	return ids
+}
@@ -0,0 +1,105 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"orgchart_nexoan/api"
+)
+
+// graphMLKeys declares the data attributes of nodes and edges
+var graphMLKeys = []graphMLKey{
+	{ID: "name", For: "node", Name: "name", Type: "string"},
+	{ID: "kind_major", For: "node", Name: "kind_major", Type: "string"},
+	{ID: "kind_minor", For: "node", Name: "kind_minor", Type: "string"},
+	{ID: "relationship", For: "edge", Name: "relationship", Type: "string"},
+	{ID: "start_date", For: "edge", Name: "start_date", Type: "string"},
+	{ID: "end_date", For: "edge", Name: "end_date", Type: "string"},
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph as GraphML, which Gephi and yEd can open. Nodes carry the entity
+// name and kind, and edges the relationship name and its start and end dates.
+func WriteGraphML(w io.Writer, graph *api.Graph, opts Options) error {
+	document := graphMLDocument{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys:  graphMLKeys,
+		Graph: graphMLGraph{ID: "orgchart", EdgeDefault: "directed"},
+	}
+
+	for _, node := range graph.Nodes {
+		document.Graph.Nodes = append(document.Graph.Nodes, graphMLNode{
+			ID: node.ID,
+			Data: []graphMLData{
+				{Key: "name", Value: node.Name},
+				{Key: "kind_major", Value: node.Kind.Major},
+				{Key: "kind_minor", Value: node.Kind.Minor},
+			},
+		})
+	}
+	ids := edgeIDs(graph.Edges)
+	for i, edge := range graph.Edges {
+		data := []graphMLData{
+			{Key: "relationship", Value: edge.Name},
+			{Key: "start_date", Value: date(edge.StartTime)},
+		}
+		if edge.EndTime != "" {
+			data = append(data, graphMLData{Key: "end_date", Value: date(edge.EndTime)})
+		}
+		document.Graph.Edges = append(document.Graph.Edges, graphMLEdge{
+			ID:     ids[i],
+			Source: edge.From,
+			Target: edge.To,
+			Data:   data,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write GraphML: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("failed to write GraphML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,89 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"orgchart_nexoan/api"
+)
+
+// WriteJSONLD writes the graph as JSON-LD. Entities are typed with the classes of their major and
+// minor kind, and every relationship is a resource of its own carrying its type, source, target
+// and start and end dates, so that its time bounds are kept.
+func WriteJSONLD(w io.Writer, graph *api.Graph, opts Options) error {
+	vocabulary := rdfVocabulary{base: opts.baseURI()}
+	items := []map[string]interface{}{}
+
+	for _, class := range rdfClasses(graph) {
+		item := map[string]interface{}{
+			"@id":   "oc:" + class.Name,
+			"@type": "rdfs:Class",
+		}
+		if class.SubClassOf != "" {
+			item["rdfs:subClassOf"] = map[string]string{"@id": "oc:" + class.SubClassOf}
+		}
+		items = append(items, item)
+	}
+
+	for _, node := range graph.Nodes {
+		types := []string{}
+		for _, class := range nodeClasses(node) {
+			types = append(types, "oc:"+class)
+		}
+		item := map[string]interface{}{
+			"@id":      vocabulary.entity(node.ID),
+			"@type":    types,
+			"label":    node.Name,
+			"entityId": node.ID,
+		}
+		if node.Created != "" {
+			item["created"] = node.Created
+		}
+		if node.Terminated != "" {
+			item["terminated"] = node.Terminated
+		}
+		items = append(items, item)
+	}
+
+	ids := edgeIDs(graph.Edges)
+	for i, edge := range graph.Edges {
+		item := map[string]interface{}{
+			"@id":              vocabulary.relationship(ids[i]),
+			"@type":            "oc:Relationship",
+			"relationshipType": edge.Name,
+			"source":           vocabulary.entity(edge.From),
+			"target":           vocabulary.entity(edge.To),
+			"startDate":        date(edge.StartTime),
+		}
+		if edge.EndTime != "" {
+			item["endDate"] = date(edge.EndTime)
+		}
+		items = append(items, item)
+	}
+
+	document := map[string]interface{}{
+		"@context": map[string]interface{}{
+			"oc":               vocabulary.ontology(),
+			"rdfs":             rdfsNamespace,
+			"xsd":              xsdNamespace,
+			"label":            "rdfs:label",
+			"entityId":         "oc:entityId",
+			"created":          map[string]string{"@id": "oc:created", "@type": "xsd:dateTime"},
+			"terminated":       map[string]string{"@id": "oc:terminated", "@type": "xsd:dateTime"},
+			"relationshipType": "oc:relationshipType",
+			"source":           map[string]string{"@id": "oc:source", "@type": "@id"},
+			"target":           map[string]string{"@id": "oc:target", "@type": "@id"},
+			"startDate":        map[string]string{"@id": "oc:startDate", "@type": "xsd:date"},
+			"endDate":          map[string]string{"@id": "oc:endDate", "@type": "xsd:date"},
+		},
+		"@graph": items,
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("failed to write JSON-LD: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"orgchart_nexoan/api"
+)
+
+// mermaidEscaper escapes text for a double quoted Mermaid label
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "\n", " ")
+
+// WriteMermaid writes the graph as a Mermaid flowchart. Entity IDs contain characters Mermaid
+// does not accept in node IDs, so nodes are numbered n0, n1, ... in the order of the graph and
+// labelled with the entity name.
+func WriteMermaid(w io.Writer, graph *api.Graph, opts Options) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "flowchart LR")
+	if graph.Date != "" {
+		fmt.Fprintf(out, "  %%%% Org chart on %s\n", graph.Date)
+	}
+
+	ids := map[string]string{}
+	for i, node := range graph.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(out, "  %s[\"%s\"]\n", ids[node.ID], mermaidEscaper.Replace(node.Name))
+	}
+	for _, edge := range graph.Edges {
+		label := mermaidEscaper.Replace(edge.Name + " " + dateRange(edge))
+		fmt.Fprintf(out, "  %s -->|\"%s\"| %s\n", ids[edge.From], label, ids[edge.To])
+	}
+
+	return out.Flush()
+}
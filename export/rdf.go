@@ -0,0 +1,85 @@
+package export
+
+import (
+	"net/url"
+	"strings"
+
+	"orgchart_nexoan/api"
+)
+
+// Namespaces of the vocabularies used by the linked data formats
+const (
+	rdfsNamespace = "http://www.w3.org/2000/01/rdf-schema#"
+	xsdNamespace  = "http://www.w3.org/2001/XMLSchema#"
+)
+
+// rdfVocabulary builds the URIs of the linked data formats from a base URI. Entities and
+// relationships get stable URIs derived from their Nexoan IDs:
+//
+//	<base>entity/<id>          an entity
+//	<base>relationship/<id>    a relationship
+//	<base>ontology/<Name>      a class (the kinds) or property
+type rdfVocabulary struct {
+	base string
+}
+
+func (v rdfVocabulary) ontology() string {
+	return v.base + "ontology/"
+}
+
+func (v rdfVocabulary) entity(id string) string {
+	return v.base + "entity/" + url.PathEscape(id)
+}
+
+func (v rdfVocabulary) relationship(id string) string {
+	return v.base + "relationship/" + url.PathEscape(id)
+}
+
+// className returns the local name of the class of a kind in upper camel case, e.g. "Minister"
+// for "minister"
+func className(kind string) string {
+	var name strings.Builder
+	for _, word := range strings.FieldsFunc(kind, func(r rune) bool { return r == '_' || r == ' ' }) {
+		name.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return url.PathEscape(name.String())
+}
+
+// rdfClass is a class of the exported entities: a major kind, or a minor kind that is a
+// subclass of its major kind
+type rdfClass struct {
+	Name       string
+	SubClassOf string
+}
+
+// rdfClasses returns the classes used by the nodes of a graph in the order they first appear
+func rdfClasses(graph *api.Graph) []rdfClass {
+	seen := map[string]bool{}
+	classes := []rdfClass{}
+	add := func(class rdfClass) {
+		if class.Name == "" || seen[class.Name] {
+			return
+		}
+		seen[class.Name] = true
+		classes = append(classes, class)
+	}
+	for _, node := range graph.Nodes {
+		major := className(node.Kind.Major)
+		add(rdfClass{Name: major})
+		if minor := className(node.Kind.Minor); minor != major {
+			add(rdfClass{Name: minor, SubClassOf: major})
+		}
+	}
+	return classes
+}
+
+// nodeClasses returns the classes of a node: its major and minor kind
+func nodeClasses(node api.GraphNode) []string {
+	classes := []string{}
+	for _, kind := range []string{node.Kind.Major, node.Kind.Minor} {
+		if name := className(kind); name != "" && (len(classes) == 0 || classes[0] != name) {
+			classes = append(classes, name)
+		}
+	}
+	return classes
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"orgchart_nexoan/api"
+)
+
+// turtleEscaper escapes a string for a double quoted Turtle literal
+var turtleEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// turtleLiteral returns s as a Turtle string literal
+func turtleLiteral(s string) string {
+	return `"` + turtleEscaper.Replace(s) + `"`
+}
+
+// writeTurtleSubject writes a subject with its predicate-object pairs
+func writeTurtleSubject(out io.Writer, subject string, pairs [][2]string) {
+	fmt.Fprintf(out, "%s", subject)
+	for i, pair := range pairs {
+		separator := " ;"
+		if i == len(pairs)-1 {
+			separator = " ."
+		}
+		if i == 0 {
+			fmt.Fprintf(out, " %s %s%s\n", pair[0], pair[1], separator)
+		} else {
+			fmt.Fprintf(out, "    %s %s%s\n", pair[0], pair[1], separator)
+		}
+	}
+	fmt.Fprintln(out)
+}
+
+// WriteTurtle writes the graph as RDF in Turtle, with the same resources and properties as
+// WriteJSONLD
+func WriteTurtle(w io.Writer, graph *api.Graph, opts Options) error {
+	vocabulary := rdfVocabulary{base: opts.baseURI()}
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "@prefix oc: <%s> .\n", vocabulary.ontology())
+	fmt.Fprintf(out, "@prefix rdfs: <%s> .\n", rdfsNamespace)
+	fmt.Fprintf(out, "@prefix xsd: <%s> .\n\n", xsdNamespace)
+
+	for _, class := range rdfClasses(graph) {
+		pairs := [][2]string{{"a", "rdfs:Class"}}
+		if class.SubClassOf != "" {
+			pairs = append(pairs, [2]string{"rdfs:subClassOf", "oc:" + class.SubClassOf})
+		}
+		writeTurtleSubject(out, "oc:"+class.Name, pairs)
+	}
+
+	for _, node := range graph.Nodes {
+		types := []string{}
+		for _, class := range nodeClasses(node) {
+			types = append(types, "oc:"+class)
+		}
+		pairs := [][2]string{
+			{"a", strings.Join(types, ", ")},
+			{"rdfs:label", turtleLiteral(node.Name)},
+			{"oc:entityId", turtleLiteral(node.ID)},
+		}
+		if node.Created != "" {
+			pairs = append(pairs, [2]string{"oc:created", turtleLiteral(node.Created) + "^^xsd:dateTime"})
+		}
+		if node.Terminated != "" {
+			pairs = append(pairs, [2]string{"oc:terminated", turtleLiteral(node.Terminated) + "^^xsd:dateTime"})
+		}
+		writeTurtleSubject(out, "<"+vocabulary.entity(node.ID)+">", pairs)
+	}
+
+	ids := edgeIDs(graph.Edges)
+	for i, edge := range graph.Edges {
+		pairs := [][2]string{
+			{"a", "oc:Relationship"},
+			{"oc:relationshipType", turtleLiteral(edge.Name)},
+			{"oc:source", "<" + vocabulary.entity(edge.From) + ">"},
+			{"oc:target", "<" + vocabulary.entity(edge.To) + ">"},
+			{"oc:startDate", turtleLiteral(date(edge.StartTime)) + "^^xsd:date"},
+		}
+		if edge.EndTime != "" {
+			pairs = append(pairs, [2]string{"oc:endDate", turtleLiteral(date(edge.EndTime)) + "^^xsd:date"})
+		}
+		writeTurtleSubject(out, "<"+vocabulary.relationship(ids[i])+">", pairs)
+	}
+
+	return out.Flush()
+}
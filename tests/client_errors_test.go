@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"orgchart_nexoan/tests/fakenexoan"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientTypedErrors(t *testing.T) {
+	// Updating an entity that does not exist
+	_, err := client.UpdateEntity("missing_entity", &models.Entity{ID: "missing_entity"})
+	var notFound *api.NotFoundError
+	assert.ErrorAs(t, err, &notFound)
+	assert.ErrorIs(t, err, api.ErrEntityNotFound)
+	if notFound != nil {
+		assert.Equal(t, http.StatusNotFound, notFound.StatusCode)
+		assert.Equal(t, http.MethodPut, notFound.Method)
+	}
+
+	// Creating an entity whose ID is taken
+	_, err = client.CreateEntity(&models.Entity{
+		ID:   "gov_01",
+		Kind: models.Kind{Major: "Organisation", Minor: "government"},
+		Name: models.TimeBasedValue{StartTime: "2024-01-01T00:00:00Z", Value: "Government of Sri Lanka"},
+	})
+	var conflict *api.ConflictError
+	assert.ErrorAs(t, err, &conflict)
+	assert.ErrorIs(t, err, api.ErrDuplicateEntity)
+
+	// Every typed error is a RequestError
+	var reqErr *api.RequestError
+	assert.ErrorAs(t, err, &reqErr)
+}
+
+// flakyServer answers the first failures requests with 503 and passes the rest to a fake Nexoan
+func flakyServer(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	fake := fakenexoan.New()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	policy := api.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, Multiplier: 2}
+
+	// Reads are retried until they succeed
+	server, requests := flakyServer(t, 2)
+	retryingClient := api.NewClient(server.URL+"/entities", server.URL+"/v1/entities", api.WithRetryPolicy(policy))
+	results, err := retryingClient.SearchEntities(&models.SearchCriteria{Name: "Government of Sri Lanka"})
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Equal(t, int32(3), requests.Load())
+
+	// Creating an entity is not idempotent, so it is sent once
+	server, requests = flakyServer(t, 1)
+	retryingClient = api.NewClient(server.URL+"/entities", server.URL+"/v1/entities", api.WithRetryPolicy(policy))
+	_, err = retryingClient.CreateEntity(&models.Entity{ID: "gov_01"})
+	var serverErr *api.ServerError
+	assert.ErrorAs(t, err, &serverErr)
+	assert.Equal(t, int32(1), requests.Load())
+
+	// The last error is returned once the attempts are used up
+	server, requests = flakyServer(t, 5)
+	retryingClient = api.NewClient(server.URL+"/entities", server.URL+"/v1/entities", api.WithRetryPolicy(policy))
+	_, err = retryingClient.GetAllRelatedEntities("gov_01")
+	assert.True(t, errors.As(err, &serverErr))
+	assert.Equal(t, int32(3), requests.Load())
+	if serverErr != nil {
+		assert.Equal(t, "unavailable", serverErr.Body)
+	}
+}
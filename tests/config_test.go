@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	// Without a file the defaults are used
+	config, err := api.LoadConfig("")
+	assert.NoError(t, err)
+	assert.Equal(t, api.DefaultConfig(), config)
+
+	dir := t.TempDir()
+	writeGazetteFile(t, dir, "orgchart.yaml", `update_endpoint: http://nexoan:8080/entities
+timeout: 1m
+retry:
+  max_attempts: 5
+roots:
+  - id: pc_western
+    name: Western Provincial Council
+presidencies:
+  rw:
+    orgchart: data/orgchart/rw
+    people: /srv/data/people/rw
+kinds:
+  ministry: minister
+`)
+	config, err = api.LoadConfig(filepath.Join(dir, "orgchart.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://nexoan:8080/entities", config.UpdateEndpoint)
+	assert.Equal(t, api.DefaultConfig().QueryEndpoint, config.QueryEndpoint)
+	assert.Equal(t, time.Minute, config.Timeout)
+
+	// Settings left out of a section keep their defaults
+	assert.Equal(t, 5, config.Retry.MaxAttempts)
+	assert.Equal(t, api.DefaultRetryPolicy().InitialBackoff, config.Retry.InitialBackoff)
+	assert.Equal(t, []api.RootNode{{ID: "pc_western", Name: "Western Provincial Council", Created: "2015-01-09", Kind: "government"}}, config.Roots)
+	assert.Equal(t, map[string]string{"ministry": "minister"}, config.Kinds)
+
+	// Relative data directories are relative to the config file
+	orgchartDir, err := config.DataDir("rw", "organisation")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "data", "orgchart", "rw"), orgchartDir)
+	peopleDir, err := config.DataDir("rw", "person")
+	assert.NoError(t, err)
+	assert.Equal(t, "/srv/data/people/rw", peopleDir)
+	_, err = config.DataDir("gr", "organisation")
+	assert.ErrorContains(t, err, `unknown presidency "gr" (configured: rw)`)
+
+	// Environment variables override the file
+	env := map[string]string{api.EnvQueryEndpoint: "http://nexoan:8081/v1/entities", api.EnvRetries: "1"}
+	assert.NoError(t, config.ApplyEnv(func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}))
+	assert.Equal(t, "http://nexoan:8080/entities", config.UpdateEndpoint)
+	assert.Equal(t, "http://nexoan:8081/v1/entities", config.QueryEndpoint)
+	assert.Equal(t, 1, config.Retry.MaxAttempts)
+
+	env = map[string]string{api.EnvTimeout: "soon"}
+	assert.ErrorContains(t, config.ApplyEnv(func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}), api.EnvTimeout)
+
+	// Misspelt settings are reported instead of being ignored
+	writeGazetteFile(t, dir, "typo.yaml", "update_endpiont: http://nexoan:8080/entities\n")
+	_, err = api.LoadConfig(filepath.Join(dir, "typo.yaml"))
+	assert.ErrorContains(t, err, "failed to decode config file")
+}
+
+func TestStateFile(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("HOME", configDir)
+
+	// State files are kept per update endpoint, whatever the working directory
+	config := api.DefaultConfig()
+	path, err := config.StateFile("counters.json")
+	assert.NoError(t, err)
+	assert.DirExists(t, filepath.Dir(path))
+	assert.Equal(t, "counters.json", filepath.Base(path))
+
+	other := api.DefaultConfig()
+	other.UpdateEndpoint = "http://nexoan:8080/entities"
+	otherPath, err := other.StateFile("counters.json")
+	assert.NoError(t, err)
+	assert.NotEqual(t, path, otherPath)
+	assert.Equal(t, "http_nexoan_8080_entities", filepath.Base(filepath.Dir(otherPath)))
+}
+
+func TestKindMapping(t *testing.T) {
+	dataDir := t.TempDir()
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,ministry,AS_MINISTER,2019-12-10
+2153-12_tr_02,Minister of Defence,ministry,Sri Lanka Army,dept,AS_DEPARTMENT,2019-12-10`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	opts := &api.ProcessOptions{KindMapping: map[string]string{"ministry": "minister", "dept": "department"}}
+	assert.NoError(t, memoryProcessor.ProcessTransactions(dataDir, "organisation", opts))
+
+	results, err := store.SearchEntities(&models.SearchCriteria{Name: "Sri Lanka Army"})
+	assert.NoError(t, err)
+	if assert.Len(t, results, 1) {
+		assert.Equal(t, "department", results[0].Kind.Minor)
+	}
+	assert.Equal(t, 1, opts.EntityCounters["minister"])
+	assert.Equal(t, 1, opts.EntityCounters["department"])
+}
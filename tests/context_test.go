@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"orgchart_nexoan/tests/fakenexoan"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.SearchEntitiesContext(ctx, &models.SearchCriteria{Name: "Government of Sri Lanka"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The same request succeeds with a live context
+	results, err := client.SearchEntitiesContext(context.Background(), &models.SearchCriteria{Name: "Government of Sri Lanka"})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+}
+
+func TestProcessTransactionsContextStopsBetweenTransactions(t *testing.T) {
+	dataDir := t.TempDir()
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-10`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+
+	// Cancel once the first transaction has been applied
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopAfterFirst := &cancellingStore{Store: store, cancel: cancel}
+	opts := &api.ProcessOptions{}
+	err = api.NewProcessor(stopAfterFirst).ProcessTransactionsContext(ctx, dataDir, "organisation", opts)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, opts.EntityCounters["minister"])
+
+	results, err := store.SearchEntities(&models.SearchCriteria{Kind: &models.Kind{Major: "Organisation", Minor: "minister"}})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	if len(results) == 1 {
+		assert.Equal(t, "Minister of Defence", results[0].Name)
+	}
+}
+
+// cancellingStore cancels a context after the first relationship is written through it
+type cancellingStore struct {
+	api.Store
+	cancel context.CancelFunc
+}
+
+func (c *cancellingStore) UpdateEntity(id string, entity *models.Entity) (*models.Entity, error) {
+	defer c.cancel()
+	return c.Store.UpdateEntity(id, entity)
+}
+
+func TestProcessTransactionsContextFinishesTransactionInFlight(t *testing.T) {
+	// The server answers the requests about the slow minister only after the deadline has passed
+	fake := fakenexoan.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if r.Method == http.MethodPost && strings.Contains(string(body), "Minister of Slowness") {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	slowClient := api.NewClient(server.URL+"/entities", server.URL+"/v1/entities", api.WithRetryPolicy(api.NoRetry))
+	slowProcessor := api.NewProcessor(slowClient)
+	_, err := slowProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+
+	dataDir := t.TempDir()
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Slowness,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Government of Sri Lanka,government,Minister of Haste,minister,AS_MINISTER,2019-12-10`)
+
+	// The transaction in flight when the deadline passes is finished, and the next one is not started
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	opts := &api.ProcessOptions{}
+	err = slowProcessor.ProcessTransactionsContext(ctx, dataDir, "organisation", opts)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, opts.EntityCounters["minister"])
+
+	relations, err := slowClient.GetRelatedEntities(api.DefaultGovernmentID, &models.Relationship{Name: "AS_MINISTER"})
+	assert.NoError(t, err)
+	if assert.Len(t, relations, 1) {
+		assert.Equal(t, "2153-12_min_1", relations[0].RelatedEntityID)
+	}
+	results, err := slowClient.SearchEntities(&models.SearchCriteria{Name: "Minister of Haste"})
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
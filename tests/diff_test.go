@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"bytes"
+	"orgchart_nexoan/api"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// diffNames returns the names of the entities of a diff section
+func diffNames(entities []api.DiffEntity) []string {
+	names := []string{}
+	for _, entity := range entities {
+		names = append(names, entity.Name)
+	}
+	return names
+}
+
+func TestBuildDiff(t *testing.T) {
+	orgDir := t.TempDir()
+	writeGazetteFile(t, filepath.Join(orgDir, "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Government of Sri Lanka,government,Minister of Finance,minister,AS_MINISTER,2019-12-10
+2153-12_tr_03,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-10
+2153-12_tr_04,Government of Sri Lanka,government,Minister of Trade,minister,AS_MINISTER,2019-12-10
+2153-12_tr_05,Government of Sri Lanka,government,Minister of Ports,minister,AS_MINISTER,2019-12-10
+2153-12_tr_06,Minister of Defence,minister,Sri Lanka Army,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_07,Minister of Defence,minister,Sri Lanka Navy,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_08,Minister of Health,minister,Department of Hospitals,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_09,Minister of Trade,minister,Department of Exports,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_10,Minister of Ports,minister,Department of Harbours,department,AS_DEPARTMENT,2019-12-10`)
+	reshuffleDir := filepath.Join(orgDir, "2020-01-15")
+	writeGazetteFile(t, reshuffleDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2160-01_tr_01,Government of Sri Lanka,government,Minister of Education,minister,AS_MINISTER,2020-01-15`)
+	writeGazetteFile(t, reshuffleDir, "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2160-01_tr_02,Minister of Defence,minister,Sri Lanka Army,department,AS_DEPARTMENT,2020-01-15`)
+	writeGazetteFile(t, reshuffleDir, "MOVE.csv", `transaction_id,old_parent,new_parent,child,type,date
+2160-01_tr_03,Minister of Defence,Minister of Finance,Sri Lanka Navy,AS_DEPARTMENT,2020-01-15`)
+	writeGazetteFile(t, reshuffleDir, "RENAME.csv", `transaction_id,old,new,type,date
+2160-01_tr_04,Minister of Health,Minister of Health and Wellness,AS_MINISTER,2020-01-15`)
+	writeGazetteFile(t, reshuffleDir, "MERGE.csv", `transaction_id,old,new,type,date
+2160-01_tr_05,"[Minister of Trade, Minister of Ports]",Minister of Trade and Ports,AS_MINISTER,2020-01-15`)
+
+	peopleDir := t.TempDir()
+	writeGazetteFile(t, filepath.Join(peopleDir, "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-13_tr_01,Minister of Defence,minister,Gotabaya Rajapaksa,citizen,AS_APPOINTED,2019-12-10
+2153-13_tr_02,Minister of Finance,minister,Mahinda Rajapaksa,citizen,AS_APPOINTED,2019-12-10`)
+	writeGazetteFile(t, filepath.Join(peopleDir, "2020-01-15"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2160-02_tr_01,Minister of Education,minister,Dinesh Gunawardena,citizen,AS_APPOINTED,2020-01-15`)
+	writeGazetteFile(t, filepath.Join(peopleDir, "2020-01-15"), "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2160-02_tr_02,Minister of Defence,minister,Gotabaya Rajapaksa,citizen,AS_APPOINTED,2020-01-15`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(orgDir, "organisation", nil))
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(peopleDir, "person", nil))
+
+	diff, err := api.BuildDiff(store, "", "2020-01-01", "2020-02-01")
+	assert.NoError(t, err)
+	if diff == nil {
+		return
+	}
+
+	assert.Equal(t, []string{"Minister of Education"}, diffNames(diff.MinistersAdded))
+	assert.Empty(t, diff.MinistersTerminated)
+
+	assert.Len(t, diff.Renames, 1)
+	if len(diff.Renames) == 1 {
+		assert.Equal(t, "Minister of Health", diff.Renames[0].From.Name)
+		assert.Equal(t, "Minister of Health and Wellness", diff.Renames[0].To.Name)
+		assert.Equal(t, "2020-01-15", diff.Renames[0].Date)
+	}
+
+	assert.Len(t, diff.Merges, 1)
+	if len(diff.Merges) == 1 {
+		assert.Equal(t, []string{"Minister of Trade", "Minister of Ports"}, diffNames(diff.Merges[0].From))
+		assert.Equal(t, "Minister of Trade and Ports", diff.Merges[0].Into.Name)
+	}
+
+	// Departments handed over by the rename and the merge are not reported as moved
+	assert.Empty(t, diff.DepartmentsAdded)
+	assert.Len(t, diff.DepartmentsTerminated, 1)
+	if len(diff.DepartmentsTerminated) == 1 {
+		assert.Equal(t, "Sri Lanka Army", diff.DepartmentsTerminated[0].Entity.Name)
+		assert.Equal(t, "Minister of Defence", diff.DepartmentsTerminated[0].Minister.Name)
+	}
+	assert.Len(t, diff.DepartmentsMoved, 1)
+	if len(diff.DepartmentsMoved) == 1 {
+		assert.Equal(t, "Sri Lanka Navy", diff.DepartmentsMoved[0].Department.Name)
+		assert.Equal(t, "Minister of Defence", diff.DepartmentsMoved[0].From.Name)
+		assert.Equal(t, "Minister of Finance", diff.DepartmentsMoved[0].To.Name)
+	}
+
+	assert.Len(t, diff.PeopleAppointed, 1)
+	if len(diff.PeopleAppointed) == 1 {
+		assert.Equal(t, "Dinesh Gunawardena", diff.PeopleAppointed[0].Entity.Name)
+		assert.Equal(t, "Minister of Education", diff.PeopleAppointed[0].Minister.Name)
+	}
+	assert.Len(t, diff.PeopleRemoved, 1)
+	if len(diff.PeopleRemoved) == 1 {
+		assert.Equal(t, "Gotabaya Rajapaksa", diff.PeopleRemoved[0].Entity.Name)
+	}
+
+	var out bytes.Buffer
+	diff.Print(&out)
+	assert.True(t, strings.HasPrefix(out.String(), "Changes between 2020-01-01 and 2020-02-01\n"))
+	assert.Contains(t, out.String(), "Ministers renamed:\n  Minister of Health (2153-12_min_3) -> Minister of Health and Wellness")
+
+	// Nothing changed between two dates of the same gazette period
+	diff, err = api.BuildDiff(store, "", "2019-12-20", "2019-12-31")
+	assert.NoError(t, err)
+	assert.True(t, diff.Empty())
+
+	_, err = api.BuildDiff(store, "", "2020-02-01", "2020-01-01")
+	assert.Error(t, err)
+}
+
+func TestBuildDiffFollowsRenameChain(t *testing.T) {
+	orgDir := t.TempDir()
+	writeGazetteFile(t, filepath.Join(orgDir, "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Minister of Health,minister,Department of Hospitals,department,AS_DEPARTMENT,2019-12-10`)
+	writeGazetteFile(t, filepath.Join(orgDir, "2020-01-15"), "RENAME.csv", `transaction_id,old,new,type,date
+2160-01_tr_01,Minister of Health,Minister of Health and Wellness,AS_MINISTER,2020-01-15`)
+	writeGazetteFile(t, filepath.Join(orgDir, "2020-03-01"), "RENAME.csv", `transaction_id,old,new,type,date
+2165-02_tr_01,Minister of Health and Wellness,Minister of Health and Indigenous Medicine,AS_MINISTER,2020-03-01`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(orgDir, "organisation", nil))
+
+	// Both renames fall between the dates, so the first minister is renamed to the last
+	diff, err := api.BuildDiff(store, "", "2020-01-01", "2020-04-01")
+	assert.NoError(t, err)
+	if diff == nil {
+		return
+	}
+	if assert.Len(t, diff.Renames, 1) {
+		assert.Equal(t, "Minister of Health", diff.Renames[0].From.Name)
+		assert.Equal(t, "Minister of Health and Indigenous Medicine", diff.Renames[0].To.Name)
+		assert.Equal(t, "2020-03-01", diff.Renames[0].Date)
+	}
+	assert.Empty(t, diff.MinistersAdded)
+	assert.Empty(t, diff.MinistersTerminated)
+	assert.Empty(t, diff.DepartmentsMoved)
+
+	// Only the first rename falls between these dates
+	diff, err = api.BuildDiff(store, "", "2020-01-01", "2020-02-01")
+	assert.NoError(t, err)
+	if diff != nil && assert.Len(t, diff.Renames, 1) {
+		assert.Equal(t, "Minister of Health and Wellness", diff.Renames[0].To.Name)
+	}
+}
+
+func TestBuildDiffOfPresidencyData(t *testing.T) {
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree("../data/orgchart/gr", "organisation", &api.ProcessOptions{}))
+
+	// The state ministers related to the government with HAS_MINISTER are listed as added
+	diff, err := api.BuildDiff(store, "", "2019-12-10", "2020-01-09")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"State Minister of Education Services",
+		"State Minister of Development Banks and Loans",
+		"State Minister of Transport Services Management",
+		"State Minister of Railway Services",
+	}, diffNames(diff.MinistersAdded))
+	assert.Empty(t, diff.MinistersTerminated)
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntityCountersPersistAcrossRuns(t *testing.T) {
+	// Both folders are gazette 2153-12, so their IDs share the 2153-12 prefix
+	firstDir := t.TempDir()
+	writeGazetteFile(t, firstDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Minister of Defence,minister,Sri Lanka Army,department,AS_DEPARTMENT,2019-12-10`)
+	secondDir := t.TempDir()
+	writeGazetteFile(t, secondDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_03,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-10
+2153-12_tr_04,Minister of Health,minister,Department of Hospitals,department,AS_DEPARTMENT,2019-12-10`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+
+	// Each run loads the counters saved by the one before, as the CLI does
+	statePath := filepath.Join(t.TempDir(), "entity_counters.json")
+	for _, dataDir := range []string{firstDir, secondDir} {
+		counters, err := api.LoadEntityCounters(statePath)
+		assert.NoError(t, err)
+		opts := &api.ProcessOptions{EntityCounters: counters}
+		assert.NoError(t, memoryProcessor.ProcessTransactions(dataDir, "organisation", opts))
+		assert.NoError(t, api.SaveEntityCounters(statePath, opts.EntityCounters))
+	}
+
+	counters, err := api.LoadEntityCounters(statePath)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"minister": 2, "department": 2}, counters)
+
+	ids := map[string]string{}
+	for _, kind := range []string{"minister", "department"} {
+		results, err := store.SearchEntities(&models.SearchCriteria{Kind: &models.Kind{Major: "Organisation", Minor: kind}})
+		assert.NoError(t, err)
+		for _, result := range results {
+			ids[result.Name] = result.ID
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"Minister of Defence":     "2153-12_min_1",
+		"Minister of Health":      "2153-12_min_2",
+		"Sri Lanka Army":          "2153-12_dep_1",
+		"Department of Hospitals": "2153-12_dep_2",
+	}, ids)
+}
+
+func TestLoadEntityCounters(t *testing.T) {
+	dir := t.TempDir()
+
+	// A missing state file starts every counter at zero
+	counters, err := api.LoadEntityCounters(filepath.Join(dir, "missing.json"))
+	assert.NoError(t, err)
+	assert.Empty(t, counters)
+	assert.NotNil(t, counters)
+
+	// A corrupt state file is an error rather than a reset, which would reuse IDs
+	corruptPath := filepath.Join(dir, "corrupt.json")
+	assert.NoError(t, os.WriteFile(corruptPath, []byte(`{"minister": 3,`), 0o644))
+	_, err = api.LoadEntityCounters(corruptPath)
+	assert.ErrorContains(t, err, "failed to decode entity counters from "+corruptPath)
+
+	// Saving replaces the file and leaves no temporary files behind
+	assert.NoError(t, api.SaveEntityCounters(corruptPath, map[string]int{"minister": 3}))
+	counters, err = api.LoadEntityCounters(corruptPath)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"minister": 3}, counters)
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestEntityCountersSavedWithEveryTransaction(t *testing.T) {
+	dataDir := t.TempDir()
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Minister of Defence,minister,Sri Lanka Army,department,AS_DEPARTMENT,2019-12-10`)
+
+	store := api.NewMemoryStore()
+	_, err := api.NewProcessor(store).CreateGovernmentNode()
+	assert.NoError(t, err)
+
+	// The second transaction times out after creating the department, as if the run crashed
+	// there; the counters saved so far include the ID it used up
+	statePath := filepath.Join(t.TempDir(), "entity_counters.json")
+	timingOut := &timingOutStore{Store: store, entityID: "2153-12_min_1"}
+	err = api.NewProcessor(timingOut).ProcessTransactions(dataDir, "organisation", &api.ProcessOptions{CountersFile: statePath})
+	assert.ErrorIs(t, err, errTimeout)
+
+	counters, err := api.LoadEntityCounters(statePath)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"minister": 1, "department": 1}, counters)
+}
@@ -0,0 +1,248 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/export"
+	"orgchart_nexoan/models"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// loadExportGraph builds a small org chart in a MemoryStore: a minister with one active and one
+// terminated department
+func loadExportGraph(t *testing.T, date string) *api.Graph {
+	rootDir := t.TempDir()
+	writeGazetteFile(t, filepath.Join(rootDir, "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,"Minister of ""Special"" Affairs",minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,"Minister of ""Special"" Affairs",minister,Department of Archives,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_03,"Minister of ""Special"" Affairs",minister,Department of Records,department,AS_DEPARTMENT,2019-12-10`)
+	writeGazetteFile(t, filepath.Join(rootDir, "2020-01-15"), "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2160-01_tr_01,"Minister of ""Special"" Affairs",minister,Department of Records,department,AS_DEPARTMENT,2020-01-15`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(rootDir, "organisation", nil))
+
+	graph, err := api.LoadGraph(store, date, nil)
+	assert.NoError(t, err)
+	return graph
+}
+
+func TestLoadGraph(t *testing.T) {
+	graph := loadExportGraph(t, "")
+	assert.Len(t, graph.Nodes, 4)
+	assert.Len(t, graph.Edges, 3)
+	node, ok := graph.Node("2153-12_min_1")
+	assert.True(t, ok)
+	assert.Equal(t, `Minister of "Special" Affairs`, node.Name)
+
+	// On a date only the active relationships and the entities they reach are kept
+	graph = loadExportGraph(t, "2020-02-01")
+	assert.Equal(t, "2020-02-01", graph.Date)
+	assert.Len(t, graph.Nodes, 3)
+	assert.Len(t, graph.Edges, 2)
+	_, ok = graph.Node("2153-12_dep_2")
+	assert.False(t, ok)
+}
+
+func TestExportDOT(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, export.Write(&out, loadExportGraph(t, "2020-02-01"), "dot", export.Options{}))
+	assert.Equal(t, `digraph orgchart {
+  rankdir=LR;
+  node [shape=box];
+  label="Org chart on 2020-02-01";
+  "gov_01" [label="Government of Sri Lanka", class="government"];
+  "2153-12_min_1" [label="Minister of \"Special\" Affairs", class="minister"];
+  "2153-12_dep_1" [label="Department of Archives", class="department"];
+  "gov_01" -> "2153-12_min_1" [label="AS_MINISTER\n2019-12-10 to present"];
+  "2153-12_min_1" -> "2153-12_dep_1" [label="AS_DEPARTMENT\n2019-12-10 to present"];
+}
+`, out.String())
+}
+
+func TestExportMermaid(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, export.Write(&out, loadExportGraph(t, ""), "mermaid", export.Options{}))
+	assert.Equal(t, `flowchart LR
+  n0["Government of Sri Lanka"]
+  n1["Minister of #quot;Special#quot; Affairs"]
+  n2["Department of Archives"]
+  n3["Department of Records"]
+  n0 -->|"AS_MINISTER 2019-12-10 to present"| n1
+  n1 -->|"AS_DEPARTMENT 2019-12-10 to present"| n2
+  n1 -->|"AS_DEPARTMENT 2019-12-10 to 2020-01-15"| n3
+`, out.String())
+}
+
+func TestExportGraphML(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, export.Write(&out, loadExportGraph(t, ""), "graphml", export.Options{}))
+
+	// Read the document back to check it is well formed
+	var document struct {
+		Keys []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"key"`
+		Graph struct {
+			Nodes []struct {
+				ID   string `xml:"id,attr"`
+				Data []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"node"`
+			Edges []struct {
+				ID     string `xml:"id,attr"`
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+				Data   []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	assert.NoError(t, xml.Unmarshal(out.Bytes(), &document))
+	assert.Len(t, document.Keys, 6)
+	assert.Len(t, document.Graph.Nodes, 4)
+	assert.Len(t, document.Graph.Edges, 3)
+	if len(document.Graph.Nodes) == 4 && len(document.Graph.Edges) == 3 {
+		assert.Equal(t, "2153-12_min_1", document.Graph.Nodes[1].ID)
+		assert.Equal(t, `Minister of "Special" Affairs`, document.Graph.Nodes[1].Data[0].Value)
+		terminated := document.Graph.Edges[2]
+		assert.Equal(t, "2153-12_min_1_2153-12_dep_2_2019-12-10", terminated.ID)
+		assert.Equal(t, "2153-12_dep_2", terminated.Target)
+		assert.Len(t, terminated.Data, 3)
+		assert.Equal(t, "2020-01-15", terminated.Data[2].Value)
+	}
+}
+
+// recurringGraph holds a department that was moved away from a minister and back, so Nexoan has
+// two relationships with the same ID, and a third with that ID starting on the same day
+func recurringGraph() *api.Graph {
+	return &api.Graph{
+		Nodes: []api.GraphNode{
+			{ID: "2153-12_min_1", Name: "Minister of Defence", Kind: models.Kind{Major: "Organisation", Minor: "minister"}},
+			{ID: "2153-12_dep_1", Name: "Sri Lanka Army", Kind: models.Kind{Major: "Organisation", Minor: "department"}},
+		},
+		Edges: []api.GraphEdge{
+			{ID: "2153-12_min_1_2153-12_dep_1", From: "2153-12_min_1", To: "2153-12_dep_1", Name: "AS_DEPARTMENT", StartTime: "2019-12-10T00:00:00Z", EndTime: "2020-01-15T00:00:00Z"},
+			{ID: "2153-12_min_1_2153-12_dep_1", From: "2153-12_min_1", To: "2153-12_dep_1", Name: "AS_DEPARTMENT", StartTime: "2021-03-01T00:00:00Z", EndTime: "2021-03-01T00:00:00Z"},
+			{ID: "2153-12_min_1_2153-12_dep_1", From: "2153-12_min_1", To: "2153-12_dep_1", Name: "AS_DEPARTMENT", StartTime: "2021-03-01T00:00:00Z"},
+		},
+	}
+}
+
+func TestExportGraphMLRecurringRelationship(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, export.Write(&out, recurringGraph(), "graphml", export.Options{}))
+
+	var document struct {
+		Graph struct {
+			Edges []struct {
+				ID string `xml:"id,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	assert.NoError(t, xml.Unmarshal(out.Bytes(), &document))
+	ids := []string{}
+	for _, edge := range document.Graph.Edges {
+		ids = append(ids, edge.ID)
+	}
+	assert.Equal(t, []string{
+		"2153-12_min_1_2153-12_dep_1_2019-12-10",
+		"2153-12_min_1_2153-12_dep_1_2021-03-01",
+		"2153-12_min_1_2153-12_dep_1_2021-03-01_2",
+	}, ids)
+}
+
+func TestExportTurtle(t *testing.T) {
+	var out bytes.Buffer
+	opts := export.Options{BaseURI: "https://data.example.org/orgchart"}
+	assert.NoError(t, export.Write(&out, loadExportGraph(t, "2020-02-01"), "turtle", opts))
+	turtle := out.String()
+
+	assert.Contains(t, turtle, "@prefix oc: <https://data.example.org/orgchart/ontology/> .\n")
+	assert.Contains(t, turtle, "oc:Minister a rdfs:Class ;\n    rdfs:subClassOf oc:Organisation .\n")
+	assert.Contains(t, turtle, `<https://data.example.org/orgchart/entity/2153-12_min_1> a oc:Organisation, oc:Minister ;
+    rdfs:label "Minister of \"Special\" Affairs" ;
+    oc:entityId "2153-12_min_1" ;
+    oc:created "2019-12-10T00:00:00Z"^^xsd:dateTime .
+`)
+	assert.Contains(t, turtle, `<https://data.example.org/orgchart/relationship/gov_01_2153-12_min_1_2019-12-10> a oc:Relationship ;
+    oc:relationshipType "AS_MINISTER" ;
+    oc:source <https://data.example.org/orgchart/entity/gov_01> ;
+    oc:target <https://data.example.org/orgchart/entity/2153-12_min_1> ;
+    oc:startDate "2019-12-10"^^xsd:date .
+`)
+	assert.NotContains(t, turtle, "2153-12_dep_2")
+}
+
+func TestExportJSONLD(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, export.Write(&out, loadExportGraph(t, ""), "jsonld", export.Options{}))
+
+	var document struct {
+		Context map[string]interface{}   `json:"@context"`
+		Graph   []map[string]interface{} `json:"@graph"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &document))
+	assert.Equal(t, export.DefaultBaseURI+"ontology/", document.Context["oc"])
+
+	// Four classes, four entities and three relationships
+	assert.Len(t, document.Graph, 11)
+	items := map[string]map[string]interface{}{}
+	for _, item := range document.Graph {
+		items[item["@id"].(string)] = item
+	}
+
+	minister := items[export.DefaultBaseURI+"entity/2153-12_min_1"]
+	assert.Equal(t, `Minister of "Special" Affairs`, minister["label"])
+	assert.Equal(t, []interface{}{"oc:Organisation", "oc:Minister"}, minister["@type"])
+
+	terminated := items[export.DefaultBaseURI+"relationship/2153-12_min_1_2153-12_dep_2_2019-12-10"]
+	assert.Equal(t, "oc:Relationship", terminated["@type"])
+	assert.Equal(t, "AS_DEPARTMENT", terminated["relationshipType"])
+	assert.Equal(t, export.DefaultBaseURI+"entity/2153-12_min_1", terminated["source"])
+	assert.Equal(t, "2019-12-10", terminated["startDate"])
+	assert.Equal(t, "2020-01-15", terminated["endDate"])
+}
+
+func TestExportLinkedDataRecurringRelationship(t *testing.T) {
+	prefix := export.DefaultBaseURI + "relationship/2153-12_min_1_2153-12_dep_1_"
+	want := []string{prefix + "2019-12-10", prefix + "2021-03-01", prefix + "2021-03-01_2"}
+
+	var out bytes.Buffer
+	assert.NoError(t, export.Write(&out, recurringGraph(), "jsonld", export.Options{}))
+	var document struct {
+		Graph []map[string]interface{} `json:"@graph"`
+	}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &document))
+	ids := []string{}
+	for _, item := range document.Graph {
+		if item["@type"] == "oc:Relationship" {
+			ids = append(ids, item["@id"].(string))
+		}
+	}
+	assert.Equal(t, want, ids)
+
+	out.Reset()
+	assert.NoError(t, export.Write(&out, recurringGraph(), "turtle", export.Options{}))
+	for _, id := range want {
+		assert.Equal(t, 1, strings.Count(out.String(), "<"+id+"> a oc:Relationship"), id)
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	var out bytes.Buffer
+	assert.Error(t, export.Write(&out, &api.Graph{}, "svg", export.Options{}))
+}
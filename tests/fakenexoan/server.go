@@ -0,0 +1,260 @@
+// Package fakenexoan provides an in-memory fake of the Nexoan Update and Query APIs for tests.
+// It serves the endpoints used by api.Client over an api.MemoryStore and reproduces the response
+// quirks of the real server: names in search results are protobuf objects with a hex encoded
+// value, and the relations endpoints return a bare JSON array instead of a {"body": ...} wrapper.
+//
+// Usage:
+//
+//	server := httptest.NewServer(fakenexoan.New())
+//	defer server.Close()
+//	client := api.NewClient(server.URL+"/entities", server.URL+"/v1/entities")
+package fakenexoan
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+)
+
+// Server is an http.Handler serving the entities and relationships of an api.MemoryStore
+type Server struct {
+	mu    sync.Mutex
+	mux   *http.ServeMux
+	store *api.MemoryStore
+}
+
+// New creates an empty fake Nexoan server
+func New() *Server {
+	s := &Server{
+		mux:   http.NewServeMux(),
+		store: api.NewMemoryStore(),
+	}
+
+	// Update API
+	s.mux.HandleFunc("POST /entities", s.createEntity)
+	s.mux.HandleFunc("PUT /entities/{id}", s.updateEntity)
+	s.mux.HandleFunc("DELETE /entities/{id}", s.deleteEntity)
+
+	// Query API
+	s.mux.HandleFunc("POST /v1/entities/search", s.searchEntities)
+	s.mux.HandleFunc("GET /v1/entities/root", s.rootEntities)
+	s.mux.HandleFunc("POST /v1/entities/{id}/relations", s.relations)
+	s.mux.HandleFunc("POST /v1/entities/{id}/allrelations", s.allRelations)
+	s.mux.HandleFunc("GET /v1/entities/{id}/metadata", s.metadata)
+	s.mux.HandleFunc("GET /v1/entities/{id}/attributes/{name}", s.attribute)
+
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mux.ServeHTTP(w, r)
+}
+
+// Store returns the store holding the entities of the server
+func (s *Server) Store() *api.MemoryStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store
+}
+
+// Reset removes every entity and relationship
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = api.NewMemoryStore()
+}
+
+// writeJSON writes value as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeError writes an error response with the status code matching a store error
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, api.ErrEntityNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, api.ErrDuplicateEntity):
+		status = http.StatusConflict
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// decode reads a JSON request body into value, writing a 400 if it is invalid
+func decode(w http.ResponseWriter, r *http.Request, value interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(value); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// decodeQuery is decode for the bodies of Query API requests. Fields the real server does not
+// filter on are rejected, so that a query relying on them fails instead of being answered with
+// results the real server would not return.
+func decodeQuery(w http.ResponseWriter, r *http.Request, value interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		http.Error(w, fmt.Sprintf("invalid query: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func (s *Server) createEntity(w http.ResponseWriter, r *http.Request) {
+	var entity models.Entity
+	if !decode(w, r, &entity) {
+		return
+	}
+
+	created, err := s.store.CreateEntity(&entity)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) updateEntity(w http.ResponseWriter, r *http.Request) {
+	var entity models.Entity
+	if !decode(w, r, &entity) {
+		return
+	}
+
+	updated, err := s.store.UpdateEntity(r.PathValue("id"), &entity)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (s *Server) deleteEntity(w http.ResponseWriter, r *http.Request) {
+	if err := s.store.DeleteEntity(r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// encodeName encodes a name the way the real server returns it in search results
+func encodeName(name string) string {
+	encoded, _ := json.Marshal(map[string]string{
+		"typeUrl": "type.googleapis.com/google.protobuf.StringValue",
+		"value":   hex.EncodeToString([]byte(name)),
+	})
+	return string(encoded)
+}
+
+func (s *Server) searchEntities(w http.ResponseWriter, r *http.Request) {
+	var criteria models.SearchCriteria
+	if !decodeQuery(w, r, &criteria) {
+		return
+	}
+
+	results, err := s.store.SearchEntities(&criteria)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	for i := range results {
+		results[i].Name = encodeName(results[i].Name)
+	}
+	writeJSON(w, http.StatusOK, models.SearchResponse{Body: results})
+}
+
+func (s *Server) rootEntities(w http.ResponseWriter, r *http.Request) {
+	roots, err := s.store.GetRootEntities(r.URL.Query().Get("kind"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.RootEntitiesResponse{Body: roots})
+}
+
+func (s *Server) relations(w http.ResponseWriter, r *http.Request) {
+	var query models.Relationship
+	if !decodeQuery(w, r, &query) {
+		return
+	}
+
+	// The relations endpoints return a bare array
+	relations, err := s.store.GetRelatedEntities(r.PathValue("id"), &query)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, relations)
+}
+
+func (s *Server) allRelations(w http.ResponseWriter, r *http.Request) {
+	relations, err := s.store.GetAllRelatedEntities(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, relations)
+}
+
+// entity returns the entity named by the {id} path value, writing a 404 if it does not exist
+func (s *Server) entity(w http.ResponseWriter, r *http.Request) (models.Entity, bool) {
+	id := r.PathValue("id")
+	entity, exists := s.store.Entity(id)
+	if !exists {
+		writeError(w, fmt.Errorf("%w: %s", api.ErrEntityNotFound, id))
+		return models.Entity{}, false
+	}
+	return entity, true
+}
+
+func (s *Server) metadata(w http.ResponseWriter, r *http.Request) {
+	metadata, err := s.store.GetEntityMetadata(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, metadata)
+}
+
+func (s *Server) attribute(w http.ResponseWriter, r *http.Request) {
+	stored, ok := s.entity(w, r)
+	if !ok {
+		return
+	}
+
+	name := r.PathValue("name")
+	startTime := r.URL.Query().Get("startTime")
+	endTime := r.URL.Query().Get("endTime")
+
+	values := []models.TimeBasedValue{}
+	for _, entry := range stored.Attributes {
+		if entry.Key != name {
+			continue
+		}
+		for _, value := range entry.Value.Values {
+			// Keep the values that overlap the requested time range
+			if startTime != "" && value.EndTime != "" && value.EndTime <= startTime {
+				continue
+			}
+			if endTime != "" && value.StartTime > endTime {
+				continue
+			}
+			values = append(values, value)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, values)
+}
@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"orgchart_nexoan/api"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gazetteFiles maps the relative path of every file under dir to its content
+func gazetteFiles(t *testing.T, dir string) map[string]string {
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[rel] = string(content)
+		return nil
+	})
+	assert.NoError(t, err)
+	return files
+}
+
+func TestReconstructGazettesRoundTrip(t *testing.T) {
+	orgDir := t.TempDir()
+	first := filepath.Join(orgDir, "2019-12-10")
+	writeGazetteFile(t, first, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-10
+2153-12_tr_03,Government of Sri Lanka,government,Minister of Trade,minister,AS_MINISTER,2019-12-10
+2153-12_tr_04,Government of Sri Lanka,government,"Minister of Ports, Shipping",minister,AS_MINISTER,2019-12-10
+2153-12_tr_05,Minister of Defence,minister,Sri Lanka Army,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_06,Minister of Defence,minister,Sri Lanka Navy,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_07,Minister of Health,minister,Department of Hospitals,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_08,Minister of Trade,minister,Department of Exports,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_09,"Minister of Ports, Shipping",minister,Department of Harbours,department,AS_DEPARTMENT,2019-12-10`)
+	writeGazetteFile(t, first, "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date`)
+	writeGazetteFile(t, first, "MOVE.csv", `transaction_id,old_parent,new_parent,child,type,date`)
+	writeGazetteFile(t, first, "RENAME.csv", `transaction_id,old,new,type,date`)
+	writeGazetteFile(t, first, "MERGE.csv", `transaction_id,old,new,type,date`)
+
+	second := filepath.Join(orgDir, "2020-01-15")
+	writeGazetteFile(t, second, "RENAME.csv", `transaction_id,old,new,type,date
+2160-01_tr_01,Minister of Health,Minister of Health and Wellness ,minister,2020-01-15`)
+	writeGazetteFile(t, second, "MERGE.csv", `transaction_id,old,new,type,date
+2160-01_tr_02,"[""Minister of Trade"", ""Minister of Ports, Shipping""]",Minister of Trade and Ports,minister,2020-01-15`)
+	writeGazetteFile(t, second, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2160-01_tr_03,Government of Sri Lanka,government,Minister of Education,minister,AS_MINISTER,2020-01-15`)
+	writeGazetteFile(t, second, "MOVE.csv", `transaction_id,old_parent,new_parent,child,type,date
+2160-01_tr_04,Minister of Defence,Minister of Education,Sri Lanka Navy,department,2020-01-15`)
+	writeGazetteFile(t, second, "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2160-01_tr_05,Minister of Defence,minister,Sri Lanka Army,department,AS_DEPARTMENT,2020-01-15`)
+
+	peopleDir := t.TempDir()
+	writeGazetteFile(t, filepath.Join(peopleDir, "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-13_tr_01,Minister of Defence,minister,Gotabaya Rajapaksa,citizen,AS_APPOINTED,2019-12-10
+2153-13_tr_02,Minister of Health,minister,Mahinda Rajapaksa,citizen,AS_APPOINTED,2019-12-10`)
+	writeGazetteFile(t, filepath.Join(peopleDir, "2019-12-10"), "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date`)
+	writeGazetteFile(t, filepath.Join(peopleDir, "2019-12-10"), "MOVE.csv", `transaction_id,old_parent,new_parent,child,type,date`)
+	writeGazetteFile(t, filepath.Join(peopleDir, "2020-01-15"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2160-02_tr_01,Minister of Education,minister,Dinesh Gunawardena,citizen,AS_APPOINTED,2020-01-15`)
+	writeGazetteFile(t, filepath.Join(peopleDir, "2020-01-15"), "MOVE.csv", `transaction_id,old_parent,new_parent,child,type,date
+2160-02_tr_02,Minister of Defence,Minister of Education,Gotabaya Rajapaksa,AS_APPOINTED,2020-01-15`)
+	writeGazetteFile(t, filepath.Join(peopleDir, "2020-01-15"), "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2160-02_tr_03,Minister of Health,minister,Mahinda Rajapaksa,citizen,AS_APPOINTED,2020-01-15`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(orgDir, "organisation", nil))
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(peopleDir, "person", nil))
+
+	graph, err := api.LoadGraph(store, "", nil)
+	assert.NoError(t, err)
+
+	// The rebuilt folders are identical to the ones they were loaded from
+	for processType, sourceDir := range map[string]string{"organisation": orgDir, "person": peopleDir} {
+		folders, err := api.ReconstructGazettes(graph, processType)
+		assert.NoError(t, err)
+		assert.NoError(t, api.MatchGazetteFileSet(folders, sourceDir, processType))
+		exportDir := t.TempDir()
+		assert.NoError(t, api.WriteGazetteFolders(exportDir, processType, folders, false))
+		assert.Equal(t, gazetteFiles(t, sourceDir), gazetteFiles(t, exportDir), processType)
+	}
+
+	// Without a source to match, only the files with rows are written
+	folders, err := api.ReconstructGazettes(graph, "organisation")
+	assert.NoError(t, err)
+	exportDir := t.TempDir()
+	assert.NoError(t, api.WriteGazetteFolders(exportDir, "organisation", folders, false))
+	_, err = os.Stat(filepath.Join(exportDir, "2019-12-10", "RENAME.csv"))
+	assert.True(t, os.IsNotExist(err))
+
+	// A graph loaded for a date lacks the history needed to rebuild the gazettes
+	dated, err := api.LoadGraph(store, "2020-01-01", nil)
+	assert.NoError(t, err)
+	_, err = api.ReconstructGazettes(dated, "organisation")
+	assert.Error(t, err)
+}
+
+func TestReconstructGazettesOfRepositoryData(t *testing.T) {
+	sourceDir := "../data/gota_gazettes"
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	journal, err := api.OpenJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+	assert.NoError(t, err)
+	defer journal.Close()
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(sourceDir, "organisation", &api.ProcessOptions{Journal: journal}))
+
+	graph, err := api.LoadGraph(store, "", journal)
+	assert.NoError(t, err)
+	folders, err := api.ReconstructGazettes(graph, "organisation")
+	assert.NoError(t, err)
+	assert.NoError(t, api.MatchGazetteFileSet(folders, sourceDir, "organisation"))
+
+	// The rebuilt files keep the transaction IDs and names of the source byte for byte; the folders
+	// of data/gota_gazettes differ in their line endings
+	source := gazetteFiles(t, sourceDir)
+	for _, useCRLF := range []bool{false, true} {
+		exportDir := t.TempDir()
+		assert.NoError(t, api.WriteGazetteFolders(exportDir, "organisation", folders, useCRLF))
+		rebuilt := gazetteFiles(t, exportDir)
+		assert.ElementsMatch(t, mapKeys(source), mapKeys(rebuilt))
+		folder := "2020-01-01"
+		if useCRLF {
+			folder = "2019-12-10"
+		}
+		for path, content := range source {
+			if filepath.Dir(path) == folder {
+				assert.Equal(t, content, rebuilt[path], path)
+			}
+		}
+	}
+}
+
+// mapKeys returns the keys of a map in no particular order
+func mapKeys(m map[string]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
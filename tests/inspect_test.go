@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"orgchart_nexoan/tests/fakenexoan"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspectEntities(t *testing.T) {
+	server := httptest.NewServer(fakenexoan.New())
+	defer server.Close()
+	inspectClient := api.NewClient(server.URL+"/entities", server.URL+"/v1/entities")
+	inspectProcessor := api.NewProcessor(inspectClient)
+	_, err := inspectProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+
+	dataDir := t.TempDir()
+	writeGazetteFile(t, filepath.Join(dataDir, "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Minister of Health,minister,Department of Hospitals,department,AS_DEPARTMENT,2019-12-10`)
+	writeGazetteFile(t, filepath.Join(dataDir, "2020-01-15"), "RENAME.csv", `transaction_id,old,new,type,date
+2160-01_tr_01,Minister of Health,Minister of Health and Wellness,minister,2020-01-15`)
+	assert.NoError(t, inspectProcessor.ProcessTransactionTree(dataDir, "organisation", &api.ProcessOptions{}))
+
+	healthID := entityID(t, inspectClient, "Minister of Health")
+	_, err = inspectClient.UpdateEntity(healthID, &models.Entity{
+		ID:       healthID,
+		Metadata: []models.MetadataEntry{{Key: "gazette", Value: "2153/12"}},
+	})
+	assert.NoError(t, err)
+
+	inspections, err := api.InspectEntities(inspectClient, "Minister of Health", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	if !assert.Len(t, inspections, 1) {
+		return
+	}
+	inspection := inspections[0]
+	assert.Equal(t, healthID, inspection.ID)
+	assert.Equal(t, "Organisation/minister", inspection.Kind)
+	assert.Equal(t, "2019-12-10", inspection.Created)
+	assert.Equal(t, "", inspection.Terminated)
+	assert.Equal(t, map[string]interface{}{"gazette": "2153/12"}, inspection.Metadata)
+
+	// The government and the department were handed to the new minister, so only the rename is active
+	wellnessID := entityID(t, inspectClient, "Minister of Health and Wellness")
+	if assert.Len(t, inspection.Active, 1) {
+		assert.Equal(t, api.InspectedRelationship{
+			ID:          inspection.Active[0].ID,
+			Name:        "RENAMED_TO",
+			Direction:   api.DirectionOutgoing,
+			RelatedID:   wellnessID,
+			RelatedKind: "Organisation/minister",
+			RelatedName: "Minister of Health and Wellness",
+			StartDate:   "2020-01-15",
+		}, inspection.Active[0])
+	}
+	names := []string{}
+	for _, rel := range inspection.Ended {
+		names = append(names, rel.Direction+" "+rel.Name+" "+rel.RelatedName)
+	}
+	assert.ElementsMatch(t, []string{
+		"INCOMING AS_MINISTER Government of Sri Lanka",
+		"OUTGOING AS_DEPARTMENT Department of Hospitals",
+	}, names)
+
+	var out bytes.Buffer
+	inspection.Print(&out)
+	assert.Contains(t, out.String(), "Organisation/minister \"Minister of Health\" ("+healthID+")\n  Created:    2019-12-10\n  Terminated: present\n")
+	assert.Contains(t, out.String(), "  Metadata:\n    gazette: 2153/12\n")
+	assert.Contains(t, out.String(), "  Active relationships (1):\n    -> RENAMED_TO Organisation/minister \"Minister of Health and Wellness\"")
+	assert.Contains(t, out.String(), "    <- AS_MINISTER Organisation/government \"Government of Sri Lanka\" (gov_01), 2019-12-10 to 2020-01-15\n")
+
+	// The new minister is found by ID; it holds the government, the department and the rename
+	inspections, err = api.InspectEntities(inspectClient, wellnessID, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	if assert.Len(t, inspections, 1) {
+		assert.Len(t, inspections[0].Active, 3)
+		assert.Empty(t, inspections[0].Ended)
+	}
+
+	// Relationships ending after the given time are still active
+	inspections, err = api.InspectEntities(inspectClient, healthID, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	if assert.Len(t, inspections, 1) {
+		assert.Len(t, inspections[0].Active, 3)
+	}
+
+	_, err = api.InspectEntities(inspectClient, "Minister of Nothing", time.Now())
+	assert.ErrorContains(t, err, `no entity found with ID or name "Minister of Nothing"`)
+}
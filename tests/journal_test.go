@@ -0,0 +1,225 @@
+package tests
+
+import (
+	"errors"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalRecordsAppliedTransactions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	journal, err := api.OpenJournal(path)
+	assert.NoError(t, err)
+	assert.False(t, journal.IsApplied("organisation", "data/orgchart/gr/2019-12-10", "2153-12_tr_01"))
+
+	err = journal.Record(api.JournalEntry{
+		TransactionID: "2153-12_tr_01",
+		FileType:      "ADD",
+		ProcessType:   "organisation",
+		DataDir:       "data/orgchart/gr/2019-12-10",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, journal.Close())
+
+	// Simulate a crash while the next entry was being written
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.NoError(t, err)
+	_, err = file.WriteString(`{"transaction_id":"2153-12_tr_0`)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	// The applied transaction survives reopening and the truncated line is ignored
+	journal, err = api.OpenJournal(path)
+	assert.NoError(t, err)
+	defer journal.Close()
+	assert.Len(t, journal.Entries(), 1)
+	assert.True(t, journal.IsApplied("organisation", "data/orgchart/gr/2019-12-10", "2153-12_tr_01"))
+	assert.False(t, journal.IsApplied("person", "data/orgchart/gr/2019-12-10", "2153-12_tr_01"))
+	assert.False(t, journal.IsApplied("organisation", "data/orgchart/gr/2019-12-10", "2153-12_tr_02"))
+	// Other data trees reuse the transaction IDs
+	assert.False(t, journal.IsApplied("organisation", "data/gota_gazettes/2019-12-10", "2153-12_tr_01"))
+
+	// New entries are not appended to the truncated line
+	err = journal.Record(api.JournalEntry{TransactionID: "2153-12_tr_02", FileType: "ADD", ProcessType: "organisation"})
+	assert.NoError(t, err)
+	reopened, err := api.OpenJournal(path)
+	assert.NoError(t, err)
+	defer reopened.Close()
+	assert.Len(t, reopened.Entries(), 2)
+	assert.True(t, reopened.IsApplied("organisation", "", "2153-12_tr_02"))
+}
+
+func TestJournalRemoveEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	journal, err := api.OpenJournal(path)
+	assert.NoError(t, err)
+	defer journal.Close()
+
+	first := api.JournalEntry{
+		TransactionID: "2153-12_tr_01",
+		FileType:      "ADD",
+		ProcessType:   "organisation",
+		Changes: []api.Change{
+			{Operation: api.ChangeCreateEntity, EntityID: "2153-12_min_1"},
+			{Operation: api.ChangeAddRelationship, EntityID: "gov_01", RelationshipID: "gov_01_2153-12_min_1", RelatedEntityID: "2153-12_min_1", Name: "AS_MINISTER", StartTime: "2019-12-10T00:00:00Z"},
+		},
+	}
+	second := api.JournalEntry{TransactionID: "2153-12_tr_02", FileType: "ADD", ProcessType: "organisation", Changes: []api.Change{}}
+	assert.NoError(t, journal.Record(first))
+	assert.NoError(t, journal.Record(second))
+
+	// A file named like the old fixed temporary file is left alone
+	strayPath := path + ".tmp"
+	assert.NoError(t, os.WriteFile(strayPath, []byte("not the journal\n"), 0o644))
+
+	// Removing an entry rewrites the file, and later entries are still appended to it
+	assert.NoError(t, journal.Remove([]int{1}))
+	stray, err := os.ReadFile(strayPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "not the journal\n", string(stray))
+	files, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, files, 2, "No temporary files should be left behind")
+	assert.False(t, journal.IsApplied("organisation", "", "2153-12_tr_02"))
+	assert.NoError(t, journal.Record(api.JournalEntry{TransactionID: "2153-12_tr_03", FileType: "ADD", ProcessType: "organisation"}))
+
+	reopened, err := api.OpenJournal(path)
+	assert.NoError(t, err)
+	defer reopened.Close()
+	entries := reopened.Entries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, first.Changes, entries[0].Changes)
+	assert.Equal(t, "2153-12_tr_03", entries[1].TransactionID)
+	assert.False(t, reopened.IsApplied("organisation", "", "2153-12_tr_02"))
+}
+
+func TestJournalRemoveKeepsOtherEntriesOfTheTransaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	journal, err := api.OpenJournal(path)
+	assert.NoError(t, err)
+	defer journal.Close()
+
+	// A failed attempt and the retry that applied the transaction share its key
+	failed := api.JournalEntry{TransactionID: "2153-12_tr_01", FileType: "ADD", ProcessType: "organisation", Failed: true, Error: "request timed out",
+		Changes: []api.Change{{Operation: api.ChangeCreateEntity, EntityID: "2153-12_min_1"}}}
+	applied := api.JournalEntry{TransactionID: "2153-12_tr_01", FileType: "ADD", ProcessType: "organisation",
+		Changes: []api.Change{{Operation: api.ChangeCreateEntity, EntityID: "2153-12_min_2"}}}
+	assert.NoError(t, journal.Record(failed))
+	assert.NoError(t, journal.Record(applied))
+
+	// Removing the applied entry keeps the failed attempt
+	assert.NoError(t, journal.Remove([]int{1}))
+	assert.False(t, journal.IsApplied("organisation", "", "2153-12_tr_01"))
+	assert.Equal(t, []api.JournalEntry{failed}, journal.FailedAttempts("organisation", "", "2153-12_tr_01"))
+
+	reopened, err := api.OpenJournal(path)
+	assert.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, []api.JournalEntry{failed}, reopened.Entries())
+}
+
+func TestResumeSkipsTransactionsOfTheSameDataDirectory(t *testing.T) {
+	// Like data/gota_gazettes and data/orgchart/gr, the two trees use the same transaction IDs
+	firstDir := filepath.Join(t.TempDir(), "2019-12-10")
+	writeGazetteFile(t, firstDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10`)
+	secondDir := filepath.Join(t.TempDir(), "2019-12-10")
+	writeGazetteFile(t, secondDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-10`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+
+	journal, err := api.OpenJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+	assert.NoError(t, err)
+	defer journal.Close()
+	opts := &api.ProcessOptions{Journal: journal, Resume: true}
+	assert.NoError(t, memoryProcessor.ProcessTransactions(firstDir, "organisation", opts))
+	assert.NoError(t, memoryProcessor.ProcessTransactions(secondDir, "organisation", opts))
+	// Resuming the first folder again applies nothing
+	assert.NoError(t, memoryProcessor.ProcessTransactions(firstDir, "organisation", opts))
+
+	assert.Len(t, journal.Entries(), 2)
+	assert.Equal(t, []string{"Minister of Defence", "Minister of Health"}, ministersInCreationOrder(t, store))
+}
+
+func TestResumeAfterTransactionFailedPartway(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "2019-12-10")
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+
+	journal, err := api.OpenJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+	assert.NoError(t, err)
+	defer journal.Close()
+
+	// The minister is created, but relating it to the government times out
+	timingOut := &timingOutStore{Store: store, entityID: api.DefaultGovernmentID}
+	opts := &api.ProcessOptions{Journal: journal}
+	err = api.NewProcessor(timingOut).ProcessTransactions(dataDir, "organisation", opts)
+	assert.ErrorIs(t, err, errTimeout)
+
+	// The ID of the orphaned minister is used up and its creation is journaled
+	assert.Equal(t, 1, opts.EntityCounters["minister"])
+	assert.False(t, journal.IsApplied("organisation", dataDir, "2153-12_tr_01"))
+	attempts := journal.FailedAttempts("organisation", dataDir, "2153-12_tr_01")
+	if assert.Len(t, attempts, 1) {
+		assert.Equal(t, []api.Change{{Operation: api.ChangeCreateEntity, EntityID: "2153-12_min_1"}}, attempts[0].Changes)
+		assert.Contains(t, attempts[0].Error, errTimeout.Error())
+	}
+
+	// Resuming retries the transaction with the next ID
+	opts.Resume = true
+	assert.NoError(t, memoryProcessor.ProcessTransactions(dataDir, "organisation", opts))
+	assert.True(t, journal.IsApplied("organisation", dataDir, "2153-12_tr_01"))
+	results, err := store.SearchEntities(&models.SearchCriteria{Name: "Minister of Defence"})
+	assert.NoError(t, err)
+	ids := []string{}
+	for _, result := range results {
+		ids = append(ids, result.ID)
+	}
+	assert.Equal(t, []string{"2153-12_min_1", "2153-12_min_2"}, ids)
+
+	// Undoing the folder also deletes the orphaned minister
+	undone, err := memoryProcessor.UndoTransactions(journal, func(entry api.JournalEntry) bool { return entry.DataDir == dataDir })
+	assert.NoError(t, err)
+	assert.Len(t, undone, 2)
+	if assert.Len(t, journal.Entries(), 1) {
+		assert.True(t, journal.Entries()[0].Undo)
+	}
+	results, err = store.SearchEntities(&models.SearchCriteria{Kind: &models.Kind{Major: "Organisation", Minor: "minister"}})
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+var errTimeout = errors.New("request timed out")
+
+// timingOutStore fails the first update of an entity without applying it, like a request that
+// times out
+type timingOutStore struct {
+	api.Store
+	entityID string
+	failed   bool
+}
+
+func (s *timingOutStore) UpdateEntity(id string, entity *models.Entity) (*models.Entity, error) {
+	if id == s.entityID && !s.failed {
+		s.failed = true
+		return nil, errTimeout
+	}
+	return s.Store.UpdateEntity(id, entity)
+}
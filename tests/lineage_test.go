@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"bytes"
+	"orgchart_nexoan/api"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildLineage(t *testing.T) {
+	rootDir := t.TempDir()
+	writeGazetteFile(t, filepath.Join(rootDir, "2015-01-12"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+1897-15_tr_01,Government of Sri Lanka,government,Minister of Irrigation and Water Resources Management,minister,AS_MINISTER,2015-01-12
+1897-15_tr_02,Government of Sri Lanka,government,Minister of Lineage Fisheries,minister,AS_MINISTER,2015-01-12`)
+	writeGazetteFile(t, filepath.Join(rootDir, "2018-05-01"), "RENAME.csv", `transaction_id,old,new,type,date
+2069-37_tr_01,Minister of Irrigation and Water Resources Management,Minister of Lineage Irrigation,AS_MINISTER,2018-05-01`)
+	writeGazetteFile(t, filepath.Join(rootDir, "2020-08-12"), "MERGE.csv", `transaction_id,old,new,type,date
+2189-10_tr_01,"[Minister of Lineage Irrigation, Minister of Lineage Fisheries]",Minister of Lineage Water and Fisheries,AS_MINISTER,2020-08-12`)
+	assert.NoError(t, processor.ProcessTransactionTree(rootDir, "organisation", nil))
+
+	// The renamed minister has the original ministry before it and the merged ministry after it
+	lineage, err := api.BuildLineage(client, "Minister of Lineage Irrigation")
+	assert.NoError(t, err)
+	if lineage == nil {
+		return
+	}
+	assert.Len(t, lineage.Ministers, 1)
+	assert.Len(t, lineage.Predecessors, 1)
+	if len(lineage.Predecessors) == 1 {
+		assert.Equal(t, "Minister of Irrigation and Water Resources Management", lineage.Predecessors[0].From.Name)
+		assert.Equal(t, "RENAMED_TO", lineage.Predecessors[0].Relationship)
+		assert.Equal(t, "2018-05-01", lineage.Predecessors[0].Date)
+	}
+	assert.Len(t, lineage.Successors, 1)
+	if len(lineage.Successors) == 1 {
+		assert.Equal(t, "Minister of Lineage Water and Fisheries", lineage.Successors[0].To.Name)
+		assert.Equal(t, "MERGED_INTO", lineage.Successors[0].Relationship)
+		assert.Equal(t, "2020-08-12", lineage.Successors[0].Date)
+	}
+
+	// The original ministry leads through the rename to the merge
+	lineage, err = api.BuildLineage(client, "Minister of Irrigation and Water Resources Management")
+	assert.NoError(t, err)
+	if lineage == nil {
+		return
+	}
+	assert.Empty(t, lineage.Predecessors)
+	assert.Len(t, lineage.Successors, 2)
+	if len(lineage.Successors) == 2 {
+		assert.Equal(t, "Minister of Lineage Irrigation", lineage.Successors[0].To.Name)
+		assert.Equal(t, "Minister of Lineage Water and Fisheries", lineage.Successors[1].To.Name)
+	}
+
+	// The merged ministry can be looked up by ID and has every ministry before it
+	merged := lineage.Successors[len(lineage.Successors)-1].To
+	lineage, err = api.BuildLineage(client, merged.ID)
+	assert.NoError(t, err)
+	if lineage == nil {
+		return
+	}
+	assert.Equal(t, []api.LineageMinister{merged}, lineage.Ministers)
+	assert.Len(t, lineage.Predecessors, 3)
+	assert.Empty(t, lineage.Successors)
+
+	var out bytes.Buffer
+	lineage.Print(&out)
+	assert.Contains(t, out.String(), "2018-05-01  Minister of Irrigation and Water Resources Management")
+	assert.Contains(t, out.String(), "renamed to Minister of Lineage Irrigation")
+
+	_, err = api.BuildLineage(client, "Minister of Nothing")
+	assert.Error(t, err)
+}
@@ -2,8 +2,10 @@ package tests
 
 import (
 	"fmt"
+	"net/http/httptest"
 	"orgchart_nexoan/api"
 	"orgchart_nexoan/models"
+	"orgchart_nexoan/tests/fakenexoan"
 	"os"
 	"testing"
 
@@ -12,12 +14,24 @@ import (
 
 var client *api.Client
 
+// processor applies transactions through client
+var processor *api.Processor
+
 func TestMain(m *testing.M) {
-	// Set up test environment with correct URLs
-	client = api.NewClient("http://localhost:8080/entities", "http://localhost:8081/v1/entities")
+	// Run against a live Nexoan stack when its endpoints are given, otherwise against the in-memory fake
+	updateURL := os.Getenv("NEXOAN_UPDATE_URL")
+	queryURL := os.Getenv("NEXOAN_QUERY_URL")
+	var server *httptest.Server
+	if updateURL == "" || queryURL == "" {
+		server = httptest.NewServer(fakenexoan.New())
+		updateURL = server.URL + "/entities"
+		queryURL = server.URL + "/v1/entities"
+	}
+	client = api.NewClient(updateURL, queryURL)
+	processor = api.NewProcessor(client)
 
 	// Create government node using CreateGovernmentNode
-	government, err := client.CreateGovernmentNode()
+	government, err := processor.CreateGovernmentNode()
 	if err != nil {
 		fmt.Printf("Failed to create government node: %v\n", err)
 		os.Exit(1)
@@ -30,6 +44,9 @@ func TestMain(m *testing.M) {
 
 	// Run tests
 	code := m.Run()
+	if server != nil {
+		server.Close()
+	}
 	os.Exit(code)
 }
 
@@ -73,19 +90,19 @@ func TestCreateMinisters(t *testing.T) {
 	for _, tc := range testCases {
 		t.Logf("Creating minister: %s", tc.child)
 
-		// Create transaction map for AddEntity
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		// Create transaction for AddEntity
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
 		// Use AddEntity to create the minister
-		_, err := client.AddOrgEntity(transaction, entityCounters)
+		_, err := processor.AddOrgEntity(transaction, entityCounters)
 		assert.NoError(t, err)
 
 		// Update the counter for the next iteration
@@ -172,19 +189,19 @@ func TestCreateDepartments(t *testing.T) {
 	for _, tc := range testCases {
 		t.Logf("Creating department: %s under minister: %s", tc.child, tc.parent)
 
-		// Create transaction map for AddEntity
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		// Create transaction for AddEntity
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
 		// Use AddEntity to create the department
-		_, err := client.AddOrgEntity(transaction, entityCounters)
+		_, err := processor.AddOrgEntity(transaction, entityCounters)
 		assert.NoError(t, err)
 
 		// Update the counter for the next iteration
@@ -226,18 +243,18 @@ func TestCreateDepartments(t *testing.T) {
 }
 
 func TestTerminateDepartment(t *testing.T) {
-	// Create transaction map for terminating the department
-	transaction := map[string]interface{}{
-		"parent":      "Minister of Defence",
-		"child":       "Sri Lankan Army",
-		"date":        "2024-01-01",
-		"parent_type": "minister",
-		"child_type":  "department",
-		"rel_type":    "AS_DEPARTMENT",
+	// Create transaction for terminating the department
+	transaction := api.TerminateTransaction{
+		Parent:     "Minister of Defence",
+		Child:      "Sri Lankan Army",
+		Date:       "2024-01-01",
+		ParentType: "minister",
+		ChildType:  "department",
+		RelType:    "AS_DEPARTMENT",
 	}
 
 	// Terminate the department relationship
-	err := client.TerminateOrgEntity(transaction)
+	err := processor.TerminateOrgEntity(transaction)
 	assert.NoError(t, err)
 
 	// Find the minister to verify the relationship
@@ -279,18 +296,18 @@ func TestTerminateDepartment(t *testing.T) {
 }
 
 func TestTerminateMinister(t *testing.T) {
-	// Create transaction map for terminating the minister
-	transaction := map[string]interface{}{
-		"parent":      "Government of Sri Lanka",
-		"child":       "Minister of Defence",
-		"date":        "2024-01-01",
-		"parent_type": "government",
-		"child_type":  "minister",
-		"rel_type":    "AS_MINISTER",
+	// Create transaction for terminating the minister
+	transaction := api.TerminateTransaction{
+		Parent:     "Government of Sri Lanka",
+		Child:      "Minister of Defence",
+		Date:       "2024-01-01",
+		ParentType: "government",
+		ChildType:  "minister",
+		RelType:    "AS_MINISTER",
 	}
 
 	// Terminate the minister relationship
-	err := client.TerminateOrgEntity(transaction)
+	err := processor.TerminateOrgEntity(transaction)
 	assert.NoError(t, err)
 
 	// Find the government to verify the relationship
@@ -337,32 +354,32 @@ func TestMoveDepartment(t *testing.T) {
 		"minister": 2, // Since we already have 2 ministers from previous tests
 	}
 
-	// Create transaction map for new minister
-	newMinisterTransaction := map[string]interface{}{
-		"parent":         "Government of Sri Lanka",
-		"child":          "Minister of Education",
-		"date":           "2024-01-01",
-		"parent_type":    "government",
-		"child_type":     "minister",
-		"rel_type":       "AS_MINISTER",
-		"transaction_id": "2153/12_tr_06",
+	// Create transaction for new minister
+	newMinisterTransaction := api.AddTransaction{
+		Parent:        "Government of Sri Lanka",
+		Child:         "Minister of Education",
+		Date:          "2024-01-01",
+		ParentType:    "government",
+		ChildType:     "minister",
+		RelType:       "AS_MINISTER",
+		TransactionID: "2153/12_tr_06",
 	}
 
 	// Create the new minister
-	_, err := client.AddOrgEntity(newMinisterTransaction, entityCounters)
+	_, err := processor.AddOrgEntity(newMinisterTransaction, entityCounters)
 	assert.NoError(t, err)
 
-	// Create transaction map for moving the department
-	transaction := map[string]interface{}{
-		"old_parent": "Minister of Finance, Economic and Policy Development",
-		"new_parent": "Minister of Education",
-		"child":      "Department of Policies",
-		"type":       "AS_DEPARTMENT",
-		"date":       "2024-01-01",
+	// Create transaction for moving the department
+	transaction := api.MoveTransaction{
+		OldParent: "Minister of Finance, Economic and Policy Development",
+		NewParent: "Minister of Education",
+		Child:     "Department of Policies",
+		Type:      "AS_DEPARTMENT",
+		Date:      "2024-01-01",
 	}
 
 	// Move the department
-	err = client.MoveDepartment(transaction)
+	err = processor.MoveDepartment(transaction)
 	assert.NoError(t, err)
 
 	// Find the new minister to verify the new relationship
@@ -435,17 +452,17 @@ func TestRenameMinister(t *testing.T) {
 		"minister": 2,
 	}
 
-	// Create transaction map for renaming the minister
-	transaction := map[string]interface{}{
-		"old":            "Minister of Finance, Economic and Policy Development",
-		"new":            "Minister of Finance",
-		"type":           "AS_MINISTER",
-		"date":           "2024-01-01",
-		"transaction_id": "2153/13_tr_01",
+	// Create transaction for renaming the minister
+	transaction := api.RenameTransaction{
+		Old:           "Minister of Finance, Economic and Policy Development",
+		New:           "Minister of Finance",
+		Type:          "AS_MINISTER",
+		Date:          "2024-01-01",
+		TransactionID: "2153/13_tr_01",
 	}
 
 	// Rename the minister
-	newMinisterCounter, err := client.RenameMinister(transaction, entityCounters)
+	newMinisterCounter, err := processor.RenameMinister(transaction, entityCounters)
 	assert.NoError(t, err)
 	assert.Greater(t, newMinisterCounter, 0)
 
@@ -554,16 +571,16 @@ func TestMergeMinisters(t *testing.T) {
 		"minister": 0, // Since we already have 3 ministers from previous tests
 	}
 
-	// Create transaction map for merging ministers
-	transaction := map[string]interface{}{
-		"old":            "[Minister of Finance, Minister of Education]",
-		"new":            "Minister of Finance and Education",
-		"date":           "2025-01-01",
-		"transaction_id": "2154/13_tr_01",
+	// Create transaction for merging ministers
+	transaction := api.MergeTransaction{
+		Old:           "[Minister of Finance, Minister of Education]",
+		New:           "Minister of Finance and Education",
+		Date:          "2025-01-01",
+		TransactionID: "2154/13_tr_01",
 	}
 
 	// Merge the ministers
-	newMinisterCounter, err := client.MergeMinisters(transaction, entityCounters)
+	newMinisterCounter, err := processor.MergeMinisters(transaction, entityCounters)
 	assert.NoError(t, err)
 	assert.Greater(t, newMinisterCounter, 0)
 
@@ -714,18 +731,18 @@ func TestMergeMinisters(t *testing.T) {
 }
 
 func TestTerminateNonExistentMinister(t *testing.T) {
-	// Create transaction map for terminating a non-existent minister
-	transaction := map[string]interface{}{
-		"parent":      "Government of Sri Lanka",
-		"child":       "Non Existent Minister",
-		"date":        "2025-01-01",
-		"parent_type": "government",
-		"child_type":  "minister",
-		"rel_type":    "AS_MINISTER",
+	// Create transaction for terminating a non-existent minister
+	transaction := api.TerminateTransaction{
+		Parent:     "Government of Sri Lanka",
+		Child:      "Non Existent Minister",
+		Date:       "2025-01-01",
+		ParentType: "government",
+		ChildType:  "minister",
+		RelType:    "AS_MINISTER",
 	}
 
 	// Attempt to terminate the non-existent minister
-	err := client.TerminateOrgEntity(transaction)
+	err := processor.TerminateOrgEntity(transaction)
 	assert.Error(t, err)
 }
 
@@ -737,31 +754,31 @@ func TestTerminateMinisterWithChildren(t *testing.T) {
 	}
 
 	// Create minister
-	ministerTransaction := map[string]interface{}{
-		"parent":         "Government of Sri Lanka",
-		"child":          "Minister to Terminate",
-		"date":           "2025-01-01",
-		"parent_type":    "government",
-		"child_type":     "minister",
-		"rel_type":       "AS_MINISTER",
-		"transaction_id": "2154/14_tr_01",
+	ministerTransaction := api.AddTransaction{
+		Parent:        "Government of Sri Lanka",
+		Child:         "Minister to Terminate",
+		Date:          "2025-01-01",
+		ParentType:    "government",
+		ChildType:     "minister",
+		RelType:       "AS_MINISTER",
+		TransactionID: "2154/14_tr_01",
 	}
 
-	_, err := client.AddOrgEntity(ministerTransaction, entityCounters)
+	_, err := processor.AddOrgEntity(ministerTransaction, entityCounters)
 	assert.NoError(t, err)
 
 	// Create department under the minister
-	departmentTransaction := map[string]interface{}{
-		"parent":         "Minister to Terminate",
-		"child":          "Department Under Minister",
-		"date":           "2025-01-01",
-		"parent_type":    "minister",
-		"child_type":     "department",
-		"rel_type":       "AS_DEPARTMENT",
-		"transaction_id": "2154/14_tr_02",
+	departmentTransaction := api.AddTransaction{
+		Parent:        "Minister to Terminate",
+		Child:         "Department Under Minister",
+		Date:          "2025-01-01",
+		ParentType:    "minister",
+		ChildType:     "department",
+		RelType:       "AS_DEPARTMENT",
+		TransactionID: "2154/14_tr_02",
 	}
 
-	_, err = client.AddOrgEntity(departmentTransaction, entityCounters)
+	_, err = processor.AddOrgEntity(departmentTransaction, entityCounters)
 	assert.NoError(t, err)
 
 	// Debug: Print minister's relationships before termination
@@ -782,34 +799,34 @@ func TestTerminateMinisterWithChildren(t *testing.T) {
 	fmt.Printf("Debug: Minister's relationships before termination: %+v\n", relations)
 
 	// Attempt to terminate the minister
-	terminateTransaction := map[string]interface{}{
-		"parent":      "Government of Sri Lanka",
-		"child":       "Minister to Terminate",
-		"date":        "2025-01-02",
-		"parent_type": "government",
-		"child_type":  "minister",
-		"rel_type":    "AS_MINISTER",
+	terminateTransaction := api.TerminateTransaction{
+		Parent:     "Government of Sri Lanka",
+		Child:      "Minister to Terminate",
+		Date:       "2025-01-02",
+		ParentType: "government",
+		ChildType:  "minister",
+		RelType:    "AS_MINISTER",
 	}
 
 	fmt.Printf("Debug: Attempting to terminate minister with transaction: %+v\n", terminateTransaction)
-	err = client.TerminateOrgEntity(terminateTransaction)
+	err = processor.TerminateOrgEntity(terminateTransaction)
 	assert.Error(t, err)
 	// assert.Contains(t, err.Error(), "cannot terminate minister with active departments")
 
 }
 
 func TestMoveDepartmentToNonExistentMinister(t *testing.T) {
-	// Create transaction map for moving department to non-existent minister
-	transaction := map[string]interface{}{
-		"old_parent": "Minister of Finance and Education",
-		"new_parent": "Non Existent Minister",
-		"child":      "Department of Policies",
-		"type":       "AS_DEPARTMENT",
-		"date":       "2025-01-01",
+	// Create transaction for moving department to non-existent minister
+	transaction := api.MoveTransaction{
+		OldParent: "Minister of Finance and Education",
+		NewParent: "Non Existent Minister",
+		Child:     "Department of Policies",
+		Type:      "AS_DEPARTMENT",
+		Date:      "2025-01-01",
 	}
 
 	// Attempt to move the department
-	err := client.MoveDepartment(transaction)
+	err := processor.MoveDepartment(transaction)
 	assert.Error(t, err)
 }
 
@@ -819,16 +836,16 @@ func TestMergeNonExistentMinister(t *testing.T) {
 		"minister": 0,
 	}
 
-	// Create transaction map for merging non-existent minister
-	transaction := map[string]interface{}{
-		"old":            "[Non Existent Minister]",
-		"new":            "New Merged Minister",
-		"date":           "2025-01-01",
-		"transaction_id": "2154/14_tr_03",
+	// Create transaction for merging non-existent minister
+	transaction := api.MergeTransaction{
+		Old:           "[Non Existent Minister]",
+		New:           "New Merged Minister",
+		Date:          "2025-01-01",
+		TransactionID: "2154/14_tr_03",
 	}
 
 	// Attempt to merge the ministers
-	_, err := client.MergeMinisters(transaction, entityCounters)
+	_, err := processor.MergeMinisters(transaction, entityCounters)
 	fmt.Printf("Debug: Full error from MergeMinisters: %+v\n", err)
 	assert.Error(t, err)
 }
@@ -839,42 +856,43 @@ func TestCreateDuplicateMinister(t *testing.T) {
 		"minister": 0,
 	}
 
-	// Create transaction map for first minister
-	firstMinisterTransaction := map[string]interface{}{
-		"parent":         "Government of Sri Lanka",
-		"child":          "Duplicate Minister",
-		"date":           "2025-01-01",
-		"parent_type":    "government",
-		"child_type":     "minister",
-		"rel_type":       "AS_MINISTER",
-		"transaction_id": "2154/15_tr_01",
+	// Create transaction for first minister
+	firstMinisterTransaction := api.AddTransaction{
+		Parent:        "Government of Sri Lanka",
+		Child:         "Duplicate Minister",
+		Date:          "2025-01-01",
+		ParentType:    "government",
+		ChildType:     "minister",
+		RelType:       "AS_MINISTER",
+		TransactionID: "2154/15_tr_01",
 	}
 
 	// Create the first minister
-	firstMinister, err := client.AddOrgEntity(firstMinisterTransaction, entityCounters)
+	firstMinister, err := processor.AddOrgEntity(firstMinisterTransaction, entityCounters)
 	assert.NoError(t, err)
 	assert.NotNil(t, firstMinister)
 
 	// Update counter for second attempt
 	entityCounters["minister"]++
 
-	// Create transaction map for second minister with same name
-	secondMinisterTransaction := map[string]interface{}{
-		"parent":         "Government of Sri Lanka",
-		"child":          "Duplicate Minister",
-		"date":           "2025-01-02",
-		"parent_type":    "government",
-		"child_type":     "minister",
-		"rel_type":       "AS_MINISTER",
-		"transaction_id": "2154/15_tr_02",
+	// Create transaction for second minister with same name
+	secondMinisterTransaction := api.AddTransaction{
+		Parent:        "Government of Sri Lanka",
+		Child:         "Duplicate Minister",
+		Date:          "2025-01-02",
+		ParentType:    "government",
+		ChildType:     "minister",
+		RelType:       "AS_MINISTER",
+		TransactionID: "2154/15_tr_02",
 	}
 
-	// Create the second minister with same name
-	secondMinister, err := client.AddOrgEntity(secondMinisterTransaction, entityCounters)
-	assert.NoError(t, err, "Should be able to create minister with same name but different ID")
-	assert.NotNil(t, secondMinister)
+	// Adding the same minister again is rejected with the existing entity
+	counter, err := processor.AddOrgEntity(secondMinisterTransaction, entityCounters)
+	var existsErr *api.EntityExistsError
+	assert.ErrorAs(t, err, &existsErr, "Should not create a second active minister with the same name")
+	assert.Equal(t, entityCounters["minister"], counter, "Counter should be unchanged when no entity is created")
 
-	// Verify both ministers exist and have different IDs
+	// Verify only the first minister exists
 	searchCriteria := &models.SearchCriteria{
 		Kind: &models.Kind{
 			Major: "Organisation",
@@ -885,7 +903,33 @@ func TestCreateDuplicateMinister(t *testing.T) {
 
 	results, err := client.SearchEntities(searchCriteria)
 	assert.NoError(t, err)
-	assert.Len(t, results, 2, "Should find two ministers with this name")
-	assert.NotEqual(t, results[0].ID, results[1].ID, "Ministers should have different IDs")
+	assert.Len(t, results, 1, "Should find one minister with this name")
+	if existsErr != nil && len(results) == 1 {
+		assert.Equal(t, results[0].ID, existsErr.EntityID, "Error should name the existing minister")
+	}
+}
+
+func TestRenameReusesActiveMinister(t *testing.T) {
+	dataDir := t.TempDir()
+	// An earlier Minister of Health ended before the one that is active when the rename happens
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-10
+2153-12_tr_03,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-12
+2153-12_tr_04,Government of Sri Lanka,government,Minister of Wellness,minister,AS_MINISTER,2019-12-12`)
+	writeGazetteFile(t, dataDir, "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_02,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-11`)
+	writeGazetteFile(t, dataDir, "RENAME.csv", `transaction_id,old,new,type,date
+2153-12_tr_05,Minister of Wellness,Minister of Health,minister,2019-12-13`)
 
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactions(dataDir, "organisation", &api.ProcessOptions{}))
+
+	relations, err := store.GetRelatedEntities("2153-12_min_3", &models.Relationship{Name: "RENAMED_TO"})
+	assert.NoError(t, err)
+	if assert.Len(t, relations, 1) {
+		assert.Equal(t, "2153-12_min_2", relations[0].RelatedEntityID)
+	}
 }
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"orgchart_nexoan/api"
 	"orgchart_nexoan/models"
 	"testing"
 
@@ -51,19 +52,19 @@ func TestCreatePeople(t *testing.T) {
 	for _, tc := range ministersTestCases {
 		t.Logf("Creating minister: %s", tc.child)
 
-		// Create transaction map for AddEntity
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		// Create transaction for AddEntity
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
 		// Use AddEntity to create the minister
-		_, err := client.AddOrgEntity(transaction, ministerEntityCounters)
+		_, err := processor.AddOrgEntity(transaction, ministerEntityCounters)
 		assert.NoError(t, err)
 
 		// Update the counter for the next iteration
@@ -134,19 +135,19 @@ func TestCreatePeople(t *testing.T) {
 	for _, tc := range peopleTestCases {
 		t.Logf("Creating person: %s", tc.child)
 
-		// Create transaction map for AddEntity
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		// Create transaction for AddEntity
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
 		// Use AddEntity to create the person
-		_, err := client.AddPersonEntity(transaction, personEntityCounters)
+		_, err := processor.AddPersonEntity(transaction, personEntityCounters)
 		assert.NoError(t, err)
 
 		// Update the counter for the next iteration
@@ -252,19 +253,19 @@ func TestCreatePeopleWithManyMinisters(t *testing.T) {
 	for _, tc := range ministersTestCases {
 		t.Logf("Creating minister: %s", tc.child)
 
-		// Create transaction map for AddEntity
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		// Create transaction for AddEntity
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
 		// Use AddEntity to create the minister
-		_, err := client.AddOrgEntity(transaction, ministerEntityCounters)
+		_, err := processor.AddOrgEntity(transaction, ministerEntityCounters)
 		assert.NoError(t, err)
 
 		// Update the counter for the next iteration
@@ -362,19 +363,19 @@ func TestCreatePeopleWithManyMinisters(t *testing.T) {
 	for _, tc := range peopleTestCases {
 		t.Logf("Creating person: %s", tc.child)
 
-		// Create transaction map for AddEntity
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		// Create transaction for AddEntity
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
 		// Use AddEntity to create the person
-		_, err := client.AddPersonEntity(transaction, personEntityCounters)
+		_, err := processor.AddPersonEntity(transaction, personEntityCounters)
 		assert.NoError(t, err)
 
 		// Update the counter for the next iteration
@@ -444,19 +445,19 @@ func TestTerminatePerson(t *testing.T) {
 	for _, tc := range ministersTestCases {
 		t.Logf("Creating minister: %s", tc.child)
 
-		// Create transaction map for AddEntity
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		// Create transaction for AddEntity
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
 		// Use AddEntity to create the minister
-		_, err := client.AddOrgEntity(transaction, ministerEntityCounters)
+		_, err := processor.AddOrgEntity(transaction, ministerEntityCounters)
 		assert.NoError(t, err)
 
 		// Update the counter for the next iteration
@@ -518,19 +519,19 @@ func TestTerminatePerson(t *testing.T) {
 	for _, tc := range peopleTestCases {
 		t.Logf("Creating person: %s", tc.child)
 
-		// Create transaction map for AddEntity
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		// Create transaction for AddEntity
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
 		// Use AddEntity to create the person
-		_, err := client.AddPersonEntity(transaction, personEntityCounters)
+		_, err := processor.AddPersonEntity(transaction, personEntityCounters)
 		assert.NoError(t, err)
 
 		// Update the counter for the next iteration
@@ -568,18 +569,18 @@ func TestTerminatePerson(t *testing.T) {
 	parent_minister := "Minister of Health and Space Exploration"
 	child_person := "Sanath Abeywardena"
 
-	// Create transaction map for terminating the person
-	transaction := map[string]interface{}{
-		"parent":      parent_minister,
-		"child":       child_person,
-		"date":        "2019-11-01",
-		"parent_type": "minister",
-		"child_type":  "citizen",
-		"rel_type":    "AS_APPOINTED",
+	// Create transaction for terminating the person
+	transaction := api.TerminateTransaction{
+		Parent:     parent_minister,
+		Child:      child_person,
+		Date:       "2019-11-01",
+		ParentType: "minister",
+		ChildType:  "citizen",
+		RelType:    "AS_APPOINTED",
 	}
 
 	// Terminate the person relationship
-	err := client.TerminatePersonEntity(transaction)
+	err := processor.TerminatePersonEntity(transaction)
 	assert.NoError(t, err)
 
 	// Find the minister to verify the relationship
@@ -672,19 +673,19 @@ func TestTerminateMultipleMinistersForPerson(t *testing.T) {
 	for _, tc := range ministersTestCases {
 		t.Logf("Creating minister: %s", tc.child)
 
-		// Create transaction map for AddEntity
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		// Create transaction for AddEntity
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
 		// Use AddEntity to create the minister
-		_, err := client.AddOrgEntity(transaction, ministerEntityCounters)
+		_, err := processor.AddOrgEntity(transaction, ministerEntityCounters)
 		assert.NoError(t, err)
 
 		// Update the counter for the next iteration
@@ -749,17 +750,17 @@ func TestTerminateMultipleMinistersForPerson(t *testing.T) {
 	for _, tc := range peopleTestCases {
 		t.Logf("Creating person relationship with minister: %s", tc.parent)
 
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
-		_, err := client.AddPersonEntity(transaction, personEntityCounters)
+		_, err := processor.AddPersonEntity(transaction, personEntityCounters)
 		personEntityCounters[tc.childType]++
 		assert.NoError(t, err)
 	}
@@ -792,18 +793,18 @@ func TestTerminateMultipleMinistersForPerson(t *testing.T) {
 	}
 
 	for _, tc := range terminateCases {
-		// Create transaction map for terminating the relationship
-		transaction := map[string]interface{}{
-			"parent":      tc.ministerName,
-			"child":       personName,
-			"date":        tc.date,
-			"parent_type": "minister",
-			"child_type":  "citizen",
-			"rel_type":    "AS_APPOINTED",
+		// Create transaction for terminating the relationship
+		transaction := api.TerminateTransaction{
+			Parent:     tc.ministerName,
+			Child:      personName,
+			Date:       tc.date,
+			ParentType: "minister",
+			ChildType:  "citizen",
+			RelType:    "AS_APPOINTED",
 		}
 
 		// Terminate the relationship
-		err := client.TerminatePersonEntity(transaction)
+		err := processor.TerminatePersonEntity(transaction)
 		assert.NoError(t, err)
 
 		// Find the minister
@@ -900,19 +901,19 @@ func TestMovePerson(t *testing.T) {
 	for _, tc := range ministersTestCases {
 		t.Logf("Creating minister: %s", tc.child)
 
-		// Create transaction map for AddEntity
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		// Create transaction for AddEntity
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
 		// Use AddEntity to create the minister
-		_, err := client.AddOrgEntity(transaction, ministerEntityCounters)
+		_, err := processor.AddOrgEntity(transaction, ministerEntityCounters)
 		assert.NoError(t, err)
 
 		// Update the counter for the next iteration
@@ -959,17 +960,17 @@ func TestMovePerson(t *testing.T) {
 	for _, tc := range peopleTestCases {
 		t.Logf("Creating person relationship with minister: %s", tc.parent)
 
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
-		_, err := client.AddPersonEntity(transaction, personEntityCounters)
+		_, err := processor.AddPersonEntity(transaction, personEntityCounters)
 		assert.NoError(t, err)
 	}
 
@@ -985,17 +986,17 @@ func TestMovePerson(t *testing.T) {
 	assert.Len(t, personResults, 1)
 	personID := personResults[0].ID
 
-	// Create transaction map for moving the person from one minister to another
-	transaction := map[string]interface{}{
-		"old_parent": "Minister of Agriculture and Food Security",
-		"new_parent": "Minister of Environment and Climate Change",
-		"child":      personName,
-		"type":       "AS_APPOINTED",
-		"date":       "2020-01-01",
+	// Create transaction for moving the person from one minister to another
+	transaction := api.MoveTransaction{
+		OldParent: "Minister of Agriculture and Food Security",
+		NewParent: "Minister of Environment and Climate Change",
+		Child:     personName,
+		Type:      "AS_APPOINTED",
+		Date:      "2020-01-01",
 	}
 
 	// Move the person
-	err = client.MovePerson(transaction)
+	err = processor.MovePerson(transaction)
 	assert.NoError(t, err)
 
 	// Find the old minister to verify the old relationship is terminated
@@ -1102,17 +1103,17 @@ func TestSwapMultiplePeople(t *testing.T) {
 	for _, tc := range ministersTestCases {
 		t.Logf("Creating minister: %s", tc.child)
 
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
-		_, err := client.AddOrgEntity(transaction, ministerEntityCounters)
+		_, err := processor.AddOrgEntity(transaction, ministerEntityCounters)
 		assert.NoError(t, err)
 		ministerEntityCounters[tc.childType]++
 
@@ -1174,17 +1175,17 @@ func TestSwapMultiplePeople(t *testing.T) {
 	for _, tc := range peopleTestCases {
 		t.Logf("Creating person relationship with minister: %s", tc.parent)
 
-		transaction := map[string]interface{}{
-			"parent":         tc.parent,
-			"child":          tc.child,
-			"date":           tc.date,
-			"parent_type":    tc.parentType,
-			"child_type":     tc.childType,
-			"rel_type":       tc.relType,
-			"transaction_id": tc.transactionID,
+		transaction := api.AddTransaction{
+			Parent:        tc.parent,
+			Child:         tc.child,
+			Date:          tc.date,
+			ParentType:    tc.parentType,
+			ChildType:     tc.childType,
+			RelType:       tc.relType,
+			TransactionID: tc.transactionID,
 		}
 
-		_, err := client.AddPersonEntity(transaction, personEntityCounters)
+		_, err := processor.AddPersonEntity(transaction, personEntityCounters)
 		personEntityCounters[tc.childType]++
 		assert.NoError(t, err)
 	}
@@ -1234,15 +1235,15 @@ func TestSwapMultiplePeople(t *testing.T) {
 
 	// Execute the swap moves
 	for _, move := range swapMoves {
-		transaction := map[string]interface{}{
-			"old_parent": move.oldParent,
-			"new_parent": move.newParent,
-			"child":      move.person,
-			"type":       "AS_APPOINTED",
-			"date":       move.date,
+		transaction := api.MoveTransaction{
+			OldParent: move.oldParent,
+			NewParent: move.newParent,
+			Child:     move.person,
+			Type:      "AS_APPOINTED",
+			Date:      move.date,
 		}
 
-		err := client.MovePerson(transaction)
+		err := processor.MovePerson(transaction)
 		assert.NoError(t, err)
 	}
 
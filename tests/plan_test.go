@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"bytes"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDryRunPlan(t *testing.T) {
+	dataDir := t.TempDir()
+	grDir := filepath.Join(dataDir, "gr")
+	writeGazetteFile(t, grDir, api.TermManifestFile, `{"name": "Presidency of Gotabaya Rajapaksa", "start": "2019-11-18"}`)
+	writeGazetteFile(t, filepath.Join(grDir, "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Minister of Health,minister,Department of Hospitals,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_03,Minister of Defence,minister,Sri Lanka Army,department,AS_DEPARTMENT,2019-12-10`)
+	rwDir := filepath.Join(dataDir, "rw")
+	writeGazetteFile(t, rwDir, api.TermManifestFile, `{"name": "Presidency of Ranil Wickremesinghe", "start": "2022-07-21"}`)
+	writeGazetteFile(t, filepath.Join(rwDir, "2022-07-22"), "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2289-43_tr_01,Minister of Defence,minister,Sri Lanka Army,department,AS_DEPARTMENT,2022-07-22`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	plan := memoryProcessor.EnableDryRun()
+
+	counters := map[string]int{}
+	opts := &api.ProcessOptions{EntityCounters: counters}
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(grDir, "organisation", opts))
+	// A root created after a transaction is not attributed to it
+	_, _, err = memoryProcessor.CreateRootNode(api.RootNode{ID: "pc_western", Name: "Western Provincial Council", Created: "2019-01-01", Kind: "government"})
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(rwDir, "organisation", opts))
+
+	type step struct {
+		transactionID string
+		fileType      string
+		method        string
+		entityID      string
+	}
+	steps := make([]step, len(plan.Steps))
+	for i, planStep := range plan.Steps {
+		steps[i] = step{planStep.TransactionID, planStep.FileType, planStep.Method, planStep.EntityID}
+	}
+	assert.Equal(t, []step{
+		{"term_gr", api.TermFileType, "CreateEntity", "term_gr"},
+		{"term_gr", api.TermFileType, "UpdateEntity", api.DefaultGovernmentID},
+		{"2153-12_tr_01", "ADD", "CreateEntity", "2153-12_min_1"},
+		{"2153-12_tr_01", "ADD", "UpdateEntity", api.DefaultGovernmentID},
+		{"2153-12_tr_01", "ADD", "UpdateEntity", "term_gr"},
+		{"2153-12_tr_03", "ADD", "CreateEntity", "2153-12_dep_1"},
+		{"2153-12_tr_03", "ADD", "UpdateEntity", "2153-12_min_1"},
+		{"", "", "CreateEntity", "pc_western"},
+		{"term_rw", api.TermFileType, "CreateEntity", "term_rw"},
+		{"term_rw", api.TermFileType, "UpdateEntity", api.DefaultGovernmentID},
+		{"2289-43_tr_01", "TERMINATE", "UpdateEntity", "2153-12_min_1"},
+	}, steps)
+
+	// The transaction whose parent is missing is recorded as an error and the rest are still planned
+	if assert.Len(t, plan.Errors, 1) {
+		assert.Equal(t, "2153-12_tr_02", plan.Errors[0].TransactionID)
+		assert.Equal(t, "ADD", plan.Errors[0].FileType)
+		assert.ErrorContains(t, plan.Errors[0].Err, "Minister of Health")
+	}
+	// The IDs planned for the created entities are taken from the counters
+	assert.Equal(t, map[string]int{"minister": 1, "department": 1}, counters)
+
+	var out bytes.Buffer
+	plan.Print(&out)
+	assert.Contains(t, out.String(), "Plan: 5 entities to create, 5 relationships to add, 1 relationships to end, 1 errors")
+
+	// Nothing is written to the store
+	results, err := store.SearchEntities(&models.SearchCriteria{Kind: &models.Kind{Major: "Organisation", Minor: "minister"}})
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	_, exists := store.Entity("term_gr")
+	assert.False(t, exists)
+}
@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateRootNodeIsIdempotent(t *testing.T) {
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	root := api.RootNode{ID: "gov_lk", Name: "Government of Sri Lanka", Created: "2015-01-09", Kind: "government"}
+
+	entity, created, err := memoryProcessor.CreateRootNode(root)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "2015-01-09T00:00:00Z", entity.Created)
+
+	// Initialising again finds the existing root
+	entity, created, err = memoryProcessor.CreateRootNode(root)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, "gov_lk", entity.ID)
+	assert.Equal(t, "Government of Sri Lanka", entity.Name.Value)
+
+	// A root with the same ID but another name is not silently reused
+	root.Name = "Government of Ceylon"
+	_, _, err = memoryProcessor.CreateRootNode(root)
+	assert.ErrorContains(t, err, "already exists")
+
+	_, err = memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	_, err = memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	roots, err := store.GetRootEntities("Organisation")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"gov_lk", "gov_01"}, roots)
+}
+
+func TestProvincialCouncilRoot(t *testing.T) {
+	dataDir := t.TempDir()
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Western Provincial Council,provincial_council,Provincial Minister of Health,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Western Provincial Council,provincial_council,Provincial Minister of Roads,minister,AS_MINISTER,2019-12-10
+2153-12_tr_03,Western Provincial Council,provincial_council,Provincial Minister of Transport,minister,AS_MINISTER,2019-12-10
+2153-12_tr_04,Provincial Minister of Health,minister,Provincial Department of Health,department,AS_DEPARTMENT,2019-12-10`)
+	writeGazetteFile(t, dataDir, "RENAME.csv", `transaction_id,old,new,type,date
+2153-12_tr_05,Provincial Minister of Health,Provincial Minister of Health and Indigenous Medicine,minister,2020-01-15`)
+	writeGazetteFile(t, dataDir, "MERGE.csv", `transaction_id,old,new,type,date
+2153-12_tr_06,"[Provincial Minister of Roads, Provincial Minister of Transport]",Provincial Minister of Roads and Transport,minister,2020-01-15`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	_, _, err = memoryProcessor.CreateRootNode(api.RootNode{
+		ID:      "pc_western",
+		Name:    "Western Provincial Council",
+		Created: "1988-01-01",
+		Kind:    "provincial_council",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactions(dataDir, "organisation", nil))
+
+	// The renamed and merged ministers stay under the council
+	snapshot, err := api.BuildSnapshot(store, "pc_western", "2020-01-15")
+	assert.NoError(t, err)
+	names := map[string]string{}
+	for _, child := range snapshot.Root.Children {
+		names[child.Name] = child.Relationship
+	}
+	assert.Equal(t, map[string]string{
+		"Provincial Minister of Health and Indigenous Medicine": "AS_MINISTER",
+		"Provincial Minister of Roads and Transport":            "AS_MINISTER",
+	}, names)
+
+	// Nothing was added to the national government
+	ministers, err := store.GetRelatedEntities(api.DefaultGovernmentID, &models.Relationship{})
+	assert.NoError(t, err)
+	assert.Empty(t, ministers)
+}
+
+func TestRenameType(t *testing.T) {
+	testCases := []struct {
+		name       string
+		renameType string
+		// wantRelationship is the relationship from the government to the renamed minister, or
+		// empty if the rename fails
+		wantRelationship string
+	}{
+		{name: "relationship name", renameType: "AS_CABINET_MINISTER", wantRelationship: "AS_CABINET_MINISTER"},
+		{name: "kind", renameType: "minister", wantRelationship: "AS_MINISTER"},
+		{name: "mapped kind", renameType: "ministry", wantRelationship: "AS_MINISTER"},
+		{name: "other kind", renameType: "department"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dataDir := t.TempDir()
+			writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-10`)
+			writeGazetteFile(t, dataDir, "RENAME.csv", `transaction_id,old,new,type,date
+2153-12_tr_02,Minister of Health,Minister of Health and Wellness,`+tc.renameType+`,2020-01-15`)
+
+			store := api.NewMemoryStore()
+			memoryProcessor := api.NewProcessor(store)
+			_, err := memoryProcessor.CreateGovernmentNode()
+			assert.NoError(t, err)
+			err = memoryProcessor.ProcessTransactions(dataDir, "organisation", &api.ProcessOptions{KindMapping: map[string]string{"ministry": "minister"}})
+			if tc.wantRelationship == "" {
+				assert.ErrorContains(t, err, "only ministers can be renamed or merged")
+				return
+			}
+			assert.NoError(t, err)
+
+			relations, err := store.GetRelatedEntities(api.DefaultGovernmentID, &models.Relationship{StartTime: "2020-01-15T00:00:00Z"})
+			assert.NoError(t, err)
+			if assert.Len(t, relations, 1) {
+				assert.Equal(t, tc.wantRelationship, relations[0].Name)
+				renamed, _ := store.Entity(relations[0].RelatedEntityID)
+				assert.Equal(t, "Minister of Health and Wellness", renamed.Name.Value)
+			}
+		})
+	}
+}
+
+func TestLoadRootNodes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roots.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`[
+  {"id": "gov_01", "name": "Government of Sri Lanka", "created": "2015-01-09"},
+  {"id": "pc_western", "name": "Western Provincial Council", "created": "1988-01-01", "kind": "provincial_council"}
+]`), 0o644))
+
+	roots, err := api.LoadRootNodes(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []api.RootNode{
+		{ID: "gov_01", Name: "Government of Sri Lanka", Created: "2015-01-09", Kind: "government"},
+		{ID: "pc_western", Name: "Western Provincial Council", Created: "1988-01-01", Kind: "provincial_council"},
+	}, roots)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"name": "Western Provincial Council"}]`), 0o644))
+	_, err = api.LoadRootNodes(path)
+	assert.ErrorContains(t, err, "needs an id and a name")
+}
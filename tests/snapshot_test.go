@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"bytes"
+	"orgchart_nexoan/api"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSnapshot(t *testing.T) {
+	rootDir := t.TempDir()
+	writeGazetteFile(t, filepath.Join(rootDir, "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Minister of Defence,minister,Sri Lanka Army,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_03,Minister of Defence,minister,Sri Lanka Navy,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_04,Government of Sri Lanka,government,Minister of Finance,minister,AS_MINISTER,2019-12-10`)
+	writeGazetteFile(t, filepath.Join(rootDir, "2020-01-15"), "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2160-01_tr_01,Minister of Defence,minister,Sri Lanka Navy,department,AS_DEPARTMENT,2020-01-15`)
+	peopleDir := filepath.Join(t.TempDir(), "2019-12-10")
+	writeGazetteFile(t, peopleDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-13_tr_01,Minister of Defence,minister,Gotabaya Rajapaksa,citizen,AS_APPOINTED,2019-12-10
+2153-13_tr_02,Minister of Finance,minister,Mahinda Rajapaksa,citizen,AS_APPOINTED,2019-12-10`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(rootDir, "organisation", nil))
+	assert.NoError(t, memoryProcessor.ProcessTransactions(peopleDir, "person", nil))
+
+	// Before the termination both departments belong to the Minister of Defence
+	snapshot, err := api.BuildSnapshot(store, "", "2020-01-01")
+	assert.NoError(t, err)
+	assert.Equal(t, "gov_01", snapshot.Root.ID)
+	assert.Len(t, snapshot.Root.Children, 2)
+	if len(snapshot.Root.Children) != 2 {
+		return
+	}
+	defence := snapshot.Root.Children[0]
+	assert.Equal(t, "Minister of Defence", defence.Name)
+	assert.Equal(t, "AS_MINISTER", defence.Relationship)
+	assert.Len(t, defence.Children, 3)
+
+	// After it only the army and the appointed minister remain
+	snapshot, err = api.BuildSnapshot(store, "", "2020-02-01")
+	assert.NoError(t, err)
+	var out bytes.Buffer
+	snapshot.Print(&out)
+	assert.Equal(t, `Org chart on 2020-02-01
+Government of Sri Lanka [government] (gov_01)
+  Minister of Defence [minister] (2153-12_min_1) since 2019-12-10
+    Gotabaya Rajapaksa [citizen] (2153-13_cit_1) since 2019-12-10
+    Sri Lanka Army [department] (2153-12_dep_1) since 2019-12-10
+  Minister of Finance [minister] (2153-12_min_2) since 2019-12-10
+    Mahinda Rajapaksa [citizen] (2153-13_cit_2) since 2019-12-10
+`, out.String())
+
+	// Nothing is active before the first gazette
+	snapshot, err = api.BuildSnapshot(store, "", "2019-01-01")
+	assert.NoError(t, err)
+	assert.Empty(t, snapshot.Root.Children)
+
+	_, err = api.BuildSnapshot(store, "", "01/01/2020")
+	assert.Error(t, err)
+}
+
+func TestBuildSnapshotOfPresidencyData(t *testing.T) {
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree("../data/orgchart/gr", "organisation", &api.ProcessOptions{}))
+
+	// The state ministers are related to the government with HAS_MINISTER
+	snapshot, err := api.BuildSnapshot(store, "", "2020-01-09")
+	assert.NoError(t, err)
+	assert.Len(t, snapshot.Root.Children, 33)
+	relationships := map[string]string{}
+	for _, minister := range snapshot.Root.Children {
+		relationships[minister.Name] = minister.Relationship
+	}
+	assert.Equal(t, "AS_MINISTER", relationships["Minister of Defence"])
+	assert.Equal(t, "HAS_MINISTER", relationships["State Minister of Railway Services"])
+	assert.Equal(t, "HAS_MINISTER", relationships["State Minister of Education Services"])
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"orgchart_nexoan/tests/fakenexoan"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessTransactionsOnMemoryStore(t *testing.T) {
+	dataDir := t.TempDir()
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Minister of Defence,minister,Sri Lanka Army,department,AS_DEPARTMENT,2019-12-10`)
+	writeGazetteFile(t, dataDir, "RENAME.csv", `transaction_id,old,new,type,date
+2153-12_tr_03,Minister of Defence,Minister of Defence and Security,AS_MINISTER,2019-12-10`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactions(dataDir, "organisation", nil))
+
+	// The department moved to the renamed minister
+	renamed, err := store.SearchEntities(&models.SearchCriteria{
+		Kind: &models.Kind{Major: "Organisation", Minor: "minister"},
+		Name: "Minister of Defence and Security",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, renamed, 1)
+	if len(renamed) != 1 {
+		return
+	}
+	relations, err := store.GetRelatedEntities(renamed[0].ID, &models.Relationship{
+		Name:      "AS_DEPARTMENT",
+		StartTime: "2019-12-10T00:00:00Z",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, relations, 1)
+
+	roots, err := store.GetRootEntities("Organisation")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gov_01"}, roots)
+}
+
+func TestRecordingStore(t *testing.T) {
+	recorder := api.NewRecordingStore(api.NewMemoryStore())
+	recordingProcessor := api.NewProcessor(recorder)
+
+	_, err := recordingProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	_, err = recordingProcessor.AddOrgEntity(api.AddTransaction{
+		TransactionID: "2153-12_tr_01",
+		Parent:        "Government of Sri Lanka",
+		ParentType:    "government",
+		Child:         "Minister of Defence",
+		ChildType:     "minister",
+		RelType:       "AS_MINISTER",
+		Date:          "2019-12-10",
+	}, map[string]int{"minister": 0})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []api.Change{
+		{Operation: api.ChangeCreateEntity, EntityID: "gov_01"},
+		{Operation: api.ChangeCreateEntity, EntityID: "2153-12_min_1"},
+		{
+			Operation:       api.ChangeAddRelationship,
+			EntityID:        "gov_01",
+			RelationshipID:  "gov_01_2153-12_min_1",
+			RelatedEntityID: "2153-12_min_1",
+			Name:            "AS_MINISTER",
+			StartTime:       "2019-12-10T00:00:00Z",
+		},
+	}, recorder.Changes())
+}
+
+func TestFakeServerRejectsUnknownQueryFields(t *testing.T) {
+	server := httptest.NewServer(fakenexoan.New())
+	defer server.Close()
+	fakeClient := api.NewClient(server.URL+"/entities", server.URL+"/v1/entities")
+	_, err := api.NewProcessor(fakeClient).CreateGovernmentNode()
+	assert.NoError(t, err)
+
+	// The relations query only filters on the fields of models.Relationship
+	resp, err := http.Post(server.URL+"/v1/entities/gov_01/relations", "application/json", strings.NewReader(`{"direction": "INCOMING"}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+"/v1/entities/search", "application/json", strings.NewReader(`{"kind": {"major": "Organisation"}, "parent": "gov_01"}`))
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	relations, err := fakeClient.GetRelatedEntities("gov_01", &models.Relationship{RelatedEntityID: "2153-12_min_1"})
+	assert.NoError(t, err)
+	assert.Empty(t, relations)
+}
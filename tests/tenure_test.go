@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"bytes"
+	"orgchart_nexoan/api"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildTenureHistory(t *testing.T) {
+	orgDir := t.TempDir()
+	writeGazetteFile(t, filepath.Join(orgDir, "2019-11-22"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2150-20_tr_01,Government of Sri Lanka,government,Minister of Tenure Power,minister,AS_MINISTER,2019-11-22
+2150-20_tr_02,Government of Sri Lanka,government,"Minister of Tenure Transport, Highways",minister,AS_MINISTER,2019-11-22`)
+
+	peopleDir := t.TempDir()
+	writeGazetteFile(t, filepath.Join(peopleDir, "2019-11-22"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2150-21_tr_01,Minister of Tenure Power,minister,Ranjith Tenure,citizen,AS_APPOINTED,2019-11-22`)
+	writeGazetteFile(t, filepath.Join(peopleDir, "2020-08-12"), "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2189-11_tr_01,Minister of Tenure Power,minister,Ranjith Tenure,citizen,AS_APPOINTED,2020-08-12`)
+	writeGazetteFile(t, filepath.Join(peopleDir, "2020-08-12"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2189-11_tr_02,"Minister of Tenure Transport, Highways",minister,Ranjith Tenure,citizen,AS_APPOINTED,2020-08-12`)
+
+	journal, err := api.OpenJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+	assert.NoError(t, err)
+	defer journal.Close()
+
+	opts := &api.ProcessOptions{Journal: journal}
+	assert.NoError(t, processor.ProcessTransactionTree(orgDir, "organisation", opts))
+	assert.NoError(t, processor.ProcessTransactionTree(peopleDir, "person", opts))
+
+	history, err := api.BuildTenureHistory(client, "Ranjith Tenure", journal)
+	assert.NoError(t, err)
+	if history == nil {
+		return
+	}
+	assert.Len(t, history.Tenures, 2)
+	if len(history.Tenures) != 2 {
+		return
+	}
+
+	first := history.Tenures[0]
+	assert.Equal(t, "Minister of Tenure Power", first.MinisterName)
+	assert.Equal(t, "AS_APPOINTED", first.Relationship)
+	assert.Equal(t, "2019-11-22", first.StartDate)
+	assert.Equal(t, "2020-08-12", first.EndDate)
+	assert.Equal(t, "2150-21_tr_01", first.StartTransaction)
+	assert.Equal(t, "2189-11_tr_01", first.EndTransaction)
+
+	second := history.Tenures[1]
+	assert.Equal(t, "Minister of Tenure Transport, Highways", second.MinisterName)
+	assert.Equal(t, "2020-08-12", second.StartDate)
+	assert.Empty(t, second.EndDate)
+	assert.Equal(t, "2189-11_tr_02", second.StartTransaction)
+	assert.Empty(t, second.EndTransaction)
+
+	var out bytes.Buffer
+	assert.NoError(t, history.WriteCSV(&out))
+	assert.Equal(t, `person_id,person,minister_id,minister,relationship,start_date,end_date,start_transaction,end_transaction
+`+first.PersonID+`,Ranjith Tenure,`+first.MinisterID+`,Minister of Tenure Power,AS_APPOINTED,2019-11-22,2020-08-12,2150-21_tr_01,2189-11_tr_01
+`+second.PersonID+`,Ranjith Tenure,`+second.MinisterID+`,"Minister of Tenure Transport, Highways",AS_APPOINTED,2020-08-12,,2189-11_tr_02,
+`, out.String())
+
+	// Without a journal the transactions are left out
+	history, err = api.BuildTenureHistory(client, first.PersonID, nil)
+	assert.NoError(t, err)
+	assert.Len(t, history.Tenures, 2)
+	for _, tenure := range history.Tenures {
+		assert.Empty(t, tenure.StartTransaction)
+	}
+
+	_, err = api.BuildTenureHistory(client, "Nobody Tenure", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,262 @@
+package tests
+
+import (
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadTerm(t *testing.T) {
+	presidencyDir := filepath.Join(t.TempDir(), "gr")
+	assert.NoError(t, os.MkdirAll(presidencyDir, 0o755))
+
+	// A presidency directory without a manifest has no term
+	term, err := api.LoadTerm(presidencyDir)
+	assert.NoError(t, err)
+	assert.Nil(t, term)
+
+	writeGazetteFile(t, presidencyDir, api.TermManifestFile, `{"name": "Presidency of Gotabaya Rajapaksa", "start": "2019-11-18", "end": "2022-07-14"}`)
+	term, err = api.LoadTerm(presidencyDir)
+	assert.NoError(t, err)
+	assert.Equal(t, &api.Term{
+		ID:    "term_gr",
+		Name:  "Presidency of Gotabaya Rajapaksa",
+		Start: "2019-11-18",
+		End:   "2022-07-14",
+	}, term)
+
+	writeGazetteFile(t, presidencyDir, api.TermManifestFile, `{"name": "Presidency of Gotabaya Rajapaksa"}`)
+	_, err = api.LoadTerm(presidencyDir)
+	assert.ErrorContains(t, err, "needs a name and a start date")
+}
+
+func TestTermMinisters(t *testing.T) {
+	presidencyDir := filepath.Join(t.TempDir(), "gr")
+	writeGazetteFile(t, presidencyDir, api.TermManifestFile, `{"name": "Presidency of Gotabaya Rajapaksa", "start": "2019-11-18", "end": "2022-07-14"}`)
+	writeGazetteFile(t, filepath.Join(presidencyDir, "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-10
+2153-12_tr_03,Minister of Health,minister,Department of Hospitals,department,AS_DEPARTMENT,2019-12-10`)
+	writeGazetteFile(t, filepath.Join(presidencyDir, "2020-01-15"), "RENAME.csv", `transaction_id,old,new,type,date
+2160-01_tr_01,Minister of Health,Minister of Health and Wellness,minister,2020-01-15`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	opts := &api.ProcessOptions{}
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(presidencyDir, "organisation", opts))
+
+	term, exists := store.Entity("term_gr")
+	if assert.True(t, exists) {
+		assert.Equal(t, models.Kind{Major: "Organisation", Minor: api.TermKind}, term.Kind)
+		assert.Equal(t, "2019-11-18T00:00:00Z", term.Created)
+		assert.Equal(t, "2022-07-14T00:00:00Z", term.Terminated)
+	}
+	terms, err := store.GetRelatedEntities(api.DefaultGovernmentID, &models.Relationship{Name: api.TermRelationship})
+	assert.NoError(t, err)
+	assert.Len(t, terms, 1)
+
+	// Every minister created in the term is related to it, including the renamed one
+	ministers, err := store.GetRelatedEntities("term_gr", &models.Relationship{Name: api.TermMinisterRelationship})
+	assert.NoError(t, err)
+	names := []string{}
+	for _, rel := range ministers {
+		entity, _ := store.Entity(rel.RelatedEntityID)
+		names = append(names, entity.Name.Value.(string))
+		assert.Equal(t, "2022-07-14T00:00:00Z", rel.EndTime)
+	}
+	assert.Equal(t, []string{"Minister of Defence", "Minister of Health", "Minister of Health and Wellness"}, names)
+
+	// The renamed minister stays under the government, not the term
+	snapshot, err := api.BuildSnapshot(store, "", "2020-01-15")
+	assert.NoError(t, err)
+	assert.Len(t, snapshot.Root.Children, 2)
+
+	// A snapshot of the term lists the ministries created in it
+	termSnapshot, err := api.BuildSnapshot(store, "term_gr", "2020-01-15")
+	assert.NoError(t, err)
+	assert.Len(t, termSnapshot.Root.Children, 3)
+
+	// Creating the term again finds the existing one
+	manifest, err := api.LoadTerm(presidencyDir)
+	assert.NoError(t, err)
+	_, created, err := memoryProcessor.CreateTermNode(manifest)
+	assert.NoError(t, err)
+	assert.False(t, created)
+	terms, err = store.GetRelatedEntities(api.DefaultGovernmentID, &models.Relationship{Name: api.TermRelationship})
+	assert.NoError(t, err)
+	assert.Len(t, terms, 1)
+
+	// Terms are not gazetted, so the rebuilt CSV files leave them out
+	graph, err := api.LoadGraph(store, "", nil)
+	assert.NoError(t, err)
+	folders, err := api.ReconstructGazettes(graph, "organisation")
+	assert.NoError(t, err)
+	for _, folder := range folders {
+		for _, transaction := range folder.Transactions {
+			if add, ok := transaction.(api.AddTransaction); ok {
+				assert.NotEqual(t, api.TermKind, add.ChildType)
+				assert.NotEqual(t, api.TermKind, add.ParentType)
+			}
+		}
+	}
+}
+
+func TestTermLinksReusedMinisters(t *testing.T) {
+	// The previous presidency created the ministers that the next one keeps or renames into
+	previousDir := filepath.Join(t.TempDir(), "mr")
+	writeGazetteFile(t, filepath.Join(previousDir, "2015-01-12"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+1897-15_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2015-01-12
+1897-15_tr_02,Government of Sri Lanka,government,Minister of Ports,minister,AS_MINISTER,2015-01-12`)
+	presidencyDir := filepath.Join(t.TempDir(), "gr")
+	writeGazetteFile(t, presidencyDir, api.TermManifestFile, `{"name": "Presidency of Gotabaya Rajapaksa", "start": "2019-11-18", "end": "2022-07-14"}`)
+	writeGazetteFile(t, filepath.Join(presidencyDir, "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Government of Sri Lanka,government,Minister of Shipping,minister,AS_MINISTER,2019-12-10`)
+	writeGazetteFile(t, filepath.Join(presidencyDir, "2020-01-15"), "RENAME.csv", `transaction_id,old,new,type,date
+2160-01_tr_01,Minister of Shipping,Minister of Ports,minister,2020-01-15`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	opts := &api.ProcessOptions{}
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(previousDir, "organisation", opts))
+	journal, err := api.OpenJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+	assert.NoError(t, err)
+	defer journal.Close()
+	opts.Journal = journal
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(presidencyDir, "organisation", opts))
+
+	// The reused ministers are related to the term from its start
+	ministers, err := store.GetRelatedEntities("term_gr", &models.Relationship{Name: api.TermMinisterRelationship})
+	assert.NoError(t, err)
+	since := map[string]string{}
+	for _, rel := range ministers {
+		entity, _ := store.Entity(rel.RelatedEntityID)
+		since[entity.Name.Value.(string)] = rel.StartTime
+	}
+	assert.Equal(t, map[string]string{
+		"Minister of Defence":  "2019-11-18T00:00:00Z",
+		"Minister of Shipping": "2019-12-10T00:00:00Z",
+		"Minister of Ports":    "2019-11-18T00:00:00Z",
+	}, since)
+
+	// Adding a minister of the term again neither relates it twice nor journals a link
+	rerunDir := filepath.Join(presidencyDir, "2019-12-20")
+	writeGazetteFile(t, rerunDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2154-01_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-20`)
+	assert.NoError(t, memoryProcessor.ProcessTransactions(rerunDir, "organisation", opts))
+	ministers, err = store.GetRelatedEntities("term_gr", &models.Relationship{Name: api.TermMinisterRelationship})
+	assert.NoError(t, err)
+	assert.Len(t, ministers, 3)
+	entries := journal.Entries()
+	assert.Equal(t, []api.Change{{Operation: api.ChangeReuseEntity, EntityID: "1897-15_min_1"}}, entries[len(entries)-1].Changes)
+}
+
+func TestTermRoot(t *testing.T) {
+	presidencyDir := filepath.Join(t.TempDir(), "pc")
+	writeGazetteFile(t, presidencyDir, api.TermManifestFile, `{"name": "Western Provincial Council 2019", "start": "2019-11-18"}`)
+	writeGazetteFile(t, filepath.Join(presidencyDir, "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Western Provincial Council,provincial_council,Provincial Minister of Health,minister,AS_MINISTER,2019-12-10`)
+	council := api.RootNode{ID: "pc_western", Name: "Western Provincial Council", Created: "1988-01-01", Kind: "provincial_council"}
+
+	// Without its root the term is skipped, but the folder is still processed
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, _, err := memoryProcessor.CreateRootNode(council)
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(presidencyDir, "organisation", &api.ProcessOptions{}))
+	_, exists := store.Entity("term_pc")
+	assert.False(t, exists)
+	ministers, err := store.GetRelatedEntities("pc_western", &models.Relationship{Name: "AS_MINISTER"})
+	assert.NoError(t, err)
+	assert.Len(t, ministers, 1)
+
+	// The term belongs to the root of the processor
+	store = api.NewMemoryStore()
+	memoryProcessor = api.NewProcessor(store)
+	_, _, err = memoryProcessor.CreateRootNode(council)
+	assert.NoError(t, err)
+	memoryProcessor.SetRootNode(council)
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(presidencyDir, "organisation", &api.ProcessOptions{}))
+	terms, err := store.GetRelatedEntities("pc_western", &models.Relationship{Name: api.TermRelationship})
+	assert.NoError(t, err)
+	if assert.Len(t, terms, 1) {
+		assert.Equal(t, "term_pc", terms[0].RelatedEntityID)
+	}
+	ministers, err = store.GetRelatedEntities("term_pc", &models.Relationship{Name: api.TermMinisterRelationship})
+	assert.NoError(t, err)
+	assert.Len(t, ministers, 1)
+}
+
+func TestPersonDataHasNoTerm(t *testing.T) {
+	presidencyDir := filepath.Join(t.TempDir(), "p_gr")
+	writeGazetteFile(t, presidencyDir, api.TermManifestFile, `{"name": "Presidency of Gotabaya Rajapaksa", "start": "2019-11-18"}`)
+	writeGazetteFile(t, filepath.Join(presidencyDir, "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-13_tr_01,Minister of Defence,minister,Gotabaya Rajapaksa,citizen,AS_APPOINTED,2019-12-10`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	addDir := t.TempDir()
+	writeGazetteFile(t, addDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10`)
+	assert.NoError(t, memoryProcessor.ProcessTransactions(addDir, "organisation", nil))
+
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(presidencyDir, "person", &api.ProcessOptions{}))
+	_, exists := store.Entity("term_p_gr")
+	assert.False(t, exists)
+	terms, err := store.GetRelatedEntities(api.DefaultGovernmentID, &models.Relationship{Name: api.TermRelationship})
+	assert.NoError(t, err)
+	assert.Empty(t, terms)
+}
+
+func TestUndoRemovesTerm(t *testing.T) {
+	presidencyDir := filepath.Join(t.TempDir(), "gr")
+	writeGazetteFile(t, presidencyDir, api.TermManifestFile, `{"name": "Presidency of Gotabaya Rajapaksa", "start": "2019-11-18"}`)
+	dataDir := filepath.Join(presidencyDir, "2019-12-10")
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	journal, err := api.OpenJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+	assert.NoError(t, err)
+	defer journal.Close()
+	assert.NoError(t, memoryProcessor.ProcessTransactionTree(presidencyDir, "organisation", &api.ProcessOptions{Journal: journal}))
+
+	// The term is journaled before the transactions of the folder that created it
+	entries := journal.Entries()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "term_gr", entries[0].TransactionID)
+		assert.Equal(t, api.TermFileType, entries[0].FileType)
+		assert.Equal(t, dataDir, entries[0].DataDir)
+		assert.Equal(t, []api.Change{
+			{Operation: api.ChangeCreateEntity, EntityID: "term_gr"},
+			{Operation: api.ChangeAddRelationship, EntityID: api.DefaultGovernmentID, RelationshipID: api.DefaultGovernmentID + "_term_gr", RelatedEntityID: "term_gr", Name: api.TermRelationship, StartTime: "2019-11-18T00:00:00Z"},
+		}, entries[0].Changes)
+	}
+
+	// Undoing the folder leaves neither the term nor an active link to it
+	_, err = memoryProcessor.UndoTransactions(journal, func(entry api.JournalEntry) bool { return entry.DataDir == dataDir })
+	assert.NoError(t, err)
+	if assert.Len(t, journal.Entries(), 1) {
+		assert.True(t, journal.Entries()[0].Undo)
+	}
+	_, exists := store.Entity("term_gr")
+	assert.False(t, exists)
+	terms, err := store.GetRelatedEntities(api.DefaultGovernmentID, &models.Relationship{Name: api.TermRelationship})
+	assert.NoError(t, err)
+	for _, rel := range terms {
+		assert.Equal(t, rel.StartTime, rel.EndTime)
+	}
+}
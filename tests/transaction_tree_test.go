@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeGazetteFile writes a CSV file into a gazette folder, creating the folder if needed
+func writeGazetteFile(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(dir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+}
+
+func TestProcessTransactionTree(t *testing.T) {
+	const addHeader = "transaction_id,parent,parent_type,child,child_type,rel_type,date\n"
+
+	testCases := []struct {
+		name string
+		// files maps paths relative to the root directory to their content
+		files map[string]string
+		// wantErr is a part of the expected error, or empty if processing succeeds
+		wantErr string
+		// wantMinisters lists the ministers in the order they were created
+		wantMinisters []string
+	}{
+		{
+			name: "folders are processed in date order",
+			files: map[string]string{
+				// The rename needs the minister added by the earlier folder
+				"2020-01-15/RENAME.csv": "transaction_id,old,new,type,date\n2160-01_tr_01,Minister of Health,Minister of Health and Wellness,AS_MINISTER,2020-01-15",
+				"2019-12-31/ADD.csv":    addHeader + "2158-03_tr_01,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-31",
+				"2019-12-10/ADD.csv":    addHeader + "2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10",
+			},
+			wantMinisters: []string{"Minister of Defence", "Minister of Health", "Minister of Health and Wellness"},
+		},
+		{
+			name: "directories not named after a date are skipped",
+			files: map[string]string{
+				"2019-12-10/ADD.csv": addHeader + "2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10",
+				"drafts/ADD.csv":     addHeader + "9999-99_tr_01,Nobody,minister,Minister of Drafts,minister,AS_MINISTER,2019-12-10",
+				"2019-13-01/ADD.csv": addHeader + "9999-99_tr_01,Nobody,minister,Minister of Month 13,minister,AS_MINISTER,2019-12-10",
+				"README.md":          "Gazettes of the presidency",
+			},
+			wantMinisters: []string{"Minister of Defence"},
+		},
+		{
+			name: "processing stops at the first failing folder",
+			files: map[string]string{
+				"2019-12-10/ADD.csv": addHeader + "2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10",
+				"2019-12-31/ADD.csv": addHeader + "2158-03_tr_01,Minister of Nothing,minister,Department of Nothing,department,AS_DEPARTMENT,2019-12-31",
+				"2020-01-15/ADD.csv": addHeader + "2160-01_tr_01,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2020-01-15",
+			},
+			wantErr:       "failed to process gazette folder 2019-12-31",
+			wantMinisters: []string{"Minister of Defence"},
+		},
+		{
+			name: "a tree without dated folders is an error",
+			files: map[string]string{
+				"drafts/ADD.csv": addHeader + "2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10",
+			},
+			wantErr:       "no dated gazette folders found",
+			wantMinisters: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rootDir := t.TempDir()
+			for path, content := range tc.files {
+				writeGazetteFile(t, filepath.Join(rootDir, filepath.Dir(path)), filepath.Base(path), content)
+			}
+
+			store := api.NewMemoryStore()
+			memoryProcessor := api.NewProcessor(store)
+			_, err := memoryProcessor.CreateGovernmentNode()
+			assert.NoError(t, err)
+
+			err = memoryProcessor.ProcessTransactionTree(rootDir, "organisation", &api.ProcessOptions{})
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.wantErr)
+			}
+			assert.Equal(t, tc.wantMinisters, ministersInCreationOrder(t, store))
+		})
+	}
+
+	// A missing root directory is reported
+	err := api.NewProcessor(api.NewMemoryStore()).ProcessTransactionTree(filepath.Join(t.TempDir(), "missing"), "organisation", nil)
+	assert.ErrorContains(t, err, "failed to read directory")
+}
+
+// ministersInCreationOrder returns the names of the ministers in a store ordered by the counter
+// of their IDs, which the entity counters shared by the folders of a tree number in order
+func ministersInCreationOrder(t *testing.T, store *api.MemoryStore) []string {
+	t.Helper()
+	results, err := store.SearchEntities(&models.SearchCriteria{Kind: &models.Kind{Major: "Organisation", Minor: "minister"}})
+	assert.NoError(t, err)
+
+	names := make([]string, len(results))
+	for _, result := range results {
+		counter, err := strconv.Atoi(result.ID[strings.LastIndex(result.ID, "_")+1:])
+		if assert.NoError(t, err) && assert.LessOrEqual(t, counter, len(names)) {
+			names[counter-1] = result.Name
+		}
+	}
+	return names
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"errors"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadTransactionsErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fileName string
+		content  string
+		// wantLine and wantColumn locate the problem the error must name
+		wantLine   int
+		wantColumn string
+		wantReason string
+	}{
+		{
+			name:     "missing header column",
+			fileName: "ADD.csv",
+			content: `transaction_id,parent,parent_type,child,child_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,2019-12-10`,
+			wantLine:   1,
+			wantColumn: "rel_type",
+			wantReason: "missing column",
+		},
+		{
+			name:     "empty value",
+			fileName: "TERMINATE.csv",
+			content: `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Minister of Defence,minister, ,department,AS_DEPARTMENT,2019-12-10`,
+			wantLine:   3,
+			wantColumn: "child",
+			wantReason: "missing value for column",
+		},
+		{
+			name:     "row shorter than the header",
+			fileName: "RENAME.csv",
+			content: `transaction_id,old,new,type,date
+2153-12_tr_01,Minister of Defence,Minister of Defence and Security,AS_MINISTER`,
+			wantLine:   2,
+			wantColumn: "date",
+			wantReason: "missing value for column",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dataDir := t.TempDir()
+			writeGazetteFile(t, dataDir, tc.fileName, tc.content)
+
+			store := api.NewMemoryStore()
+			memoryProcessor := api.NewProcessor(store)
+			_, err := memoryProcessor.CreateGovernmentNode()
+			assert.NoError(t, err)
+
+			err = memoryProcessor.ProcessTransactions(dataDir, "organisation", &api.ProcessOptions{})
+			var fileErr *api.TransactionFileError
+			if assert.True(t, errors.As(err, &fileErr), "error %v should be a TransactionFileError", err) {
+				assert.Equal(t, filepath.Join(dataDir, tc.fileName), fileErr.File)
+				assert.Equal(t, tc.wantLine, fileErr.Line)
+				assert.Equal(t, tc.wantColumn, fileErr.Column)
+				assert.Equal(t, tc.wantReason, fileErr.Reason)
+			}
+			assert.ErrorContains(t, err, filepath.Join(dataDir, tc.fileName))
+
+			// A malformed file is rejected before any of its rows is applied
+			ministers, err := store.SearchEntities(&models.SearchCriteria{Kind: &models.Kind{Major: "Organisation", Minor: "minister"}})
+			assert.NoError(t, err)
+			assert.Empty(t, ministers)
+		})
+	}
+}
+
+func TestLoadTransactionsKeepsValues(t *testing.T) {
+	dataDir := t.TempDir()
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Labour ,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Minister of Labour ,minister,Department of Labour,department,AS_DEPARTMENT,2019-12-10`)
+	// The type of a RENAME row is optional
+	writeGazetteFile(t, dataDir, "RENAME.csv", `transaction_id,old,new,type,date
+2153-12_tr_03,Minister of Labour ,Minister of Labour and Employment,,2019-12-10`)
+
+	issues, err := api.ValidateTransactions(dataDir, map[string]bool{"Government of Sri Lanka": true})
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err = memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactions(dataDir, "organisation", &api.ProcessOptions{}))
+
+	// Names keep the spaces around them, as in the file
+	ministers, err := store.SearchEntities(&models.SearchCriteria{Kind: &models.Kind{Major: "Organisation", Minor: "minister"}})
+	assert.NoError(t, err)
+	names := []string{}
+	for _, minister := range ministers {
+		names = append(names, minister.Name)
+	}
+	assert.ElementsMatch(t, []string{"Minister of Labour ", "Minister of Labour and Employment"}, names)
+
+	// A name that only differs in spaces is pointed out by the validator
+	writeGazetteFile(t, dataDir, "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_04,Minister of Labour,minister,Department of Labour,department,AS_DEPARTMENT,2019-12-11`)
+	issues, err = api.ValidateTransactions(dataDir, map[string]bool{"Government of Sri Lanka": true})
+	assert.NoError(t, err)
+	if assert.Len(t, issues, 1) {
+		assert.Contains(t, issues[0].Message, `("Minister of Labour " differs only in the spaces around it)`)
+	}
+}
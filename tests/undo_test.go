@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"bytes"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/export"
+	"orgchart_nexoan/models"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUndoTransactions(t *testing.T) {
+	rootDir := t.TempDir()
+	addDir := filepath.Join(rootDir, "2025-03-01")
+	terminateDir := filepath.Join(rootDir, "2025-04-01")
+	writeGazetteFile(t, addDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2999-01_tr_01,Government of Sri Lanka,government,Minister of Undo,minister,AS_MINISTER,2025-03-01
+2999-01_tr_02,Minister of Undo,minister,Department of Undo,department,AS_DEPARTMENT,2025-03-01`)
+	writeGazetteFile(t, terminateDir, "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2999-02_tr_01,Minister of Undo,minister,Department of Undo,department,AS_DEPARTMENT,2025-04-01`)
+
+	journal, err := api.OpenJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+	assert.NoError(t, err)
+	defer journal.Close()
+
+	opts := &api.ProcessOptions{Journal: journal}
+	assert.NoError(t, processor.ProcessTransactionTree(rootDir, "organisation", opts))
+	assert.Len(t, journal.Entries(), 3)
+
+	ministers, err := client.SearchEntities(&models.SearchCriteria{
+		Kind: &models.Kind{Major: "Organisation", Minor: "minister"},
+		Name: "Minister of Undo",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, ministers, 1)
+	departments, err := client.SearchEntities(&models.SearchCriteria{
+		Kind: &models.Kind{Major: "Organisation", Minor: "department"},
+		Name: "Department of Undo",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, departments, 1)
+	if len(ministers) != 1 || len(departments) != 1 {
+		return
+	}
+
+	// Undoing the termination reopens the department relationship
+	undone, err := processor.UndoTransactions(journal, func(entry api.JournalEntry) bool {
+		return entry.DataDir == terminateDir
+	})
+	assert.NoError(t, err)
+	assert.Len(t, undone, 1)
+	relations, err := client.GetRelatedEntities(ministers[0].ID, &models.Relationship{
+		RelatedEntityID: departments[0].ID,
+		Name:            "AS_DEPARTMENT",
+		StartTime:       "2025-05-01T00:00:00Z",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, relations, 1, "Department relationship should be active again")
+
+	// Undoing the additions deletes the entities and removes the government relationship
+	undone, err = processor.UndoTransactions(journal, func(entry api.JournalEntry) bool {
+		return entry.DataDir == addDir
+	})
+	assert.NoError(t, err)
+	assert.Len(t, undone, 2)
+	assert.Equal(t, "2999-01_tr_02", undone[0].TransactionID, "Transactions should be undone in reverse order")
+	if assert.Len(t, journal.Entries(), 1, "Only the record of the removed relationships should be left") {
+		assert.True(t, journal.Entries()[0].Undo)
+	}
+	assert.Len(t, journal.UndoneRelationships(), 2)
+
+	ministers, err = client.SearchEntities(&models.SearchCriteria{
+		Kind: &models.Kind{Major: "Organisation", Minor: "minister"},
+		Name: "Minister of Undo",
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, ministers)
+
+	government, err := client.SearchEntities(&models.SearchCriteria{
+		Kind: &models.Kind{Major: "Organisation", Minor: "government"},
+		Name: "Government of Sri Lanka",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, government, 1)
+	relations, err = client.GetRelatedEntities(government[0].ID, &models.Relationship{
+		RelatedEntityID: "2999-01_min_1",
+		StartTime:       "2025-03-01T00:00:00Z",
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, relations, "Government relationship should no longer be active")
+}
+
+func TestUndoTransactionsAfterReopeningJournal(t *testing.T) {
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+
+	// The second row adds a minister that already exists, so it only records the reuse
+	dataDir := t.TempDir()
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2999-03_tr_01,Government of Sri Lanka,government,Minister of Reopening,minister,AS_MINISTER,2025-03-01
+2999-03_tr_02,Government of Sri Lanka,government,Minister of Reopening,minister,AS_MINISTER,2025-03-01`)
+
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+	journal, err := api.OpenJournal(journalPath)
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactions(dataDir, "organisation", &api.ProcessOptions{Journal: journal}))
+	assert.NoError(t, journal.Close())
+
+	journal, err = api.OpenJournal(journalPath)
+	assert.NoError(t, err)
+	defer journal.Close()
+	entries := journal.Entries()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, []api.Change{{Operation: api.ChangeReuseEntity, EntityID: "2999-03_min_1"}}, entries[1].Changes)
+	}
+
+	undone, err := memoryProcessor.UndoTransactions(journal, func(api.JournalEntry) bool { return true })
+	assert.NoError(t, err)
+	assert.Len(t, undone, 2)
+	ministers, err := store.SearchEntities(&models.SearchCriteria{Name: "Minister of Reopening"})
+	assert.NoError(t, err)
+	assert.Empty(t, ministers)
+}
+
+func TestExportAfterUndo(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "2025-03-01")
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2999-03_tr_01,Government of Sri Lanka,government,Minister of Exports,minister,AS_MINISTER,2025-03-01
+2999-03_tr_02,Minister of Exports,minister,Department of Exports,department,AS_DEPARTMENT,2025-03-01`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	journal, err := api.OpenJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+	assert.NoError(t, err)
+	defer journal.Close()
+	assert.NoError(t, memoryProcessor.ProcessTransactions(dataDir, "organisation", &api.ProcessOptions{Journal: journal}))
+
+	_, err = memoryProcessor.UndoTransactions(journal, func(entry api.JournalEntry) bool { return true })
+	assert.NoError(t, err)
+
+	// The government still holds the relationship to the deleted minister, ended at its start
+	relations, err := store.GetAllRelatedEntities(api.DefaultGovernmentID)
+	assert.NoError(t, err)
+	if assert.Len(t, relations, 1) {
+		assert.Equal(t, "2999-03_min_1", relations[0].RelatedEntityID)
+		assert.Equal(t, relations[0].StartTime, relations[0].EndTime)
+	}
+
+	// The graph leaves the removed relationship out, so it can be exported
+	graph, err := api.LoadGraph(store, "", journal)
+	assert.NoError(t, err)
+	if assert.Len(t, graph.Nodes, 1) {
+		assert.Equal(t, api.DefaultGovernmentID, graph.Nodes[0].ID)
+	}
+	assert.Empty(t, graph.Edges)
+	for _, format := range export.Formats() {
+		var out bytes.Buffer
+		assert.NoError(t, export.Write(&out, graph, format, export.Options{}), format)
+		assert.NotContains(t, out.String(), "2999-03_min_1", format)
+	}
+	folders, err := api.ReconstructGazettes(graph, "organisation")
+	assert.NoError(t, err)
+	assert.Empty(t, folders)
+}
+
+func TestLoadGraphKeepsSameDayRelationships(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "2025-03-01")
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2999-03_tr_01,Government of Sri Lanka,government,Minister of One Day,minister,AS_MINISTER,2025-03-01`)
+	writeGazetteFile(t, dataDir, "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2999-03_tr_02,Government of Sri Lanka,government,Minister of One Day,minister,AS_MINISTER,2025-03-01`)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err := memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	journal, err := api.OpenJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+	assert.NoError(t, err)
+	defer journal.Close()
+	assert.NoError(t, memoryProcessor.ProcessTransactions(dataDir, "organisation", &api.ProcessOptions{Journal: journal}))
+
+	// A relationship ended on the day it started was not removed by undo, so it is kept
+	graph, err := api.LoadGraph(store, "", journal)
+	assert.NoError(t, err)
+	if assert.Len(t, graph.Edges, 1) {
+		assert.Equal(t, "2999-03_min_1", graph.Edges[0].To)
+		assert.Equal(t, graph.Edges[0].StartTime, graph.Edges[0].EndTime)
+	}
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTransactions(t *testing.T) {
+	dataDir := t.TempDir()
+	writeGazetteFile(t, dataDir, "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Minister of Defence,minister,Sri Lanka Army,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_03,Minister of Health,minister,Department of Health,department,AS_DEPARTMENT,2019-12-10
+2153-12_tr_04,Minister of Defence,minister,Sri Lanka Navy,department,AS_DEPARTMENT,10/12/2019
+2153-12-04,Minister of Defence,minister,Sri Lanka Air Force,department,AS_DEPARTMENT,2019-12-10
+`)
+	writeGazetteFile(t, dataDir, "MOVE.csv", `transaction_id,old_parent,new_parent,child,date
+2153-12_tr_05,Minister of Defence,Minister of Defence,Sri Lanka Army,2019-12-10
+`)
+
+	known := map[string]bool{"Government of Sri Lanka": true}
+	issues, err := api.ValidateTransactions(dataDir, known)
+	assert.NoError(t, err)
+
+	messages := map[string]bool{}
+	for _, issue := range issues {
+		messages[issue.String()] = true
+	}
+	addFile := filepath.Join(dataDir, "ADD.csv")
+	moveFile := filepath.Join(dataDir, "MOVE.csv")
+	assert.Len(t, issues, 4)
+	assert.True(t, messages[moveFile+`:1: missing column "type" for a MOVE file (expected transaction_id,old_parent,new_parent,child,type,date)`])
+	assert.True(t, messages[addFile+`:4: 2153-12_tr_03: parent "Minister of Health" is neither created earlier nor a known entity`])
+	assert.True(t, messages[addFile+`:5: 2153-12_tr_04: date "10/12/2019" does not use the 2006-01-02 layout`])
+	assert.True(t, messages[addFile+`:6: 2153-12-04: transaction_id does not have the <gazette>_tr_<n> shape`])
+
+	// Entities created by the folder are known to the next one
+	assert.True(t, known["Minister of Defence"])
+	assert.True(t, known["Sri Lanka Army"])
+}
+
+func TestValidateTransactionTree(t *testing.T) {
+	rootDir := t.TempDir()
+	writeGazetteFile(t, filepath.Join(rootDir, "2019-12-31"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2157-13_tr_01,Minister of Defence,minister,Sri Lanka Army,department,AS_DEPARTMENT,2019-12-31
+`)
+	writeGazetteFile(t, filepath.Join(rootDir, "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+`)
+
+	// The later folder refers to a minister created by the earlier one
+	issues, err := api.ValidateTransactionTree(rootDir, map[string]bool{"Government of Sri Lanka": true})
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+}
+
+func TestValidateOrgchartData(t *testing.T) {
+	// The presidencies are validated in order, so ministers created by one are known to the next
+	known := map[string]bool{api.DefaultRootNode().Name: true}
+	for _, presidency := range []string{"gr", "rw", "akd"} {
+		issues, err := api.ValidateTransactionTree(filepath.Join("..", "data", "orgchart", presidency), known)
+		assert.NoError(t, err)
+		for _, issue := range issues {
+			// The rw data refers to some ministers and departments by names that differ from the
+			// ones they were created with, which ingest rejects as well
+			if presidency == "rw" && strings.Contains(issue.Message, "is neither created earlier nor a known entity") {
+				continue
+			}
+			assert.Fail(t, "unexpected validation issue", issue.String())
+		}
+	}
+}
+
+func TestValidateTransactionsAgreesWithLoader(t *testing.T) {
+	// A trailing comma gives an unnamed column and the header is repeated, as in the akd data.
+	// Rows with more or fewer fields than the header and columns of no file type are accepted as
+	// long as every column of the file type has a value.
+	dataDir := t.TempDir()
+	writeGazetteFile(t, dataDir, "ADD.csv", "transaction_id,parent,parent_type,child,child_type,rel_type,date,\r\n"+
+		"transaction_id,parent,parent_type,child,child_type,rel_type,date,description\r\n"+
+		"2412-08_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2024-11-25,\r\n"+
+		"2412-08_tr_02,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2024-11-25\r\n"+
+		"2412-08_tr_03,Government of Sri Lanka,government,Minister of Finance,minister,AS_MINISTER,2024-11-25,,note\r\n")
+	writeGazetteFile(t, dataDir, "TERMINATE.csv", "transaction_id,parent,parent_type,child,child_type,rel_type,date,gazette_page\r\n"+
+		"2412-08_tr_04,Government of Sri Lanka,government,Minister of Finance,minister,AS_MINISTER,2024-11-26,4\r\n")
+
+	issues, err := api.ValidateTransactions(dataDir, map[string]bool{"Government of Sri Lanka": true})
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+
+	store := api.NewMemoryStore()
+	memoryProcessor := api.NewProcessor(store)
+	_, err = memoryProcessor.CreateGovernmentNode()
+	assert.NoError(t, err)
+	assert.NoError(t, memoryProcessor.ProcessTransactions(dataDir, "organisation", &api.ProcessOptions{}))
+	results, err := store.SearchEntities(&models.SearchCriteria{Kind: &models.Kind{Major: "Organisation", Minor: "minister"}})
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+}
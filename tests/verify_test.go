@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"bytes"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeVerifyTrees writes an organisation and a person data tree. Without the Department of
+// Hospitals the organisation tree leaves out one department.
+func writeVerifyTrees(t *testing.T, withHospitals bool) []api.DataTree {
+	orgDir := t.TempDir()
+	add := `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-12_tr_01,Government of Sri Lanka,government,Minister of Defence,minister,AS_MINISTER,2019-12-10
+2153-12_tr_02,Government of Sri Lanka,government,Minister of Health,minister,AS_MINISTER,2019-12-10
+2153-12_tr_03,Minister of Defence,minister,Sri Lanka Army,department,AS_DEPARTMENT,2019-12-10`
+	if withHospitals {
+		add += "\n2153-12_tr_04,Minister of Health,minister,Department of Hospitals,department,AS_DEPARTMENT,2019-12-10"
+	}
+	writeGazetteFile(t, filepath.Join(orgDir, "gr", "2019-12-10"), "ADD.csv", add)
+
+	// The person folder shares its date with the organisation folder and needs its ministers
+	peopleDir := t.TempDir()
+	writeGazetteFile(t, filepath.Join(peopleDir, "gr", "2019-12-10"), "ADD.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2153-13_tr_01,Minister of Defence,minister,Gotabaya Rajapaksa,citizen,AS_APPOINTED,2019-12-10`)
+	writeGazetteFile(t, filepath.Join(peopleDir, "gr", "2020-01-15"), "TERMINATE.csv", `transaction_id,parent,parent_type,child,child_type,rel_type,date
+2160-02_tr_01,Minister of Defence,minister,Gotabaya Rajapaksa,citizen,AS_APPOINTED,2020-01-15`)
+
+	return []api.DataTree{
+		{Dir: peopleDir, ProcessType: "person"},
+		{Dir: orgDir, ProcessType: "organisation"},
+	}
+}
+
+// entityID returns the ID of the entity with the given name in a store
+func entityID(t *testing.T, store api.Store, name string) string {
+	results, err := store.SearchEntities(&models.SearchCriteria{Name: name})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	return results[0].ID
+}
+
+// relateEntity adds a relationship from parent to child in a store
+func relateEntity(t *testing.T, store api.Store, parentID string, childID string, name string, start string) {
+	_, err := store.UpdateEntity(parentID, &models.Entity{
+		ID: parentID,
+		Relationships: []models.RelationshipEntry{{
+			Key: parentID + "_" + childID,
+			Value: models.Relationship{
+				ID:              parentID + "_" + childID,
+				RelatedEntityID: childID,
+				Name:            name,
+				StartTime:       start,
+			},
+		}},
+	})
+	assert.NoError(t, err)
+}
+
+func TestReconcileMatchingData(t *testing.T) {
+	expectedStore, err := api.ReplayDataTrees(writeVerifyTrees(t, true), nil)
+	assert.NoError(t, err)
+	actualStore, err := api.ReplayDataTrees(writeVerifyTrees(t, true), nil)
+	assert.NoError(t, err)
+
+	expected, err := api.LoadGraph(expectedStore, "", nil)
+	assert.NoError(t, err)
+	actual, err := api.LoadGraph(actualStore, "", nil)
+	assert.NoError(t, err)
+	assert.Len(t, expected.Nodes, 6)
+
+	reconciliation := api.Reconcile(expected, actual, []string{"Organisation", "Person"})
+	assert.True(t, reconciliation.Empty())
+
+	var out bytes.Buffer
+	reconciliation.Print(&out)
+	assert.Equal(t, "Nexoan matches the data\n", out.String())
+}
+
+func TestReconcileDrift(t *testing.T) {
+	expectedStore, err := api.ReplayDataTrees(writeVerifyTrees(t, true), nil)
+	assert.NoError(t, err)
+	actualStore, err := api.ReplayDataTrees(writeVerifyTrees(t, false), nil)
+	assert.NoError(t, err)
+
+	// Drift introduced by hand: an extra minister, a second Minister of Health, and the army
+	// relationship ended although the data never ends it
+	extra, err := actualStore.CreateEntity(&models.Entity{
+		ID:      "manual_min_01",
+		Created: "2020-02-01T00:00:00Z",
+		Kind:    models.Kind{Major: "Organisation", Minor: "minister"},
+		Name:    models.TimeBasedValue{StartTime: "2020-02-01T00:00:00Z", Value: "Minister of Youth"},
+	})
+	assert.NoError(t, err)
+	relateEntity(t, actualStore, api.DefaultGovernmentID, extra.ID, "AS_MINISTER", "2020-02-01T00:00:00Z")
+
+	duplicate, err := actualStore.CreateEntity(&models.Entity{
+		ID:      "manual_min_02",
+		Created: "2020-02-01T00:00:00Z",
+		Kind:    models.Kind{Major: "Organisation", Minor: "minister"},
+		Name:    models.TimeBasedValue{StartTime: "2020-02-01T00:00:00Z", Value: "Minister of Health"},
+	})
+	assert.NoError(t, err)
+	relateEntity(t, actualStore, api.DefaultGovernmentID, duplicate.ID, "AS_MINISTER", "2020-02-01T00:00:00Z")
+
+	defenceID := entityID(t, actualStore, "Minister of Defence")
+	armyID := entityID(t, actualStore, "Sri Lanka Army")
+	_, err = actualStore.UpdateEntity(defenceID, &models.Entity{
+		ID: defenceID,
+		Relationships: []models.RelationshipEntry{{
+			Key:   defenceID + "_" + armyID,
+			Value: models.Relationship{ID: defenceID + "_" + armyID, EndTime: "2020-03-01T00:00:00Z"},
+		}},
+	})
+	assert.NoError(t, err)
+
+	expected, err := api.LoadGraph(expectedStore, "", nil)
+	assert.NoError(t, err)
+	actual, err := api.LoadGraph(actualStore, "", nil)
+	assert.NoError(t, err)
+	reconciliation := api.Reconcile(expected, actual, []string{"Organisation", "Person"})
+
+	if assert.Len(t, reconciliation.MissingEntities, 1) {
+		assert.Equal(t, "Department of Hospitals", reconciliation.MissingEntities[0].Name)
+		assert.Equal(t, "Organisation/department", reconciliation.MissingEntities[0].Kind)
+	}
+	if assert.Len(t, reconciliation.ExtraEntities, 1) {
+		assert.Equal(t, "manual_min_01", reconciliation.ExtraEntities[0].ID)
+	}
+	if assert.Len(t, reconciliation.DuplicateNames, 1) {
+		assert.Equal(t, "Minister of Health", reconciliation.DuplicateNames[0].Name)
+		assert.Len(t, reconciliation.DuplicateNames[0].Entities, 2)
+	}
+	assert.Equal(t, []api.ReconciledRelationship{
+		{Parent: "Minister of Health", Child: "Department of Hospitals", Name: "AS_DEPARTMENT", StartDate: "2019-12-10"},
+	}, reconciliation.MissingRelationships)
+	assert.Equal(t, []api.ReconciledRelationship{
+		{Parent: "Government of Sri Lanka", Child: "Minister of Health", Name: "AS_MINISTER", StartDate: "2020-02-01"},
+		{Parent: "Government of Sri Lanka", Child: "Minister of Youth", Name: "AS_MINISTER", StartDate: "2020-02-01"},
+	}, reconciliation.ExtraRelationships)
+	if assert.Len(t, reconciliation.EndDateMismatches, 1) {
+		mismatch := reconciliation.EndDateMismatches[0]
+		assert.Equal(t, "Sri Lanka Army", mismatch.Relationship.Child)
+		assert.Equal(t, "", mismatch.ExpectedEnd)
+		assert.Equal(t, "2020-03-01", mismatch.ActualEnd)
+	}
+
+	var out bytes.Buffer
+	reconciliation.Print(&out)
+	assert.Contains(t, out.String(), "Missing entities (1):\n  - Organisation/department \"Department of Hospitals\"")
+	assert.Contains(t, out.String(), "Wrong end dates (1):\n  Minister of Defence -[AS_DEPARTMENT]-> Sri Lanka Army from 2019-12-10: expected present, found 2020-03-01\n")
+
+	// People are left out when only the organisation data is checked
+	withoutPeople := api.Reconcile(expected, actual, []string{"Organisation"})
+	assert.Len(t, withoutPeople.MissingEntities, 1)
+	assert.Len(t, withoutPeople.ExtraEntities, 1)
+}
+
+func TestReplayDataTreesInvalidProcessType(t *testing.T) {
+	_, err := api.ReplayDataTrees([]api.DataTree{{Dir: t.TempDir(), ProcessType: "citizen"}}, nil)
+	assert.ErrorContains(t, err, "invalid process type")
+}